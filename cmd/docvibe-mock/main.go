@@ -0,0 +1,74 @@
+// docvibe-mock serves the endpoints of schema/swagger.yaml and
+// schema/swagger-domonda.yaml with fixture files from a directory
+// for testing without access to the DocVibe Engine API.
+//
+// Fixtures are looked up at
+//
+//	<fixtures>/<endpoint path>/<key>.json
+//
+// or with the extension .zip for /extract/text-and-page-images,
+// where key is the X-Document-ID header for the Domonda endpoints
+// or the hex encoded SHA-256 hash of the uploaded document
+// for the generic endpoints.
+// If no fixture exists for a key, then default.json or default.zip is used.
+// A file <key>.error.json containing an Error object like
+// {"error": "invalid API key", "code": 401}
+// makes the endpoint respond with that error.
+//
+// Example:
+//
+//	fixtures/extract/section-35a-invoice-amounts/default.json
+//	fixtures/domonda/extract/invoice/0b3c2a4e-8f0e-4d6b-9c59-0e2f5a4f1f1e.json
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+)
+
+func main() {
+	var (
+		addr         string
+		mock         mockServer
+		errorRate    float64
+		errorCode    int
+		latency      time.Duration
+		latencyRange time.Duration
+	)
+	flag.StringVar(&addr, "addr", "localhost:8080", "address to listen on")
+	flag.StringVar(&mock.fixturesDir, "fixtures", "fixtures", "directory with the fixture files")
+	flag.StringVar(&mock.domondaAPIKey, "domonda-api-key", "", "X-Domonda-API-Key that is accepted, any non empty key if empty")
+	flag.Float64Var(&errorRate, "error-rate", 0, "rate between 0 and 1 of requests that fail with the status of -error-code")
+	flag.IntVar(&errorCode, "error-code", http.StatusInternalServerError, "HTTP status code of injected errors (400, 401 or 500)")
+	flag.DurationVar(&latency, "latency", 0, "latency added to every response")
+	flag.DurationVar(&latencyRange, "latency-jitter", 0, "random jitter added to the latency of every response")
+	flag.Parse()
+
+	if errorRate < 0 || errorRate > 1 {
+		log.Fatalf("-error-rate %f is not between 0 and 1", errorRate)
+	}
+	switch errorCode {
+	case http.StatusBadRequest, http.StatusUnauthorized, http.StatusInternalServerError:
+	default:
+		log.Fatalf("-error-code %d is not one of 400, 401 or 500", errorCode)
+	}
+	if info, err := os.Stat(mock.fixturesDir); err != nil || !info.IsDir() {
+		log.Fatalf("fixtures directory %q does not exist", mock.fixturesDir)
+	}
+	mock.faults = faultInjector{
+		errorRate:     errorRate,
+		errorCode:     errorCode,
+		latency:       latency,
+		latencyJitter: latencyRange,
+	}
+
+	fmt.Println("Serving fixtures from", mock.fixturesDir, "at", addr)
+	err := http.ListenAndServe(addr, mock.handler())
+	if err != nil {
+		log.Fatal(err)
+	}
+}
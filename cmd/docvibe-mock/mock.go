@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"math/rand/v2"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/domonda/go-types/uu"
+
+	"github.com/docvibe-ai/api/go/server"
+)
+
+type mockServer struct {
+	fixturesDir   string
+	domondaAPIKey string
+	faults        faultInjector
+}
+
+func (m *mockServer) handler() http.Handler {
+	mux := http.NewServeMux()
+	for _, path := range []string{
+		"/extract/identify-real-estate-object",
+		"/extract/section-35a-invoice-amounts",
+		"/domonda/extract/invoice",
+		"/domonda/extract/accounting-invoice",
+		"/domonda/extract/real-estate-invoice",
+		"/domonda/extract/identify-real-estate-object",
+	} {
+		mux.Handle(path, m.endpoint(path, ".json", "application/json"))
+	}
+	mux.Handle("/extract/text-and-page-images", m.endpoint("/extract/text-and-page-images", ".zip", "application/zip"))
+	return mux
+}
+
+func (m *mockServer) endpoint(path, ext, contentType string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if err := m.faults.delay(r.Context()); err != nil {
+			// The client canceled the request
+			log.Printf("%s %s: %s", r.Method, r.URL.Path, err)
+			return
+		}
+
+		var key string
+		switch r.Method {
+		case http.MethodGet:
+			key = "default"
+		case http.MethodPost:
+			var err error
+			key, err = m.fixtureKey(r)
+			if err != nil {
+				writeError(w, r, err)
+				return
+			}
+		default:
+			writeError(w, r, server.NewError(http.StatusMethodNotAllowed, "method %s not allowed", r.Method))
+			return
+		}
+
+		if err := m.faults.inject(); err != nil {
+			writeError(w, r, err)
+			return
+		}
+
+		dir := filepath.Join(m.fixturesDir, filepath.FromSlash(path))
+		fixtureErr, err := readErrorFixture(filepath.Join(dir, key+".error.json"))
+		if err != nil {
+			writeError(w, r, err)
+			return
+		}
+		if fixtureErr != nil {
+			writeError(w, r, fixtureErr)
+			return
+		}
+		data, err := os.ReadFile(filepath.Join(dir, key+ext))
+		if errors.Is(err, fs.ErrNotExist) {
+			data, err = os.ReadFile(filepath.Join(dir, "default"+ext))
+		}
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				err = server.NewError(http.StatusNotFound, "no fixture %s or default%s for %s", key+ext, ext, path)
+			}
+			writeError(w, r, err)
+			return
+		}
+		log.Printf("%s %s: fixture %s", r.Method, r.URL.Path, key)
+		w.Header().Set("Content-Type", contentType)
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	}
+}
+
+// fixtureKey returns the X-Document-ID for the Domonda endpoints
+// or the SHA-256 hash of the uploaded document
+func (m *mockServer) fixtureKey(r *http.Request) (string, error) {
+	if documentID := r.Header.Get("X-Document-ID"); documentID != "" {
+		apiKey := r.Header.Get("X-Domonda-API-Key")
+		if apiKey == "" || (m.domondaAPIKey != "" && apiKey != m.domondaAPIKey) {
+			return "", server.NewError(http.StatusUnauthorized, "invalid X-Domonda-API-Key")
+		}
+		id, err := uu.IDFromString(documentID)
+		if err != nil {
+			return "", server.NewError(http.StatusBadRequest, "invalid X-Document-ID: %s", err)
+		}
+		return id.String(), nil
+	}
+
+	var document io.Reader = r.Body
+	if mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType == "multipart/form-data" {
+		file, _, err := r.FormFile("document")
+		if err != nil {
+			return "", server.NewError(http.StatusBadRequest, "missing X-Document-ID header or document form file: %s", err)
+		}
+		defer file.Close()
+		document = file
+	}
+	hash := sha256.New()
+	n, err := io.Copy(hash, document)
+	if err != nil {
+		return "", server.NewError(http.StatusBadRequest, "failed to read document: %s", err)
+	}
+	if n == 0 {
+		return "", server.NewError(http.StatusBadRequest, "empty document")
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// readErrorFixture returns the error of the fixture file
+// or nil if the file does not exist
+func readErrorFixture(filename string) (*server.Error, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	e := new(server.Error)
+	err = json.Unmarshal(data, e)
+	if err != nil {
+		return nil, fmt.Errorf("invalid error fixture %s: %w", filename, err)
+	}
+	if e.Code == 0 {
+		e.Code = http.StatusInternalServerError
+	}
+	return e, nil
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, err error) {
+	log.Printf("%s %s: %s", r.Method, r.URL.Path, err)
+	server.WriteError(w, err)
+}
+
+// faultInjector adds latency and random errors to responses
+type faultInjector struct {
+	errorRate     float64
+	errorCode     int
+	latency       time.Duration
+	latencyJitter time.Duration
+}
+
+// delay waits for the latency with jitter
+// or returns the error of the context if it is done before
+func (f *faultInjector) delay(ctx context.Context) error {
+	d := f.latency
+	if f.latencyJitter > 0 {
+		d += rand.N(f.latencyJitter)
+	}
+	if d <= 0 {
+		return nil
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(d):
+		return nil
+	}
+}
+
+func (f *faultInjector) inject() error {
+	if f.errorRate > 0 && rand.Float64() < f.errorRate {
+		return server.NewError(f.errorCode, "injected error")
+	}
+	return nil
+}
@@ -9,7 +9,9 @@ import (
 
 	"github.com/invopop/jsonschema"
 
+	"github.com/docvibe-ai/api/go/document"
 	"github.com/docvibe-ai/api/go/invoicing"
+	"github.com/docvibe-ai/api/go/realestate"
 )
 
 func main() {
@@ -29,29 +31,51 @@ func main() {
 	if err != nil {
 		fmt.Println(err)
 	}
+	err = createSchema(
+		document.Document{},
+		"github.com/docvibe-ai/api/go/document",     // importPath
+		"text-and-page-images-document.schema.json", // schemaFilename
+	)
+	if err != nil {
+		fmt.Println(err)
+	}
+	err = createSchema(
+		realestate.UtilityInvoice{},
+		"github.com/docvibe-ai/api/go/realestate", // importPath
+		"utility-invoice.schema.json",             // schemaFilename
+		"github.com/docvibe-ai/api/go/invoicing",  // Go comments of embedded invoicing types
+	)
+	if err != nil {
+		fmt.Println(err)
+	}
 }
 
-func createSchema(val any, importPath, schemaFilename string) error {
+// createSchema writes the JSON schema of val to the schema directory.
+// Go comments of the package importPath and of the optional
+// commentImportPaths are used as schema descriptions.
+func createSchema(val any, importPath, schemaFilename string, commentImportPaths ...string) error {
 	// Get absolute path to repo directory before changing directory
 	repoDir, err := filepath.Abs("../..")
 	if err != nil {
 		return fmt.Errorf("failed to get absolute path of repo directory: %w", err)
 	}
 
-	// reflector.AddGoComments needs to be called from the directory of the reflected package
-	packageDir := filepath.Join(repoDir, strings.TrimPrefix(importPath, "github.com/docvibe-ai/api/"))
-	err = os.Chdir(packageDir)
-	if err != nil {
-		return fmt.Errorf("failed to change directory to package path %s: %w", packageDir, err)
-	}
 	reflector := &jsonschema.Reflector{
 		Anonymous:      true,
 		ExpandedStruct: true,
 		DoNotReference: true,
 	}
-	err = reflector.AddGoComments(importPath, ".")
-	if err != nil {
-		return fmt.Errorf("failed to parse Go comments: %w", err)
+	for _, path := range append([]string{importPath}, commentImportPaths...) {
+		// reflector.AddGoComments needs to be called from the directory of the package
+		packageDir := filepath.Join(repoDir, strings.TrimPrefix(path, "github.com/docvibe-ai/api/"))
+		err = os.Chdir(packageDir)
+		if err != nil {
+			return fmt.Errorf("failed to change directory to package path %s: %w", packageDir, err)
+		}
+		err = reflector.AddGoComments(path, ".")
+		if err != nil {
+			return fmt.Errorf("failed to parse Go comments of %s: %w", path, err)
+		}
 	}
 
 	schema := reflector.Reflect(val)
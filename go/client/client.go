@@ -0,0 +1,87 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DefaultBaseURL is the URL of the DocVibe production server
+const DefaultBaseURL = "https://api.docvibe.ai"
+
+// Client calls the DocVibe Engine API.
+// The zero value is not usable, use New to create a Client.
+type Client struct {
+	// BaseURL of the API without trailing slash,
+	// can be set to the URL of an httptest.Server for testing
+	BaseURL string
+	// HTTPClient used for all requests
+	HTTPClient *http.Client
+	// DomondaAPIKey is sent as X-Domonda-API-Key header
+	// to the Domonda integration endpoints
+	DomondaAPIKey string
+}
+
+// New returns a Client for the API at baseURL.
+// If baseURL is empty then DefaultBaseURL is used.
+func New(baseURL string) *Client {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+	return &Client{
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
+		HTTPClient: http.DefaultClient,
+	}
+}
+
+// NewDomonda returns a Client for the Domonda integration endpoints
+// of the API at baseURL using the passed Domonda API key.
+// If baseURL is empty then DefaultBaseURL is used.
+func NewDomonda(baseURL, apiKey string) *Client {
+	c := New(baseURL)
+	c.DomondaAPIKey = apiKey
+	return c
+}
+
+// do sends the request and returns the response body
+// if the response has the status 200 OK.
+// Any other status is returned as *Error.
+func (c *Client) do(req *http.Request) ([]byte, error) {
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	response, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	body, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response body of %s %s: %w", req.Method, req.URL, err)
+	}
+	if response.StatusCode != http.StatusOK {
+		return nil, newError(response.StatusCode, body)
+	}
+	return body, nil
+}
+
+func (c *Client) newRequest(ctx context.Context, method, path string, body io.Reader) (*http.Request, error) {
+	return http.NewRequestWithContext(ctx, method, c.BaseURL+path, body)
+}
+
+func (c *Client) doJSON(req *http.Request, result any) error {
+	body, err := c.do(req)
+	if err != nil {
+		return err
+	}
+	err = json.Unmarshal(body, result)
+	if err != nil {
+		return fmt.Errorf("failed to decode JSON response of %s %s: %w", req.Method, req.URL, err)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/domonda/go-types/uu"
+
+	"github.com/docvibe-ai/api/go/invoicing"
+	"github.com/docvibe-ai/api/go/realestate"
+)
+
+// DomondaExtractInvoice extracts the invoice data of a document stored in Domonda.
+// The returned invoice is normalized and issues describes
+// what was corrected by the normalization.
+func (c *Client) DomondaExtractInvoice(ctx context.Context, documentID uu.ID) (inv *invoicing.Invoice, issues error, err error) {
+	err = c.doDomondaJSON(ctx, "/domonda/extract/invoice", documentID, &inv)
+	if err != nil {
+		return nil, nil, err
+	}
+	if inv == nil {
+		return nil, nil, errors.New("response contains no invoice")
+	}
+	return inv, inv.Normalize(), nil
+}
+
+// DomondaExtractAccountingInvoice extracts the invoice data
+// including accounting entries of a document stored in Domonda.
+// The returned invoice is normalized and issues describes
+// what was corrected by the normalization.
+func (c *Client) DomondaExtractAccountingInvoice(ctx context.Context, documentID uu.ID) (inv *invoicing.AccountingInvoice, issues error, err error) {
+	err = c.doDomondaJSON(ctx, "/domonda/extract/accounting-invoice", documentID, &inv)
+	if err != nil {
+		return nil, nil, err
+	}
+	if inv == nil {
+		return nil, nil, errors.New("response contains no accounting invoice")
+	}
+	return inv, inv.Normalize(), nil
+}
+
+// DomondaExtractRealEstateInvoice extracts the real estate invoice data
+// including §35a amounts and identified objects of a document stored in Domonda.
+// The returned invoice is normalized and issues describes
+// what was corrected by the normalization.
+func (c *Client) DomondaExtractRealEstateInvoice(ctx context.Context, documentID uu.ID) (inv *realestate.Invoice, issues error, err error) {
+	err = c.doDomondaJSON(ctx, "/domonda/extract/real-estate-invoice", documentID, &inv)
+	if err != nil {
+		return nil, nil, err
+	}
+	if inv == nil || inv.AccountingInvoice == nil {
+		return nil, nil, errors.New("response contains no real estate invoice")
+	}
+	return inv, inv.Normalize(), nil
+}
+
+// DomondaIdentifyRealEstateObject returns the real estate objects
+// of the client's portfolio that are mentioned in a document stored in Domonda.
+func (c *Client) DomondaIdentifyRealEstateObject(ctx context.Context, documentID uu.ID) ([]*realestate.Object, error) {
+	var result []*realestate.Object
+	err := c.doDomondaJSON(ctx, "/domonda/extract/identify-real-estate-object", documentID, &result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (c *Client) doDomondaJSON(ctx context.Context, path string, documentID uu.ID, result any) error {
+	if c.DomondaAPIKey == "" {
+		return errors.New("missing Domonda API key")
+	}
+	req, err := c.newRequest(ctx, http.MethodPost, path, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("X-Document-ID", documentID.String())
+	req.Header.Set("X-Domonda-API-Key", c.DomondaAPIKey)
+	return c.doJSON(req, result)
+}
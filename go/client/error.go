@@ -0,0 +1,46 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// Error is the JSON error response of the API
+type Error struct {
+	// Error message
+	Message string `json:"error"`
+	// HTTP status code
+	Code int `json:"code,omitempty"`
+}
+
+func newError(statusCode int, body []byte) *Error {
+	e := new(Error)
+	if json.Unmarshal(body, e) != nil || e.Message == "" {
+		e.Message = strings.TrimSpace(string(body))
+		if e.Message == "" {
+			e.Message = http.StatusText(statusCode)
+		}
+	}
+	if e.Code == 0 {
+		e.Code = statusCode
+	}
+	return e
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.Code, http.StatusText(e.Code), e.Message)
+}
+
+// StatusCode returns the HTTP status code of err
+// if it is or wraps an *Error, else zero.
+func StatusCode(err error) int {
+	var e *Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return 0
+}
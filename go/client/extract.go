@@ -0,0 +1,111 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+
+	"github.com/docvibe-ai/api/go/document"
+	"github.com/docvibe-ai/api/go/realestate"
+)
+
+// IdentifyRealEstateObject returns the objects
+// from the passed candidates that are mentioned in the document.
+func (c *Client) IdentifyRealEstateObject(ctx context.Context, document io.Reader, objects []*realestate.Object) ([]*realestate.Object, error) {
+	objectsJSON, err := json.Marshal(objects)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode real estate objects: %w", err)
+	}
+
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	err = writeFormFile(form, "document", "document", "application/octet-stream", document)
+	if err != nil {
+		return nil, err
+	}
+	err = writeFormFile(form, "objects", "objects.json", "application/json", bytes.NewReader(objectsJSON))
+	if err != nil {
+		return nil, err
+	}
+	err = form.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := c.newRequest(ctx, http.MethodPost, "/extract/identify-real-estate-object", &body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+
+	var result []*realestate.Object
+	err = c.doJSON(req, &result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ExtractSection35aInvoiceAmounts returns the amounts of the invoice document
+// that are tax deductible according to German §35a EStG.
+func (c *Client) ExtractSection35aInvoiceAmounts(ctx context.Context, document io.Reader) ([]*realestate.Section35aInvoiceAmount, error) {
+	req, err := c.newDocumentRequest(ctx, "/extract/section-35a-invoice-amounts", document)
+	if err != nil {
+		return nil, err
+	}
+	var result []*realestate.Section35aInvoiceAmount
+	err = c.doJSON(req, &result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// ExtractTextAndPageImages returns a ZIP file
+// containing the text and the page images of the document.
+func (c *Client) ExtractTextAndPageImages(ctx context.Context, document io.Reader) ([]byte, error) {
+	req, err := c.newDocumentRequest(ctx, "/extract/text-and-page-images", document)
+	if err != nil {
+		return nil, err
+	}
+	return c.do(req)
+}
+
+// ExtractTextAndPageImagesZIP returns a document.ZIPReader for the ZIP file
+// containing the text and the page images of the document.
+func (c *Client) ExtractTextAndPageImagesZIP(ctx context.Context, doc io.Reader) (*document.ZIPReader, error) {
+	data, err := c.ExtractTextAndPageImages(ctx, doc)
+	if err != nil {
+		return nil, err
+	}
+	return document.NewZIPReaderBytes(data)
+}
+
+func (c *Client) newDocumentRequest(ctx context.Context, path string, document io.Reader) (*http.Request, error) {
+	req, err := c.newRequest(ctx, http.MethodPost, path, document)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	return req, nil
+}
+
+func writeFormFile(form *multipart.Writer, fieldName, filename, contentType string, content io.Reader) error {
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name=%q; filename=%q`, fieldName, filename))
+	header.Set("Content-Type", contentType)
+	part, err := form.CreatePart(header)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(part, content)
+	if err != nil {
+		return fmt.Errorf("failed to write %s form file: %w", fieldName, err)
+	}
+	return nil
+}
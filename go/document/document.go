@@ -0,0 +1,67 @@
+package document
+
+import (
+	"errors"
+	"fmt"
+)
+
+// JSONFilename is the name of the file in the ZIP
+// returned by /extract/text-and-page-images
+// that contains the Document JSON
+const JSONFilename = "document.json"
+
+// Document is the content of the document.json file in the ZIP
+// returned by /extract/text-and-page-images
+type Document struct {
+	// Pages of the document in order
+	Pages []*Page `json:"pages"`
+}
+
+// Page of a Document
+type Page struct {
+	// Page number starting at 1
+	Number int `json:"number"`
+	// Filename of the page image in the ZIP file
+	Image string `json:"image,omitempty"`
+	// Width of the page image in pixels
+	ImageWidth int `json:"image_width,omitempty"`
+	// Height of the page image in pixels
+	ImageHeight int `json:"image_height,omitempty"`
+	// Extracted text of the page
+	Text string `json:"text"`
+}
+
+// Validate returns an error if the pages are not numbered
+// consecutively starting at 1 or if a page image filename is used twice.
+func (doc *Document) Validate() error {
+	if doc == nil {
+		return errors.New("nil Document")
+	}
+	var result error
+	images := make(map[string]int)
+	for i, page := range doc.Pages {
+		if page == nil {
+			result = errors.Join(result, fmt.Errorf("page %d is nil", i+1))
+			continue
+		}
+		if page.Number != i+1 {
+			result = errors.Join(result, fmt.Errorf("page %d has number %d", i+1, page.Number))
+		}
+		if page.Image == "" {
+			continue
+		}
+		if page.Image == JSONFilename {
+			result = errors.Join(result, fmt.Errorf("page %d image has the reserved filename %s", page.Number, JSONFilename))
+		}
+		if other, ok := images[page.Image]; ok {
+			result = errors.Join(result, fmt.Errorf("page %d uses the same image %s as page %d", page.Number, page.Image, other))
+		}
+		images[page.Image] = page.Number
+	}
+	return result
+}
+
+// PageCount returns the number of pages
+func (doc *Document) PageCount() int {
+	return len(doc.Pages)
+}
@@ -0,0 +1,120 @@
+package document
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+)
+
+// ZIPReader reads the ZIP file returned by /extract/text-and-page-images.
+// Only the document.json is read when opening the ZIP,
+// page images are read on demand by PageImage.
+type ZIPReader struct {
+	zip    *zip.Reader
+	closer io.Closer
+	doc    *Document
+}
+
+// NewZIPReader returns a ZIPReader for the ZIP file data in r with the given size.
+func NewZIPReader(r io.ReaderAt, size int64) (*ZIPReader, error) {
+	zipReader, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open ZIP: %w", err)
+	}
+	file, err := zipReader.Open(JSONFilename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s in ZIP: %w", JSONFilename, err)
+	}
+	defer file.Close()
+	doc := new(Document)
+	err = json.NewDecoder(file).Decode(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode %s: %w", JSONFilename, err)
+	}
+	err = doc.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s: %w", JSONFilename, err)
+	}
+	return &ZIPReader{zip: zipReader, doc: doc}, nil
+}
+
+// NewZIPReaderBytes returns a ZIPReader for the ZIP file data.
+func NewZIPReaderBytes(data []byte) (*ZIPReader, error) {
+	return NewZIPReader(bytes.NewReader(data), int64(len(data)))
+}
+
+// OpenZIPFile opens the ZIP file with the passed filename.
+// The returned ZIPReader has to be closed after usage.
+func OpenZIPFile(filename string) (*ZIPReader, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		return nil, errors.Join(err, file.Close())
+	}
+	r, err := NewZIPReader(file, info.Size())
+	if err != nil {
+		return nil, errors.Join(err, file.Close())
+	}
+	r.closer = file
+	return r, nil
+}
+
+// Close closes the underlying file if the ZIPReader
+// was opened by OpenZIPFile.
+func (r *ZIPReader) Close() error {
+	if r.closer == nil {
+		return nil
+	}
+	return r.closer.Close()
+}
+
+// Document returns the decoded document.json
+func (r *ZIPReader) Document() *Document {
+	return r.doc
+}
+
+// PageCount returns the number of pages
+func (r *ZIPReader) PageCount() int {
+	return r.doc.PageCount()
+}
+
+// Page returns the page with the passed number starting at 1
+func (r *ZIPReader) Page(number int) (*Page, error) {
+	if number < 1 || number > len(r.doc.Pages) {
+		return nil, fmt.Errorf("page number %d out of range 1 to %d", number, len(r.doc.Pages))
+	}
+	return r.doc.Pages[number-1], nil
+}
+
+// PageText returns the text of the page with the passed number starting at 1
+func (r *ZIPReader) PageText(number int) (string, error) {
+	page, err := r.Page(number)
+	if err != nil {
+		return "", err
+	}
+	return page.Text, nil
+}
+
+// PageImage opens the image of the page with the passed number starting at 1.
+// The returned io.ReadCloser has to be closed after reading.
+func (r *ZIPReader) PageImage(number int) (io.ReadCloser, error) {
+	page, err := r.Page(number)
+	if err != nil {
+		return nil, err
+	}
+	if page.Image == "" {
+		return nil, fmt.Errorf("page %d has no image", number)
+	}
+	file, err := r.zip.Open(page.Image)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open image of page %d: %w", number, err)
+	}
+	return file, nil
+}
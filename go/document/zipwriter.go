@@ -0,0 +1,70 @@
+package document
+
+import (
+	"archive/zip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+)
+
+// ZIPWriter writes a ZIP file in the format
+// returned by /extract/text-and-page-images.
+// Pages are added in order with AddPage
+// and Close writes the document.json.
+type ZIPWriter struct {
+	zip *zip.Writer
+	doc Document
+}
+
+// NewZIPWriter returns a ZIPWriter writing to w
+func NewZIPWriter(w io.Writer) *ZIPWriter {
+	return &ZIPWriter{zip: zip.NewWriter(w)}
+}
+
+// AddPage adds the next page with its text and an image.
+// The image file extension like ".png" or ".jpg" is used
+// for the filename of the image in the ZIP.
+// If image is nil, then no page image is written.
+// The image width and height are optional and can be zero.
+func (w *ZIPWriter) AddPage(text string, image io.Reader, imageExt string, imageWidth, imageHeight int) error {
+	page := &Page{
+		Number:      len(w.doc.Pages) + 1,
+		ImageWidth:  imageWidth,
+		ImageHeight: imageHeight,
+		Text:        text,
+	}
+	if image != nil {
+		page.Image = fmt.Sprintf("page-%d%s", page.Number, imageExt)
+		if path.Base(page.Image) != page.Image {
+			return fmt.Errorf("invalid image file extension %q", imageExt)
+		}
+		file, err := w.zip.CreateHeader(&zip.FileHeader{Name: page.Image, Method: zip.Store})
+		if err != nil {
+			return err
+		}
+		_, err = io.Copy(file, image)
+		if err != nil {
+			return fmt.Errorf("failed to write image of page %d: %w", page.Number, err)
+		}
+	}
+	w.doc.Pages = append(w.doc.Pages, page)
+	return nil
+}
+
+// Close writes the document.json and closes the ZIP.
+// It does not close the underlying io.Writer.
+func (w *ZIPWriter) Close() error {
+	file, err := w.zip.Create(JSONFilename)
+	if err != nil {
+		return errors.Join(err, w.zip.Close())
+	}
+	encoder := json.NewEncoder(file)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(&w.doc)
+	if err != nil {
+		return errors.Join(fmt.Errorf("failed to write %s: %w", JSONFilename, err), w.zip.Close())
+	}
+	return w.zip.Close()
+}
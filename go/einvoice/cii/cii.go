@@ -0,0 +1,301 @@
+package cii
+
+import (
+	"encoding/xml"
+	"strings"
+	"time"
+
+	"github.com/domonda/go-types/date"
+
+	"github.com/docvibe-ai/api/go/einvoice"
+)
+
+const (
+	// GuidelineEN16931 is the specification identifier
+	// of the EN 16931 profile (ZUGFeRD/Factur-X EN 16931, formerly COMFORT)
+	GuidelineEN16931 = "urn:cen.eu:en16931:2017"
+
+	NamespaceRSM = "urn:un:unece:uncefact:data:standard:CrossIndustryInvoice:100"
+	NamespaceRAM = "urn:un:unece:uncefact:data:standard:ReusableAggregateBusinessInformationEntity:100"
+	NamespaceUDT = "urn:un:unece:uncefact:data:standard:UnqualifiedDataType:100"
+	NamespaceQDT = "urn:un:unece:uncefact:data:standard:QualifiedDataType:100"
+)
+
+// Document type codes (UNTDID 1001)
+const (
+	TypeCodeInvoice    = "380"
+	TypeCodeCreditNote = "381"
+)
+
+// The XML types use element names without namespace
+// so that unmarshalling accepts any namespace prefix.
+// Marshal adds the CII namespace prefixes afterwards.
+
+type crossIndustryInvoice struct {
+	XMLName     xml.Name          `xml:"CrossIndustryInvoice"`
+	Context     documentContext   `xml:"ExchangedDocumentContext"`
+	Document    exchangedDocument `xml:"ExchangedDocument"`
+	Transaction tradeTransaction  `xml:"SupplyChainTradeTransaction"`
+}
+
+type documentContext struct {
+	GuidelineID string `xml:"GuidelineSpecifiedDocumentContextParameter>ID"`
+}
+
+type exchangedDocument struct {
+	ID            string    `xml:"ID"`
+	TypeCode      string    `xml:"TypeCode"`
+	IssueDateTime *dateTime `xml:"IssueDateTime"`
+	Notes         []note    `xml:"IncludedNote"`
+}
+
+type note struct {
+	Content string `xml:"Content"`
+}
+
+type dateTime struct {
+	DateTimeString dateTimeString `xml:"DateTimeString"`
+}
+
+type dateTimeString struct {
+	Format string `xml:"format,attr"`
+	Value  string `xml:",chardata"`
+}
+
+type tradeTransaction struct {
+	Items      []lineItem       `xml:"IncludedSupplyChainTradeLineItem"`
+	Agreement  headerAgreement  `xml:"ApplicableHeaderTradeAgreement"`
+	Delivery   headerDelivery   `xml:"ApplicableHeaderTradeDelivery"`
+	Settlement headerSettlement `xml:"ApplicableHeaderTradeSettlement"`
+}
+
+type lineItem struct {
+	LineDocument lineDocument   `xml:"AssociatedDocumentLineDocument"`
+	Product      tradeProduct   `xml:"SpecifiedTradeProduct"`
+	Agreement    lineAgreement  `xml:"SpecifiedLineTradeAgreement"`
+	Delivery     lineDelivery   `xml:"SpecifiedLineTradeDelivery"`
+	Settlement   lineSettlement `xml:"SpecifiedLineTradeSettlement"`
+}
+
+type lineDocument struct {
+	LineID string `xml:"LineID"`
+	Notes  []note `xml:"IncludedNote"`
+}
+
+type tradeProduct struct {
+	SellerAssignedID string `xml:"SellerAssignedID,omitempty"`
+	Name             string `xml:"Name"`
+}
+
+type lineAgreement struct {
+	NetPrice tradePrice `xml:"NetPriceProductTradePrice"`
+}
+
+type tradePrice struct {
+	ChargeAmount string `xml:"ChargeAmount"`
+}
+
+type lineDelivery struct {
+	BilledQuantity billedQuantity `xml:"BilledQuantity"`
+}
+
+type billedQuantity struct {
+	UnitCode string `xml:"unitCode,attr"`
+	Value    string `xml:",chardata"`
+}
+
+type lineSettlement struct {
+	Tax        tradeTax          `xml:"ApplicableTradeTax"`
+	Period     *period           `xml:"BillingSpecifiedPeriod"`
+	Allowances []allowanceCharge `xml:"SpecifiedTradeAllowanceCharge"`
+	Summation  lineSummation     `xml:"SpecifiedTradeSettlementLineMonetarySummation"`
+}
+
+type lineSummation struct {
+	LineTotalAmount string `xml:"LineTotalAmount"`
+}
+
+type tradeTax struct {
+	CalculatedAmount      string `xml:"CalculatedAmount,omitempty"`
+	TypeCode              string `xml:"TypeCode"`
+	ExemptionReason       string `xml:"ExemptionReason,omitempty"`
+	BasisAmount           string `xml:"BasisAmount,omitempty"`
+	CategoryCode          string `xml:"CategoryCode"`
+	RateApplicablePercent string `xml:"RateApplicablePercent,omitempty"`
+}
+
+type allowanceCharge struct {
+	ChargeIndicator    indicator `xml:"ChargeIndicator"`
+	CalculationPercent string    `xml:"CalculationPercent,omitempty"`
+	BasisAmount        string    `xml:"BasisAmount,omitempty"`
+	ActualAmount       string    `xml:"ActualAmount"`
+	Reason             string    `xml:"Reason,omitempty"`
+	CategoryTradeTax   *tradeTax `xml:"CategoryTradeTax"`
+}
+
+type indicator struct {
+	Indicator bool `xml:"Indicator"`
+}
+
+type headerAgreement struct {
+	BuyerReference string              `xml:"BuyerReference,omitempty"`
+	Seller         tradeParty          `xml:"SellerTradeParty"`
+	Buyer          tradeParty          `xml:"BuyerTradeParty"`
+	BuyerOrder     *referencedDocument `xml:"BuyerOrderReferencedDocument"`
+	Contract       *referencedDocument `xml:"ContractReferencedDocument"`
+}
+
+type referencedDocument struct {
+	IssuerAssignedID string `xml:"IssuerAssignedID"`
+}
+
+type tradeParty struct {
+	ID               string            `xml:"ID,omitempty"`
+	Name             string            `xml:"Name"`
+	Contact          *tradeContact     `xml:"DefinedTradeContact"`
+	Address          *tradeAddress     `xml:"PostalTradeAddress"`
+	URI              *uriCommunication `xml:"URIUniversalCommunication"`
+	TaxRegistrations []taxRegistration `xml:"SpecifiedTaxRegistration"`
+}
+
+// uriCommunication is the electronic address of a party
+// with the electronic address scheme (EAS) code
+type uriCommunication struct {
+	URIID schemeID `xml:"URIID"`
+}
+
+// newEmailURI returns the email as electronic address
+// or nil if the email is empty
+func newEmailURI(email string) *uriCommunication {
+	if email == "" {
+		return nil
+	}
+	return &uriCommunication{URIID: schemeID{SchemeID: einvoice.ElectronicAddressSchemeEmail, Value: email}}
+}
+
+// email returns the electronic address if it is an email address
+func (u *uriCommunication) email() (string, bool) {
+	if u == nil || strings.TrimSpace(u.URIID.SchemeID) != einvoice.ElectronicAddressSchemeEmail {
+		return "", false
+	}
+	mail := strings.TrimSpace(u.URIID.Value)
+	return mail, mail != ""
+}
+
+type tradeContact struct {
+	Phone *universalCommunication `xml:"TelephoneUniversalCommunication"`
+	Email *universalCommunication `xml:"EmailURIUniversalCommunication"`
+}
+
+type universalCommunication struct {
+	CompleteNumber string `xml:"CompleteNumber,omitempty"`
+	URIID          string `xml:"URIID,omitempty"`
+}
+
+type tradeAddress struct {
+	PostcodeCode           string `xml:"PostcodeCode,omitempty"`
+	LineOne                string `xml:"LineOne,omitempty"`
+	CityName               string `xml:"CityName,omitempty"`
+	CountryID              string `xml:"CountryID,omitempty"`
+	CountrySubDivisionName string `xml:"CountrySubDivisionName,omitempty"`
+}
+
+type taxRegistration struct {
+	ID schemeID `xml:"ID"`
+}
+
+type schemeID struct {
+	SchemeID string `xml:"schemeID,attr"`
+	Value    string `xml:",chardata"`
+}
+
+// Tax registration schemes
+const (
+	schemeVATID     = "VA"
+	schemeTaxNumber = "FC"
+)
+
+type headerDelivery struct {
+	ShipTo         *shipToParty        `xml:"ShipToTradeParty"`
+	DespatchAdvice *referencedDocument `xml:"DespatchAdviceReferencedDocument"`
+}
+
+type shipToParty struct {
+	Address *tradeAddress `xml:"PostalTradeAddress"`
+}
+
+type headerSettlement struct {
+	PaymentReference string            `xml:"PaymentReference,omitempty"`
+	Currency         string            `xml:"InvoiceCurrencyCode"`
+	PaymentMeans     []paymentMeans    `xml:"SpecifiedTradeSettlementPaymentMeans"`
+	Taxes            []tradeTax        `xml:"ApplicableTradeTax"`
+	Period           *period           `xml:"BillingSpecifiedPeriod"`
+	Allowances       []allowanceCharge `xml:"SpecifiedTradeAllowanceCharge"`
+	PaymentTerms     []paymentTerms    `xml:"SpecifiedTradePaymentTerms"`
+	Summation        headerSummation   `xml:"SpecifiedTradeSettlementHeaderMonetarySummation"`
+}
+
+type paymentMeans struct {
+	TypeCode         string            `xml:"TypeCode"`
+	PayeeAccount     *financialAccount `xml:"PayeePartyCreditorFinancialAccount"`
+	PayeeInstitution *financialInst    `xml:"PayeeSpecifiedCreditorFinancialInstitution"`
+}
+
+type financialAccount struct {
+	IBANID string `xml:"IBANID"`
+}
+
+type financialInst struct {
+	BICID string `xml:"BICID"`
+}
+
+type period struct {
+	Start *dateTime `xml:"StartDateTime"`
+	End   *dateTime `xml:"EndDateTime"`
+}
+
+type paymentTerms struct {
+	Description          string    `xml:"Description,omitempty"`
+	DueDate              *dateTime `xml:"DueDateDateTime"`
+	DirectDebitMandateID string    `xml:"DirectDebitMandateID,omitempty"`
+}
+
+type headerSummation struct {
+	LineTotalAmount      string             `xml:"LineTotalAmount"`
+	ChargeTotalAmount    string             `xml:"ChargeTotalAmount,omitempty"`
+	AllowanceTotalAmount string             `xml:"AllowanceTotalAmount,omitempty"`
+	TaxBasisTotalAmount  string             `xml:"TaxBasisTotalAmount"`
+	TaxTotalAmount       amountWithCurrency `xml:"TaxTotalAmount"`
+	GrandTotalAmount     string             `xml:"GrandTotalAmount"`
+	TotalPrepaidAmount   string             `xml:"TotalPrepaidAmount,omitempty"`
+	DuePayableAmount     string             `xml:"DuePayableAmount"`
+}
+
+type amountWithCurrency struct {
+	CurrencyID string `xml:"currencyID,attr"`
+	Value      string `xml:",chardata"`
+}
+
+// dateFormat102 is the CII date format code for YYYYMMDD
+const dateFormat102 = "102"
+
+func newDateTime(d date.Date) *dateTime {
+	return &dateTime{
+		DateTimeString: dateTimeString{
+			Format: dateFormat102,
+			Value:  strings.ReplaceAll(d.String(), "-", ""),
+		},
+	}
+}
+
+// date returns the date of a date time string in format 102
+func (dt *dateTime) date() (date.Date, bool) {
+	if dt == nil {
+		return "", false
+	}
+	t, err := time.Parse("20060102", strings.TrimSpace(dt.DateTimeString.Value))
+	if err != nil {
+		return "", false
+	}
+	return date.Date(t.Format(time.DateOnly)), true
+}
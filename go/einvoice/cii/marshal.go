@@ -0,0 +1,400 @@
+package cii
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/domonda/go-types/money"
+
+	"github.com/docvibe-ai/api/go/einvoice"
+	"github.com/docvibe-ai/api/go/invoicing"
+)
+
+// Marshal returns the invoice as UN/CEFACT Cross Industry Invoice XML
+// in the EN 16931 profile as used by ZUGFeRD and Factur-X.
+//
+// Fields of the invoice that can't be represented in the EN 16931 profile
+// are returned as unmapped with their JSON path like "order_date"
+// or "items[2].delivery_id".
+// Marshal does not validate the invoice against the business rules of EN 16931.
+func Marshal(inv *invoicing.Invoice) (data []byte, unmapped []string, err error) {
+	if inv == nil {
+		return nil, nil, errors.New("invoice is nil")
+	}
+	m := &marshaller{inv: inv}
+	doc := m.crossIndustryInvoice()
+	data, err = xml.Marshal(doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err = einvoice.AddNamespacePrefixes(data, namespaceAttrs, namespacePrefix)
+	if err != nil {
+		return nil, nil, err
+	}
+	return append([]byte(xml.Header), data...), m.unmapped, nil
+}
+
+type marshaller struct {
+	inv      *invoicing.Invoice
+	unmapped []string
+}
+
+func (m *marshaller) unmappedIf(notNull bool, path string) {
+	if notNull {
+		m.unmapped = append(m.unmapped, path)
+	}
+}
+
+func (m *marshaller) crossIndustryInvoice() *crossIndustryInvoice {
+	inv := m.inv
+	doc := &crossIndustryInvoice{
+		Context: documentContext{GuidelineID: GuidelineEN16931},
+		Document: exchangedDocument{
+			ID:       inv.InvoiceID.String(),
+			TypeCode: TypeCodeInvoice,
+		},
+	}
+	if inv.CreditNote {
+		doc.Document.TypeCode = TypeCodeCreditNote
+	}
+	if inv.IssueDate.IsNotNull() {
+		doc.Document.IssueDateTime = newDateTime(inv.IssueDate.Get())
+	}
+	for _, n := range inv.Notes {
+		doc.Document.Notes = append(doc.Document.Notes, note{Content: n.String()})
+	}
+	m.unmapped = append(m.unmapped, einvoice.UnmappedFields(inv)...)
+
+	var lineTotal money.Amount
+	for i, item := range inv.Items {
+		if item == nil {
+			continue
+		}
+		line := m.lineItem(i, item)
+		doc.Transaction.Items = append(doc.Transaction.Items, line)
+		amount, _ := einvoice.ParseAmount(line.Settlement.Summation.LineTotalAmount)
+		lineTotal += amount
+	}
+	doc.Transaction.Agreement = m.headerAgreement()
+	doc.Transaction.Delivery = m.headerDelivery()
+	doc.Transaction.Settlement = m.headerSettlement(lineTotal.RoundToCents())
+	return doc
+}
+
+func (m *marshaller) lineItem(index int, item *invoicing.InvoiceItem) lineItem {
+	var (
+		inv  = m.inv
+		path = "items[" + strconv.Itoa(index) + "]"
+		sign = einvoice.ItemSign(inv, item)
+		line lineItem
+	)
+	line.LineDocument.LineID = item.PositionNumber.StringOr(strconv.Itoa(index + 1))
+	line.Product.Name = item.Description.String()
+	line.Product.SellerAssignedID = item.ProductID.String()
+	m.unmappedIf(item.OrderID.IsNotNull() && item.OrderID != inv.OrderID, path+".order_id")
+	m.unmappedIf(item.DeliveryID.IsNotNull(), path+".delivery_id")
+	m.unmappedIf(item.Currency.IsNotNull() && item.Currency != inv.Currency, path+".currency")
+
+	quantity := 1.0
+	if item.Quantity.IsNotNull() {
+		quantity = item.Quantity.Get()
+	}
+	unitCode, ok := einvoice.UnitCode(item.Unit.String())
+	m.unmappedIf(!ok && item.Unit.IsNotNull(), path+".unit")
+	line.Delivery.BilledQuantity = billedQuantity{
+		UnitCode: unitCode,
+		Value:    einvoice.FormatDecimal(float64(sign) * quantity),
+	}
+
+	subtotal := item.Subtotal.Get()
+	switch {
+	case item.UnitPrice.IsNotNull():
+		line.Agreement.NetPrice.ChargeAmount = einvoice.FormatDecimal(float64(item.UnitPrice.Get()))
+	case item.Subtotal.IsNotNull() && quantity != 0 && item.DiscountPercent.IsNull() && item.DiscountAmount.IsNull():
+		line.Agreement.NetPrice.ChargeAmount = einvoice.FormatDecimal(float64(subtotal) / quantity)
+	}
+	if item.Subtotal.IsNull() && item.UnitPrice.IsNotNull() {
+		subtotal = money.Amount(quantity) * item.UnitPrice.Get()
+	}
+
+	if item.DiscountPercent.IsNotNull() || item.DiscountAmount.IsNotNull() {
+		base := money.Amount(quantity) * item.UnitPrice.Get()
+		allowance := allowanceCharge{
+			ActualAmount: einvoice.FormatAmount(sign * item.DiscountAmount.Get()),
+		}
+		if item.DiscountPercent.IsNotNull() {
+			allowance.CalculationPercent = einvoice.FormatDecimal(float64(item.DiscountPercent.Get()))
+			if item.UnitPrice.IsNotNull() {
+				allowance.BasisAmount = einvoice.FormatAmount(sign * base)
+				if item.DiscountAmount.IsNull() {
+					allowance.ActualAmount = einvoice.FormatAmount(sign * base * money.Amount(item.DiscountPercent.Get()) / 100)
+				}
+			}
+		}
+		line.Settlement.Allowances = append(line.Settlement.Allowances, allowance)
+	}
+
+	taxPercent, hasTaxPercent := item.TaxPercent.Get(), item.TaxPercent.IsNotNull()
+	if !hasTaxPercent {
+		if subtotals := einvoice.TaxSubtotals(inv); len(subtotals) == 1 {
+			taxPercent, hasTaxPercent = subtotals[0].TaxPercent.Get(), true
+		}
+	}
+	line.Settlement.Tax = tradeTax{
+		TypeCode:     "VAT",
+		CategoryCode: string(einvoice.TaxCategoryOf(inv, taxPercent, einvoice.ExemptionReason(inv, taxPercent))),
+	}
+	if hasTaxPercent {
+		line.Settlement.Tax.RateApplicablePercent = einvoice.FormatDecimal(float64(taxPercent))
+	}
+	line.Settlement.Summation.LineTotalAmount = einvoice.FormatAmount(sign * subtotal)
+	return line
+}
+
+func (m *marshaller) headerAgreement() headerAgreement {
+	inv := m.inv
+	a := headerAgreement{
+		BuyerReference: inv.BuyerReference.String(),
+		Seller: tradeParty{
+			Name:    inv.Issuer.String(),
+			Address: newTradeAddress(inv.IssuerAddress),
+			URI:     newEmailURI(inv.IssuerEmail.String()),
+		},
+		Buyer: tradeParty{
+			ID:      inv.CustomerID.String(),
+			Name:    inv.Customer.String(),
+			Address: newTradeAddress(inv.CustomerBillingAddress),
+			URI:     newEmailURI(inv.CustomerEmail.String()),
+		},
+	}
+	if inv.IssuerVATID.IsNotNull() {
+		a.Seller.TaxRegistrations = append(a.Seller.TaxRegistrations, taxRegistration{ID: schemeID{SchemeID: schemeVATID, Value: inv.IssuerVATID.String()}})
+	}
+	if inv.IssuerTaxNumber.IsNotNull() {
+		a.Seller.TaxRegistrations = append(a.Seller.TaxRegistrations, taxRegistration{ID: schemeID{SchemeID: schemeTaxNumber, Value: inv.IssuerTaxNumber.String()}})
+	}
+	if inv.CustomerVATID.IsNotNull() {
+		a.Buyer.TaxRegistrations = append(a.Buyer.TaxRegistrations, taxRegistration{ID: schemeID{SchemeID: schemeVATID, Value: inv.CustomerVATID.String()}})
+	}
+	if inv.CustomerPhone.IsNotNull() || inv.CustomerEmail.IsNotNull() {
+		a.Buyer.Contact = &tradeContact{}
+		if inv.CustomerPhone.IsNotNull() {
+			a.Buyer.Contact.Phone = &universalCommunication{CompleteNumber: inv.CustomerPhone.String()}
+		}
+		if inv.CustomerEmail.IsNotNull() {
+			a.Buyer.Contact.Email = &universalCommunication{URIID: inv.CustomerEmail.String()}
+		}
+	}
+	if inv.OrderID.IsNotNull() {
+		a.BuyerOrder = &referencedDocument{IssuerAssignedID: inv.OrderID.String()}
+	}
+	m.unmappedIf(inv.OrderDate.IsNotNull(), "order_date")
+	if inv.ContractID.IsNotNull() {
+		a.Contract = &referencedDocument{IssuerAssignedID: inv.ContractID.String()}
+	}
+	return a
+}
+
+func newTradeAddress(address *invoicing.Address) *tradeAddress {
+	if address == nil || *address == (invoicing.Address{}) {
+		return nil
+	}
+	return &tradeAddress{
+		PostcodeCode:           address.PostalCode.String(),
+		LineOne:                address.Street.String(),
+		CityName:               address.City.String(),
+		CountryID:              address.Country.String(),
+		CountrySubDivisionName: address.State.String(),
+	}
+}
+
+func (m *marshaller) headerDelivery() headerDelivery {
+	inv := m.inv
+	var d headerDelivery
+	if address := newTradeAddress(inv.CustomerShippingAddress); address != nil {
+		d.ShipTo = &shipToParty{Address: address}
+	}
+	for i, id := range inv.DeliveryNoteIDs {
+		if i == 0 {
+			d.DespatchAdvice = &referencedDocument{IssuerAssignedID: id.String()}
+			continue
+		}
+		// EN 16931 allows only one despatch advice reference
+		m.unmapped = append(m.unmapped, fmt.Sprintf("delivery_note_ids[%d]", i))
+	}
+	return d
+}
+
+func (m *marshaller) headerSettlement(lineTotal money.Amount) headerSettlement {
+	inv := m.inv
+	s := headerSettlement{
+		PaymentReference: inv.PaymentReference.String(),
+		Currency:         inv.Currency.String(),
+	}
+	if means, ok := m.paymentMeans(); ok {
+		s.PaymentMeans = append(s.PaymentMeans, means)
+	}
+
+	subtotals := einvoice.TaxSubtotals(inv)
+	var taxBasis, taxTotal money.Amount
+	for _, t := range subtotals {
+		category := einvoice.TaxCategoryOf(inv, t.TaxPercent.Get(), t.ExemptionReason.String())
+		tax := tradeTax{
+			CalculatedAmount:      einvoice.FormatAmount(t.TaxAmount),
+			TypeCode:              "VAT",
+			ExemptionReason:       t.ExemptionReason.String(),
+			BasisAmount:           einvoice.FormatAmount(t.TaxableAmount),
+			CategoryCode:          string(category),
+			RateApplicablePercent: einvoice.FormatDecimal(float64(t.TaxPercent.Get())),
+		}
+		if category.IsReverseCharge() && tax.ExemptionReason == "" {
+			tax.ExemptionReason = inv.ReverseChargeClauseText.StringOr("Reverse charge")
+		}
+		s.Taxes = append(s.Taxes, tax)
+		taxBasis += t.TaxableAmount
+		taxTotal += t.TaxAmount
+	}
+	if inv.ReverseChargeClauseText.IsNotNull() && !slices.ContainsFunc(s.Taxes, func(t tradeTax) bool { return t.ExemptionReason == inv.ReverseChargeClauseText.String() }) {
+		m.unmapped = append(m.unmapped, "reverse_charge_clause_text")
+	}
+	if inv.Subtotal.IsNotNull() {
+		taxBasis = inv.Subtotal.Get()
+	}
+	if inv.Tax.IsNotNull() {
+		taxTotal = inv.Tax.Get()
+	}
+	if len(inv.Items) == 0 {
+		lineTotal = taxBasis
+	}
+
+	if inv.PeriodStart.IsNotNull() || inv.PeriodEnd.IsNotNull() {
+		s.Period = &period{}
+		if inv.PeriodStart.IsNotNull() {
+			s.Period.Start = newDateTime(inv.PeriodStart.Get())
+		}
+		if inv.PeriodEnd.IsNotNull() {
+			s.Period.End = newDateTime(inv.PeriodEnd.Get())
+		}
+	}
+
+	// A discount already deducted from the line total
+	// is represented as document level allowances per tax rate
+	s.Allowances = m.documentAllowances(lineTotal, taxBasis)
+	var allowanceTotal money.Amount
+	for _, a := range s.Allowances {
+		amount, _ := einvoice.ParseAmount(a.ActualAmount)
+		allowanceTotal += amount
+	}
+
+	terms := paymentTerms{
+		Description:          inv.PaymentTerms.String(),
+		DirectDebitMandateID: inv.DirectDebitMandateID.String(),
+	}
+	hasDiscount := inv.DiscountPercent.IsNotNull() || inv.DiscountAmount.IsNotNull()
+	if hasDiscount && len(s.Allowances) == 0 {
+		if discountTerms, ok := einvoice.DiscountTermsOf(inv); ok {
+			terms.Description = discountTerms.String() + terms.Description
+		} else {
+			m.unmappedIf(inv.DiscountPercent.IsNotNull(), "discount_percent")
+			m.unmappedIf(inv.DiscountAmount.IsNotNull(), "discount_amount")
+			m.unmappedIf(inv.DiscountUntilDate.IsNotNull(), "discount_until_date")
+		}
+	} else {
+		m.unmappedIf(inv.DiscountUntilDate.IsNotNull(), "discount_until_date")
+	}
+	if inv.DueDate.IsNotNull() {
+		terms.DueDate = newDateTime(inv.DueDate.Get())
+	}
+	if terms != (paymentTerms{}) {
+		s.PaymentTerms = append(s.PaymentTerms, terms)
+	}
+
+	grandTotal := taxBasis + taxTotal
+	if inv.Total.IsNotNull() {
+		grandTotal = inv.Total.Get()
+	}
+	s.Summation = headerSummation{
+		LineTotalAmount:     einvoice.FormatAmount(lineTotal),
+		TaxBasisTotalAmount: einvoice.FormatAmount(taxBasis),
+		TaxTotalAmount: amountWithCurrency{
+			CurrencyID: inv.Currency.String(),
+			Value:      einvoice.FormatAmount(taxTotal),
+		},
+		GrandTotalAmount: einvoice.FormatAmount(grandTotal),
+		DuePayableAmount: einvoice.FormatAmount(grandTotal),
+	}
+	if allowanceTotal != 0 {
+		s.Summation.AllowanceTotalAmount = einvoice.FormatAmount(allowanceTotal)
+	}
+	if inv.PaymentStatus.IsPaid() {
+		s.Summation.TotalPrepaidAmount = einvoice.FormatAmount(grandTotal)
+		s.Summation.DuePayableAmount = einvoice.FormatAmount(0)
+	}
+	m.unmappedIf(inv.PaidDate.IsNotNull(), "paid_date")
+	return s
+}
+
+// documentAllowances returns the einvoice.DocumentAllowances
+// of the invoice as allowance charges
+func (m *marshaller) documentAllowances(lineTotal, taxBasis money.Amount) []allowanceCharge {
+	inv := m.inv
+	var allowances []allowanceCharge
+	for _, a := range einvoice.DocumentAllowances(inv, lineTotal, taxBasis) {
+		allowance := allowanceCharge{
+			ActualAmount: einvoice.FormatAmount(a.Amount),
+			Reason:       "Discount",
+			CategoryTradeTax: &tradeTax{
+				TypeCode:              "VAT",
+				CategoryCode:          string(a.Category),
+				RateApplicablePercent: einvoice.FormatDecimal(float64(a.TaxSubtotal.TaxPercent.Get())),
+			},
+		}
+		if inv.DiscountPercent.IsNotNull() {
+			allowance.CalculationPercent = einvoice.FormatDecimal(float64(inv.DiscountPercent.Get()))
+			allowance.BasisAmount = einvoice.FormatAmount(a.BaseAmount)
+		}
+		allowances = append(allowances, allowance)
+	}
+	return allowances
+}
+
+func (m *marshaller) paymentMeans() (paymentMeans, bool) {
+	inv := m.inv
+	code := einvoice.PaymentMeansCode(inv)
+	if code == "" {
+		m.unmappedIf(inv.PaymentBIC.IsNotNull(), "payment_bic")
+		return paymentMeans{}, false
+	}
+	means := paymentMeans{TypeCode: code}
+	if inv.PaymentIBAN.IsNotNull() {
+		means.PayeeAccount = &financialAccount{IBANID: inv.PaymentIBAN.String()}
+	}
+	if inv.PaymentBIC.IsNotNull() {
+		means.PayeeInstitution = &financialInst{BICID: inv.PaymentBIC.String()}
+	}
+	return means, true
+}
+
+var namespaceAttrs = []xml.Attr{
+	einvoice.NamespaceAttr("rsm", NamespaceRSM),
+	einvoice.NamespaceAttr("ram", NamespaceRAM),
+	einvoice.NamespaceAttr("udt", NamespaceUDT),
+	einvoice.NamespaceAttr("qdt", NamespaceQDT),
+}
+
+// namespacePrefix returns the namespace prefix
+// for an element with the passed parent elements
+func namespacePrefix(parents []string, local string) string {
+	switch {
+	case len(parents) <= 1:
+		return "rsm"
+	case local == "DateTimeString" && parents[len(parents)-1] == "FormattedIssueDateTime":
+		return "qdt"
+	case local == "DateTimeString" || local == "Indicator":
+		return "udt"
+	}
+	return "ram"
+}
@@ -0,0 +1,161 @@
+package cii
+
+import (
+	"bytes"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/domonda/go-types/date"
+	"github.com/domonda/go-types/money"
+	"github.com/domonda/go-types/nullable"
+	"github.com/domonda/go-types/vat"
+
+	"github.com/docvibe-ai/api/go/einvoice/einvoicetest"
+	"github.com/docvibe-ai/api/go/invoicing"
+)
+
+// testInvoice returns an invoice that survives a round trip
+// through Marshal and Unmarshal unchanged
+func testInvoice() *invoicing.Invoice {
+	inv := einvoicetest.Invoice()
+	inv.BuyerReference = "04011000-12345-67"
+	return inv
+}
+
+func TestMarshalUnmarshal(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(inv *invoicing.Invoice)
+	}{
+		{name: "invoice", modify: func(inv *invoicing.Invoice) {}},
+		{name: "credit note", modify: func(inv *invoicing.Invoice) {
+			inv.CreditNote = true
+		}},
+		{name: "paid by bank transfer", modify: func(inv *invoicing.Invoice) {
+			inv.PaymentStatus = invoicing.PaymentStatusPaidWithBankTransfer
+		}},
+		{name: "reverse charge", modify: func(inv *invoicing.Invoice) {
+			inv.ReverseCharge = true
+			inv.ReverseChargeClauseText = nullable.TrimmedString("Steuerschuldnerschaft des Leistungsempfängers")
+			inv.CustomerVATID = vat.NullableID("ATU12345678")
+			inv.Tax = einvoicetest.Amount(0)
+			inv.Total = einvoicetest.Amount(150)
+			inv.TaxBreakdown = []invoicing.TaxSubtotal{{TaxPercent: einvoicetest.Rate(0), TaxableAmount: 150, ExemptionReason: inv.ReverseChargeClauseText}}
+			for _, item := range inv.Items {
+				item.TaxPercent = einvoicetest.Rate(0)
+			}
+		}},
+		{name: "address without country", modify: func(inv *invoicing.Invoice) {
+			inv.CustomerBillingAddress.Country = ""
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := testInvoice()
+			tt.modify(want)
+			data, unmapped, err := Marshal(want)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			if len(unmapped) > 0 {
+				t.Errorf("Marshal() unmapped = %v, want none", unmapped)
+			}
+			got, unmapped, err := Unmarshal(data)
+			if err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if len(unmapped) > 0 {
+				t.Errorf("Unmarshal() unmapped = %v, want none", unmapped)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Unmarshal(Marshal()) =\n%+v\nwant\n%+v\nXML:\n%s", got, want, data)
+			}
+		})
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	tests := []struct {
+		name         string
+		modify       func(inv *invoicing.Invoice)
+		wantUnmapped []string
+		wantXML      []string
+		notXML       []string
+	}{
+		{
+			name: "unmapped fields",
+			modify: func(inv *invoicing.Invoice) {
+				inv.Type = invoicing.InvoiceTypeOutgoing
+				inv.OrderDate = date.NullableDate("2024-01-01")
+			},
+			wantUnmapped: []string{"type", "order_date"},
+		},
+		{
+			name: "tax breakdown without tax percent",
+			modify: func(inv *invoicing.Invoice) {
+				inv.TaxBreakdown[1].TaxPercent = money.NullableRate{}
+			},
+			wantUnmapped: []string{"tax_breakdown"},
+			wantXML:      []string{"<ram:RateApplicablePercent>19</ram:RateApplicablePercent>", "<ram:RateApplicablePercent>7</ram:RateApplicablePercent>"},
+		},
+		{
+			name: "no country",
+			modify: func(inv *invoicing.Invoice) {
+				inv.CustomerBillingAddress.Country = ""
+				inv.IssuerAddress.Country = ""
+			},
+			notXML: []string{"<ram:CountryID></ram:CountryID>", "<ram:CountryID/>"},
+		},
+		{
+			name: "blended rate not a known VAT rate",
+			modify: func(inv *invoicing.Invoice) {
+				inv.TaxBreakdown = nil
+				inv.Items = []*invoicing.InvoiceItem{{Description: "Pauschale", Subtotal: einvoicetest.Amount(150)}}
+			},
+			notXML: []string{"<ram:RateApplicablePercent>15</ram:RateApplicablePercent>"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv := testInvoice()
+			tt.modify(inv)
+			data, unmapped, err := Marshal(inv)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			if !slices.Equal(unmapped, tt.wantUnmapped) {
+				t.Errorf("Marshal() unmapped = %v, want %v", unmapped, tt.wantUnmapped)
+			}
+			for _, s := range tt.wantXML {
+				if !bytes.Contains(data, []byte(s)) {
+					t.Errorf("Marshal() XML does not contain %s:\n%s", s, data)
+				}
+			}
+			for _, s := range tt.notXML {
+				if bytes.Contains(data, []byte(s)) {
+					t.Errorf("Marshal() XML contains %s:\n%s", s, data)
+				}
+			}
+		})
+	}
+}
+
+func TestUnmarshal_invalid(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+	}{
+		{name: "not XML", data: "no xml"},
+		{name: "no namespace", data: `<CrossIndustryInvoice><ExchangedDocument><ID>1</ID></ExchangedDocument></CrossIndustryInvoice>`},
+		{name: "other namespace", data: `<rsm:CrossIndustryInvoice xmlns:rsm="urn:un:unece:uncefact:data:standard:CrossIndustryInvoice:99"/>`},
+		{name: "invalid amount", data: `<rsm:CrossIndustryInvoice xmlns:rsm="` + NamespaceRSM + `" xmlns:ram="` + NamespaceRAM + `"><rsm:SupplyChainTradeTransaction><ram:ApplicableHeaderTradeSettlement><ram:SpecifiedTradeSettlementHeaderMonetarySummation><ram:GrandTotalAmount>1,5</ram:GrandTotalAmount></ram:SpecifiedTradeSettlementHeaderMonetarySummation></ram:ApplicableHeaderTradeSettlement></rsm:SupplyChainTradeTransaction></rsm:CrossIndustryInvoice>`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := Unmarshal([]byte(tt.data)); err == nil {
+				t.Errorf("Unmarshal() error = nil, want error")
+			}
+		})
+	}
+}
@@ -0,0 +1,407 @@
+package cii
+
+import (
+	"encoding/xml"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/domonda/go-types/bank"
+	"github.com/domonda/go-types/country"
+	"github.com/domonda/go-types/email"
+	"github.com/domonda/go-types/money"
+	"github.com/domonda/go-types/notnull"
+	"github.com/domonda/go-types/nullable"
+	"github.com/domonda/go-types/vat"
+
+	"github.com/docvibe-ai/api/go/einvoice"
+	"github.com/docvibe-ai/api/go/invoicing"
+)
+
+// Unmarshal parses UN/CEFACT Cross Industry Invoice XML,
+// like the XML embedded in ZUGFeRD and Factur-X documents,
+// into an invoice.
+//
+// Elements that are not part of the EN 16931 mapping are ignored.
+// Elements with values that can't be represented by the invoice
+// are returned as unmapped with their element path.
+// The Type of the returned invoice is not set because the direction
+// depends on the point of view, and the invoice is not normalized.
+func Unmarshal(data []byte) (inv *invoicing.Invoice, unmapped []string, err error) {
+	var doc crossIndustryInvoice
+	if err = xml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, fmt.Errorf("can't parse Cross Industry Invoice XML: %w", err)
+	}
+	if doc.XMLName.Space != NamespaceRSM {
+		return nil, nil, fmt.Errorf("root element namespace %q is not the Cross Industry Invoice namespace %q", doc.XMLName.Space, NamespaceRSM)
+	}
+	u := &unmarshaller{inv: &invoicing.Invoice{PaymentStatus: invoicing.PaymentStatusUnpaid}}
+	if err = u.crossIndustryInvoice(&doc); err != nil {
+		return nil, nil, err
+	}
+	return u.inv, u.unmapped, nil
+}
+
+type unmarshaller struct {
+	inv      *invoicing.Invoice
+	unmapped []string
+}
+
+const (
+	pathDocument    = "ExchangedDocument"
+	pathLineItem    = "SupplyChainTradeTransaction/IncludedSupplyChainTradeLineItem"
+	pathAgreement   = "SupplyChainTradeTransaction/ApplicableHeaderTradeAgreement"
+	pathSettlement  = "SupplyChainTradeTransaction/ApplicableHeaderTradeSettlement"
+	pathSummation   = pathSettlement + "/SpecifiedTradeSettlementHeaderMonetarySummation"
+	pathPaymentTerm = pathSettlement + "/SpecifiedTradePaymentTerms"
+)
+
+func (u *unmarshaller) crossIndustryInvoice(doc *crossIndustryInvoice) error {
+	inv := u.inv
+	inv.InvoiceID = nullable.TrimmedString(strings.TrimSpace(doc.Document.ID))
+	switch doc.Document.TypeCode {
+	case TypeCodeCreditNote:
+		inv.CreditNote = true
+	case TypeCodeInvoice:
+	default:
+		u.unmapped = append(u.unmapped, pathDocument+"/TypeCode")
+	}
+	if d, ok := doc.Document.IssueDateTime.date(); ok {
+		inv.IssueDate.Set(d)
+	}
+	for _, n := range doc.Document.Notes {
+		if content := strings.TrimSpace(n.Content); content != "" {
+			inv.Notes = append(inv.Notes, nullable.TrimmedString(content))
+		}
+	}
+
+	transaction := &doc.Transaction
+	u.headerAgreement(&transaction.Agreement)
+	u.headerDelivery(&transaction.Delivery)
+	if err := u.headerSettlement(&transaction.Settlement); err != nil {
+		return err
+	}
+	for i := range transaction.Items {
+		if err := u.lineItem(i, &transaction.Items[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (u *unmarshaller) headerAgreement(a *headerAgreement) {
+	inv := u.inv
+	inv.BuyerReference = nullable.TrimmedString(strings.TrimSpace(a.BuyerReference))
+	inv.Issuer = nullable.TrimmedString(strings.TrimSpace(a.Seller.Name))
+	inv.IssuerAddress = a.Seller.Address.address()
+	if mail, ok := a.Seller.URI.email(); ok {
+		inv.IssuerEmail = email.NullableAddress(mail)
+	} else if a.Seller.URI != nil {
+		u.unmapped = append(u.unmapped, pathAgreement+"/SellerTradeParty/URIUniversalCommunication")
+	}
+	for _, reg := range a.Seller.TaxRegistrations {
+		switch reg.ID.SchemeID {
+		case schemeVATID:
+			inv.IssuerVATID = vat.NullableID(strings.TrimSpace(reg.ID.Value))
+		case schemeTaxNumber:
+			inv.IssuerTaxNumber = nullable.TrimmedString(strings.TrimSpace(reg.ID.Value))
+		}
+	}
+	inv.CustomerID = nullable.TrimmedString(strings.TrimSpace(a.Buyer.ID))
+	inv.Customer = nullable.TrimmedString(strings.TrimSpace(a.Buyer.Name))
+	inv.CustomerBillingAddress = a.Buyer.Address.address()
+	for _, reg := range a.Buyer.TaxRegistrations {
+		if reg.ID.SchemeID == schemeVATID {
+			inv.CustomerVATID = vat.NullableID(strings.TrimSpace(reg.ID.Value))
+		}
+	}
+	if contact := a.Buyer.Contact; contact != nil {
+		if contact.Phone != nil {
+			inv.CustomerPhone = nullable.TrimmedString(strings.TrimSpace(contact.Phone.CompleteNumber))
+		}
+		if contact.Email != nil {
+			inv.CustomerEmail = email.NullableAddress(strings.TrimSpace(contact.Email.URIID))
+		}
+	}
+	// The electronic address is used as email
+	// if the contact has no other email
+	mail, ok := a.Buyer.URI.email()
+	switch {
+	case ok && (inv.CustomerEmail.IsNull() || strings.EqualFold(inv.CustomerEmail.String(), mail)):
+		inv.CustomerEmail = email.NullableAddress(mail)
+	case a.Buyer.URI != nil:
+		u.unmapped = append(u.unmapped, pathAgreement+"/BuyerTradeParty/URIUniversalCommunication")
+	}
+	if a.BuyerOrder != nil {
+		inv.OrderID = nullable.TrimmedString(strings.TrimSpace(a.BuyerOrder.IssuerAssignedID))
+	}
+	if a.Contract != nil {
+		inv.ContractID = nullable.TrimmedString(strings.TrimSpace(a.Contract.IssuerAssignedID))
+	}
+}
+
+func (a *tradeAddress) address() *invoicing.Address {
+	if a == nil {
+		return nil
+	}
+	return &invoicing.Address{
+		Street:     nullable.TrimmedString(strings.TrimSpace(a.LineOne)),
+		City:       nullable.TrimmedString(strings.TrimSpace(a.CityName)),
+		State:      nullable.TrimmedString(strings.TrimSpace(a.CountrySubDivisionName)),
+		PostalCode: nullable.TrimmedString(strings.TrimSpace(a.PostcodeCode)),
+		Country:    country.NullableCode(strings.TrimSpace(a.CountryID)),
+	}
+}
+
+func (u *unmarshaller) headerDelivery(d *headerDelivery) {
+	if d.ShipTo != nil {
+		u.inv.CustomerShippingAddress = d.ShipTo.Address.address()
+	}
+	if d.DespatchAdvice != nil {
+		if id := strings.TrimSpace(d.DespatchAdvice.IssuerAssignedID); id != "" {
+			u.inv.DeliveryNoteIDs = append(u.inv.DeliveryNoteIDs, notnull.TrimmedString(id))
+		}
+	}
+}
+
+func (u *unmarshaller) headerSettlement(s *headerSettlement) (err error) {
+	inv := u.inv
+	inv.PaymentReference = nullable.TrimmedString(strings.TrimSpace(s.PaymentReference))
+	inv.Currency = money.NullableCurrency(strings.TrimSpace(s.Currency))
+
+	for _, tax := range s.Taxes {
+		t := invoicing.TaxSubtotal{
+			ExemptionReason: nullable.TrimmedString(strings.TrimSpace(tax.ExemptionReason)),
+		}
+		if t.TaxableAmount, err = einvoice.ParseAmountAt(tax.BasisAmount, pathSettlement+"/ApplicableTradeTax/BasisAmount"); err != nil {
+			return err
+		}
+		if t.TaxAmount, err = einvoice.ParseAmountAt(tax.CalculatedAmount, pathSettlement+"/ApplicableTradeTax/CalculatedAmount"); err != nil {
+			return err
+		}
+		percent, err := einvoice.ParseDecimalAt(tax.RateApplicablePercent, pathSettlement+"/ApplicableTradeTax/RateApplicablePercent")
+		if err != nil {
+			return err
+		}
+		t.TaxPercent.Set(money.Rate(percent))
+		if einvoice.TaxCategory(tax.CategoryCode).IsReverseCharge() {
+			inv.ReverseCharge = true
+			inv.ReverseChargeClauseText = t.ExemptionReason
+		}
+		inv.TaxBreakdown = append(inv.TaxBreakdown, t)
+	}
+
+	if s.Period != nil {
+		if d, ok := s.Period.Start.date(); ok {
+			inv.PeriodStart.Set(d)
+		}
+		if d, ok := s.Period.End.date(); ok {
+			inv.PeriodEnd.Set(d)
+		}
+	}
+
+	for i, means := range s.PaymentMeans {
+		if means.PayeeAccount == nil && means.PayeeInstitution == nil {
+			continue
+		}
+		if inv.PaymentIBAN.IsNotNull() || inv.PaymentBIC.IsNotNull() {
+			u.unmapped = append(u.unmapped, fmt.Sprintf("%s/SpecifiedTradeSettlementPaymentMeans[%d]", pathSettlement, i))
+			continue
+		}
+		if means.PayeeAccount != nil {
+			inv.PaymentIBAN = bank.NullableIBAN(strings.TrimSpace(means.PayeeAccount.IBANID))
+		}
+		if means.PayeeInstitution != nil {
+			inv.PaymentBIC = bank.NullableBIC(strings.TrimSpace(means.PayeeInstitution.BICID))
+		}
+	}
+
+	var (
+		descriptions []string
+		discount     *einvoice.DiscountTerms
+	)
+	for i, terms := range s.PaymentTerms {
+		discountTerms, rest := einvoice.ParseDiscountTerms(terms.Description)
+		if rest != "" {
+			descriptions = append(descriptions, rest)
+		}
+		if d, ok := terms.DueDate.date(); ok {
+			inv.DueDate.Set(d)
+		}
+		if mandate := strings.TrimSpace(terms.DirectDebitMandateID); mandate != "" {
+			inv.DirectDebitMandateID = nullable.TrimmedString(mandate)
+		}
+		for j, t := range discountTerms {
+			if i > 0 || j > 0 {
+				// Only one early payment discount can be represented
+				u.unmapped = append(u.unmapped, fmt.Sprintf("%s[%d]/Description", pathPaymentTerm, i))
+				continue
+			}
+			discount = &t
+		}
+	}
+	inv.PaymentTerms = nullable.TrimmedString(strings.Join(descriptions, "\n"))
+
+	var allowanceTotal money.Amount
+	for i, allowance := range s.Allowances {
+		amount, err := einvoice.ParseAmountAt(allowance.ActualAmount, pathSettlement+"/SpecifiedTradeAllowanceCharge/ActualAmount")
+		if err != nil {
+			return err
+		}
+		if allowance.ChargeIndicator.Indicator {
+			u.unmapped = append(u.unmapped, fmt.Sprintf("%s/SpecifiedTradeAllowanceCharge[%d]", pathSettlement, i))
+			continue
+		}
+		allowanceTotal += amount
+		if allowance.CalculationPercent != "" {
+			percent, err := einvoice.ParseDecimalAt(allowance.CalculationPercent, pathSettlement+"/SpecifiedTradeAllowanceCharge/CalculationPercent")
+			if err != nil {
+				return err
+			}
+			inv.DiscountPercent.Set(money.Rate(percent))
+		}
+	}
+	if allowanceTotal != 0 {
+		inv.DiscountAmount.Set(allowanceTotal.RoundToCents())
+	}
+
+	sum := &s.Summation
+	if sum.TaxBasisTotalAmount != "" {
+		amount, err := einvoice.ParseAmountAt(sum.TaxBasisTotalAmount, pathSummation+"/TaxBasisTotalAmount")
+		if err != nil {
+			return err
+		}
+		inv.Subtotal.Set(amount)
+	}
+	if sum.TaxTotalAmount.Value != "" {
+		amount, err := einvoice.ParseAmountAt(sum.TaxTotalAmount.Value, pathSummation+"/TaxTotalAmount")
+		if err != nil {
+			return err
+		}
+		inv.Tax.Set(amount)
+	}
+	if sum.GrandTotalAmount != "" {
+		amount, err := einvoice.ParseAmountAt(sum.GrandTotalAmount, pathSummation+"/GrandTotalAmount")
+		if err != nil {
+			return err
+		}
+		inv.Total.Set(amount)
+	}
+	if sum.ChargeTotalAmount != "" {
+		u.unmapped = append(u.unmapped, pathSummation+"/ChargeTotalAmount")
+	}
+	if sum.TotalPrepaidAmount != "" {
+		prepaid, err := einvoice.ParseAmountAt(sum.TotalPrepaidAmount, pathSummation+"/TotalPrepaidAmount")
+		if err != nil {
+			return err
+		}
+		due, err := einvoice.ParseAmountAt(sum.DuePayableAmount, pathSummation+"/DuePayableAmount")
+		if err != nil {
+			return err
+		}
+		switch {
+		case prepaid != 0 && due == 0:
+			inv.PaymentStatus = paymentStatusOfMeans(s.PaymentMeans)
+		case prepaid != 0:
+			// Partial prepayments can't be represented
+			u.unmapped = append(u.unmapped, pathSummation+"/TotalPrepaidAmount")
+		}
+	}
+	if discount != nil {
+		// Applied after parsing the total
+		// because the base amount is compared with it
+		discount.Apply(inv)
+	}
+	return nil
+}
+
+// paymentStatusOfMeans returns the paid payment status
+// for the type code of the first payment means
+func paymentStatusOfMeans(means []paymentMeans) invoicing.PaymentStatus {
+	if len(means) == 0 {
+		return invoicing.PaymentStatusPaidWithElectronicPaymentMethod
+	}
+	return einvoice.PaidStatusOfMeansCode(means[0].TypeCode)
+}
+
+func (u *unmarshaller) lineItem(index int, line *lineItem) (err error) {
+	var (
+		inv  = u.inv
+		path = fmt.Sprintf("%s[%d]", pathLineItem, index)
+		item = &invoicing.InvoiceItem{
+			Description: nullable.TrimmedString(strings.TrimSpace(line.Product.Name)),
+			ProductID:   nullable.TrimmedString(strings.TrimSpace(line.Product.SellerAssignedID)),
+		}
+	)
+	if lineID := strings.TrimSpace(line.LineDocument.LineID); lineID != strconv.Itoa(index+1) {
+		item.PositionNumber = nullable.TrimmedString(lineID)
+	}
+	if len(line.LineDocument.Notes) > 0 {
+		u.unmapped = append(u.unmapped, path+"/AssociatedDocumentLineDocument/IncludedNote")
+	}
+	if line.Settlement.Period != nil {
+		u.unmapped = append(u.unmapped, path+"/SpecifiedLineTradeSettlement/BillingSpecifiedPeriod")
+	}
+
+	quantity, err := einvoice.ParseDecimalAt(line.Delivery.BilledQuantity.Value, path+"/SpecifiedLineTradeDelivery/BilledQuantity")
+	if err != nil {
+		return err
+	}
+	lineTotal, err := einvoice.ParseAmountAt(line.Settlement.Summation.LineTotalAmount, path+"/SpecifiedLineTradeSettlement/SpecifiedTradeSettlementLineMonetarySummation/LineTotalAmount")
+	if err != nil {
+		return err
+	}
+	if (quantity < 0 || lineTotal < 0) && !inv.CreditNote {
+		item.CreditNote = true
+	}
+	item.Quantity.Set(math.Abs(quantity))
+	item.Subtotal.Set(lineTotal.Abs())
+	if code := strings.TrimSpace(line.Delivery.BilledQuantity.UnitCode); code != "" && code != einvoice.DefaultUnitCode {
+		item.Unit = nullable.TrimmedString(code)
+	}
+	if line.Agreement.NetPrice.ChargeAmount != "" {
+		price, err := einvoice.ParseAmountAt(line.Agreement.NetPrice.ChargeAmount, path+"/SpecifiedLineTradeAgreement/NetPriceProductTradePrice/ChargeAmount")
+		if err != nil {
+			return err
+		}
+		item.UnitPrice.Set(price)
+	}
+	if line.Settlement.Tax.RateApplicablePercent != "" {
+		percent, err := einvoice.ParseDecimalAt(line.Settlement.Tax.RateApplicablePercent, path+"/SpecifiedLineTradeSettlement/ApplicableTradeTax/RateApplicablePercent")
+		if err != nil {
+			return err
+		}
+		item.TaxPercent.Set(money.Rate(percent))
+	}
+
+	var discount money.Amount
+	for i, allowance := range line.Settlement.Allowances {
+		if allowance.ChargeIndicator.Indicator {
+			u.unmapped = append(u.unmapped, fmt.Sprintf("%s/SpecifiedLineTradeSettlement/SpecifiedTradeAllowanceCharge[%d]", path, i))
+			continue
+		}
+		amount, err := einvoice.ParseAmountAt(allowance.ActualAmount, path+"/SpecifiedLineTradeSettlement/SpecifiedTradeAllowanceCharge/ActualAmount")
+		if err != nil {
+			return err
+		}
+		discount += amount.Abs()
+		if allowance.CalculationPercent != "" {
+			percent, err := einvoice.ParseDecimalAt(allowance.CalculationPercent, path+"/SpecifiedLineTradeSettlement/SpecifiedTradeAllowanceCharge/CalculationPercent")
+			if err != nil {
+				return err
+			}
+			item.DiscountPercent.Set(money.Rate(percent))
+		}
+	}
+	if discount != 0 {
+		// The discount amount is derived from the percentage
+		// if both are given and consistent
+		expected := money.Amount(item.Quantity.Get()) * item.UnitPrice.Get() * money.Amount(item.DiscountPercent.Get()) / 100
+		if item.DiscountPercent.IsNull() || !expected.RoundToCents().WithinOneCent(discount) {
+			item.DiscountAmount.Set(discount.RoundToCents())
+		}
+	}
+	inv.Items = append(inv.Items, item)
+	return nil
+}
@@ -0,0 +1,129 @@
+package einvoice
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/domonda/go-types/date"
+	"github.com/domonda/go-types/money"
+
+	"github.com/docvibe-ai/api/go/invoicing"
+)
+
+// DiscountTerms are the early payment discount (Skonto) terms
+// of an invoice in the structured payment terms format
+// of XRechnung: #SKONTO#TAGE=14#PROZENT=2.00#BASISBETRAG=119.00#
+type DiscountTerms struct {
+	// Days after the issue date the discount is valid
+	Days int
+	// Discount percentage
+	Percent money.Rate
+	// Amount the discount percentage applies to,
+	// null for the invoice total
+	BaseAmount money.NullableAmount
+}
+
+// String returns the terms in the XRechnung format
+// including the trailing line break required by the format
+func (t DiscountTerms) String() string {
+	s := fmt.Sprintf("#SKONTO#TAGE=%d#PROZENT=%.2f#", t.Days, t.Percent)
+	if t.BaseAmount.IsNotNull() {
+		s += fmt.Sprintf("BASISBETRAG=%.2f#", t.BaseAmount.Get())
+	}
+	return s + "\n"
+}
+
+// DiscountTermsOf returns the discount terms of an invoice
+// with a DiscountUntilDate and a DiscountPercent or DiscountAmount.
+// A DiscountAmount without percentage is returned
+// as percentage of the invoice total.
+// Returns false if the invoice has no discount or the terms
+// can't be represented because the issue date or total is missing.
+func DiscountTermsOf(inv *invoicing.Invoice) (DiscountTerms, bool) {
+	if inv.DiscountUntilDate.IsNull() || inv.IssueDate.IsNull() {
+		return DiscountTerms{}, false
+	}
+	days, ok := daysBetween(inv.IssueDate.Get(), inv.DiscountUntilDate.Get())
+	if !ok || days < 0 {
+		return DiscountTerms{}, false
+	}
+	terms := DiscountTerms{Days: days}
+	switch {
+	case inv.DiscountPercent.IsNotNull():
+		terms.Percent = inv.DiscountPercent.Get()
+		if inv.DiscountAmount.IsNotNull() && inv.DiscountPercent.Get() != 0 {
+			base := (inv.DiscountAmount.Get() * 100 / money.Amount(inv.DiscountPercent.Get())).RoundToCents()
+			if inv.Total.IsNull() || !base.WithinOneCent(inv.Total.Get()) {
+				terms.BaseAmount.Set(base)
+			}
+		}
+	case inv.DiscountAmount.IsNotNull() && inv.Total.IsNotNull() && inv.Total.Get() != 0:
+		terms.Percent = money.Rate(roundTo(float64(inv.DiscountAmount.Get()*100/inv.Total.Get()), 2))
+		// Use a base amount that results in the exact discount amount
+		// with the percentage rounded to two decimals
+		base := inv.Total.Get()
+		if terms.Percent != 0 {
+			base = (inv.DiscountAmount.Get() * 100 / money.Amount(terms.Percent)).RoundToCents()
+		}
+		terms.BaseAmount.Set(base)
+	default:
+		return DiscountTerms{}, false
+	}
+	return terms, true
+}
+
+// Apply sets the DiscountPercent and DiscountUntilDate of the invoice
+// and the DiscountAmount if the terms have a base amount
+// other than the invoice total
+func (t DiscountTerms) Apply(inv *invoicing.Invoice) {
+	inv.DiscountPercent.Set(t.Percent)
+	if inv.IssueDate.IsNotNull() {
+		if issueDate, err := time.Parse(time.DateOnly, inv.IssueDate.Get().String()); err == nil {
+			inv.DiscountUntilDate.Set(date.Date(issueDate.AddDate(0, 0, t.Days).Format(time.DateOnly)))
+		}
+	}
+	if t.BaseAmount.IsNotNull() && (inv.Total.IsNull() || !t.BaseAmount.Get().WithinOneCent(inv.Total.Get())) {
+		inv.DiscountAmount.Set((t.BaseAmount.Get() * money.Amount(t.Percent) / 100).RoundToCents())
+	}
+}
+
+var discountTermsRegexp = regexp.MustCompile(`#SKONTO#TAGE=(\d+)#PROZENT=(\d+(?:\.\d+)?)#(?:BASISBETRAG=(-?\d+(?:\.\d+)?)#)?\n?`)
+
+// ParseDiscountTerms returns the discount terms in the XRechnung format
+// found in the payment terms text and the text without them
+func ParseDiscountTerms(text string) (terms []DiscountTerms, rest string) {
+	for _, match := range discountTermsRegexp.FindAllStringSubmatch(text, -1) {
+		var t DiscountTerms
+		t.Days, _ = strconv.Atoi(match[1])
+		percent, _ := strconv.ParseFloat(match[2], 64)
+		t.Percent = money.Rate(percent)
+		if match[3] != "" {
+			base, _ := strconv.ParseFloat(match[3], 64)
+			t.BaseAmount.Set(money.Amount(base))
+		}
+		terms = append(terms, t)
+	}
+	rest = strings.TrimSpace(discountTermsRegexp.ReplaceAllString(text, ""))
+	return terms, rest
+}
+
+func daysBetween(from, until date.Date) (int, bool) {
+	fromTime, err := time.Parse(time.DateOnly, from.String())
+	if err != nil {
+		return 0, false
+	}
+	untilTime, err := time.Parse(time.DateOnly, until.String())
+	if err != nil {
+		return 0, false
+	}
+	return int(untilTime.Sub(fromTime).Hours() / 24), true
+}
+
+func roundTo(f float64, decimals int) float64 {
+	pow := math.Pow10(decimals)
+	return math.Round(f*pow) / pow
+}
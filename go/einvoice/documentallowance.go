@@ -0,0 +1,60 @@
+package einvoice
+
+import (
+	"github.com/domonda/go-types/money"
+
+	"github.com/docvibe-ai/api/go/invoicing"
+)
+
+// DocumentAllowance is a discount already deducted from the
+// tax basis total of an invoice for one tax rate,
+// represented as document level allowance in e-invoices
+type DocumentAllowance struct {
+	// Tax subtotal of the rate the allowance applies to
+	TaxSubtotal invoicing.TaxSubtotal
+	// Tax category of the TaxSubtotal
+	Category TaxCategory
+	// Sum of the item subtotals with the tax rate
+	BaseAmount money.Amount
+	// Amount of the allowance
+	Amount money.Amount
+}
+
+// DocumentAllowances returns the difference between the line total
+// and the tax basis total of the invoice as allowances per tax rate
+// of the TaxSubtotals.
+// Items without tax percent are only attributed to a single tax rate.
+// Returns nil if there is no difference or it can't be
+// attributed to the tax rates of the items.
+func DocumentAllowances(inv *invoicing.Invoice, lineTotal, taxBasis money.Amount) []DocumentAllowance {
+	if len(inv.Items) == 0 || (lineTotal-taxBasis).RoundToCents() <= 0 {
+		return nil
+	}
+	subtotals := TaxSubtotals(inv)
+	var allowances []DocumentAllowance
+	for _, t := range subtotals {
+		if t.TaxPercent.IsNull() {
+			continue
+		}
+		var itemsSum money.Amount
+		for _, item := range inv.Items {
+			if item == nil {
+				continue
+			}
+			if item.TaxPercent.IsNotNull() && item.TaxPercent.Get() == t.TaxPercent.Get() || item.TaxPercent.IsNull() && len(subtotals) == 1 {
+				itemsSum += ItemSign(inv, item) * item.Subtotal.Get()
+			}
+		}
+		amount := (itemsSum - t.TaxableAmount).RoundToCents()
+		if amount <= 0 {
+			continue
+		}
+		allowances = append(allowances, DocumentAllowance{
+			TaxSubtotal: t,
+			Category:    TaxCategoryOf(inv, t.TaxPercent.Get(), t.ExemptionReason.String()),
+			BaseAmount:  itemsSum,
+			Amount:      amount,
+		})
+	}
+	return allowances
+}
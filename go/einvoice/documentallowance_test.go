@@ -0,0 +1,96 @@
+package einvoice
+
+import (
+	"testing"
+
+	"github.com/domonda/go-types/money"
+
+	"github.com/docvibe-ai/api/go/einvoice/einvoicetest"
+	"github.com/docvibe-ai/api/go/invoicing"
+)
+
+func TestDocumentAllowances(t *testing.T) {
+	item := func(subtotal float64, taxPercent ...float64) *invoicing.InvoiceItem {
+		i := &invoicing.InvoiceItem{Subtotal: einvoicetest.Amount(subtotal)}
+		if len(taxPercent) > 0 {
+			i.TaxPercent = einvoicetest.Rate(taxPercent[0])
+		}
+		return i
+	}
+	breakdown := func(taxPercent, taxable float64) invoicing.TaxSubtotal {
+		return invoicing.TaxSubtotal{
+			TaxPercent:    einvoicetest.Rate(taxPercent),
+			TaxableAmount: money.Amount(taxable),
+			TaxAmount:     money.Amount(taxable * taxPercent / 100).RoundToCents(),
+		}
+	}
+	tests := []struct {
+		name      string
+		inv       *invoicing.Invoice
+		lineTotal money.Amount
+		taxBasis  money.Amount
+		want      []money.Amount
+	}{
+		{
+			name: "no difference",
+			inv: &invoicing.Invoice{
+				Items:        []*invoicing.InvoiceItem{item(100, 19)},
+				TaxBreakdown: []invoicing.TaxSubtotal{breakdown(19, 100)},
+			},
+			lineTotal: 100,
+			taxBasis:  100,
+		},
+		{
+			name: "per tax rate",
+			inv: &invoicing.Invoice{
+				Items:        []*invoicing.InvoiceItem{item(100, 19), item(50, 7), nil},
+				TaxBreakdown: []invoicing.TaxSubtotal{breakdown(19, 90), breakdown(7, 45)},
+			},
+			lineTotal: 150,
+			taxBasis:  135,
+			want:      []money.Amount{10, 5},
+		},
+		{
+			name: "item without tax percent not added to every rate",
+			inv: &invoicing.Invoice{
+				Items:        []*invoicing.InvoiceItem{item(100, 19), item(50, 7), item(30)},
+				TaxBreakdown: []invoicing.TaxSubtotal{breakdown(19, 90), breakdown(7, 45)},
+			},
+			lineTotal: 180,
+			taxBasis:  135,
+			want:      []money.Amount{10, 5},
+		},
+		{
+			name: "item without tax percent with single rate",
+			inv: &invoicing.Invoice{
+				Items:        []*invoicing.InvoiceItem{item(100, 19), item(30)},
+				TaxBreakdown: []invoicing.TaxSubtotal{breakdown(19, 117)},
+			},
+			lineTotal: 130,
+			taxBasis:  117,
+			want:      []money.Amount{13},
+		},
+		{
+			name:      "no items",
+			inv:       &invoicing.Invoice{TaxBreakdown: []invoicing.TaxSubtotal{breakdown(19, 90)}},
+			lineTotal: 100,
+			taxBasis:  90,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := DocumentAllowances(tt.inv, tt.lineTotal, tt.taxBasis)
+			if len(got) != len(tt.want) {
+				t.Fatalf("DocumentAllowances() = %v, want amounts %v", got, tt.want)
+			}
+			for i, a := range got {
+				if a.Amount != tt.want[i] {
+					t.Errorf("DocumentAllowances()[%d].Amount = %v, want %v", i, a.Amount, tt.want[i])
+				}
+				if a.Category != TaxCategoryStandard {
+					t.Errorf("DocumentAllowances()[%d].Category = %q, want %q", i, a.Category, TaxCategoryStandard)
+				}
+			}
+		})
+	}
+}
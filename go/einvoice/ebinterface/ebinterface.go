@@ -0,0 +1,251 @@
+package ebinterface
+
+import (
+	"encoding/xml"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/domonda/go-types/vat"
+)
+
+const (
+	// Namespace of ebInterface 6.1
+	Namespace = "http://www.ebinterface.at/schema/6p1/"
+
+	// NamespacePrefix6x is the common prefix
+	// of the namespaces of all ebInterface 6.x versions
+	NamespacePrefix6x = "http://www.ebinterface.at/schema/6p"
+
+	// GeneratingSystem is written to the GeneratingSystem attribute
+	GeneratingSystem = "DocVibe"
+)
+
+// Document types of the DocumentType attribute
+const (
+	DocumentTypeInvoice    = "Invoice"
+	DocumentTypeCreditMemo = "CreditMemo"
+)
+
+// noVATID is the VATIdentificationNumber
+// of a party without VAT ID
+const noVATID = "00000000"
+
+// The XML types use element names without namespace
+// so that unmarshalling accepts any namespace prefix
+// and the namespaces of other ebInterface 6.x versions.
+// Marshal adds the ebInterface 6.1 namespace prefix afterwards.
+
+type invoice struct {
+	XMLName                      xml.Name                      `xml:"Invoice"`
+	GeneratingSystem             string                        `xml:"GeneratingSystem,attr"`
+	DocumentType                 string                        `xml:"DocumentType,attr"`
+	InvoiceCurrency              string                        `xml:"InvoiceCurrency,attr"`
+	InvoiceNumber                string                        `xml:"InvoiceNumber"`
+	InvoiceDate                  string                        `xml:"InvoiceDate"`
+	Delivery                     *delivery                     `xml:"Delivery"`
+	Biller                       biller                        `xml:"Biller"`
+	InvoiceRecipient             invoiceRecipient              `xml:"InvoiceRecipient"`
+	Details                      details                       `xml:"Details"`
+	ReductionAndSurchargeDetails *reductionAndSurchargeDetails `xml:"ReductionAndSurchargeDetails"`
+	Tax                          tax                           `xml:"Tax"`
+	TotalGrossAmount             string                        `xml:"TotalGrossAmount"`
+	PrepaidAmount                string                        `xml:"PrepaidAmount,omitempty"`
+	PayableAmount                string                        `xml:"PayableAmount"`
+	PaymentMethod                *paymentMethod                `xml:"PaymentMethod"`
+	PaymentConditions            *paymentConditions            `xml:"PaymentConditions"`
+	Comment                      string                        `xml:"Comment,omitempty"`
+}
+
+type delivery struct {
+	DeliveryID string   `xml:"DeliveryID,omitempty"`
+	Date       string   `xml:"Date,omitempty"`
+	Period     *period  `xml:"Period"`
+	Address    *address `xml:"Address"`
+}
+
+type period struct {
+	FromDate string `xml:"FromDate"`
+	ToDate   string `xml:"ToDate"`
+}
+
+type biller struct {
+	VATIdentificationNumber string                  `xml:"VATIdentificationNumber"`
+	FurtherIdentifications  []furtherIdentification `xml:"FurtherIdentification"`
+	Address                 address                 `xml:"Address"`
+}
+
+type invoiceRecipient struct {
+	VATIdentificationNumber   string                  `xml:"VATIdentificationNumber"`
+	BillersInvoiceRecipientID string                  `xml:"BillersInvoiceRecipientID,omitempty"`
+	FurtherIdentifications    []furtherIdentification `xml:"FurtherIdentification"`
+	OrderReference            *orderReference         `xml:"OrderReference"`
+	Address                   address                 `xml:"Address"`
+}
+
+type furtherIdentification struct {
+	IdentificationType string `xml:"IdentificationType,attr"`
+	Value              string `xml:",chardata"`
+}
+
+// identificationTypeContract is the IdentificationType
+// of the contract number in a FurtherIdentification
+const identificationTypeContract = "Contract"
+
+type orderReference struct {
+	OrderID       string `xml:"OrderID"`
+	ReferenceDate string `xml:"ReferenceDate,omitempty"`
+}
+
+type address struct {
+	Name    string          `xml:"Name"`
+	Street  string          `xml:"Street,omitempty"`
+	Town    string          `xml:"Town"`
+	ZIP     string          `xml:"ZIP"`
+	Country *addressCountry `xml:"Country"`
+	Phones  []string        `xml:"Phone"`
+	Emails  []string        `xml:"Email"`
+}
+
+type addressCountry struct {
+	CountryCode string `xml:"CountryCode,attr"`
+	Name        string `xml:",chardata"`
+}
+
+type details struct {
+	ItemLists []itemList `xml:"ItemList"`
+}
+
+type itemList struct {
+	LineItems []listLineItem `xml:"ListLineItem"`
+}
+
+type listLineItem struct {
+	PositionNumber                  string              `xml:"PositionNumber,omitempty"`
+	Descriptions                    []string            `xml:"Description"`
+	ArticleNumbers                  []articleNumber     `xml:"ArticleNumber"`
+	Quantity                        itemQuantity        `xml:"Quantity"`
+	UnitPrice                       string              `xml:"UnitPrice"`
+	TaxItem                         taxItem             `xml:"TaxItem"`
+	ReductionAndSurcharge           *lineItemReductions `xml:"ReductionAndSurchargeListLineItemDetails"`
+	Delivery                        *delivery           `xml:"Delivery"`
+	InvoiceRecipientsOrderReference *lineOrderReference `xml:"InvoiceRecipientsOrderReference"`
+	LineItemAmount                  string              `xml:"LineItemAmount"`
+}
+
+type articleNumber struct {
+	ArticleNumberType string `xml:"ArticleNumberType,attr,omitempty"`
+	Value             string `xml:",chardata"`
+}
+
+// articleNumberTypeSeller is the ArticleNumberType
+// of the article number assigned by the biller
+const articleNumberTypeSeller = "SellersArticleNumber"
+
+type itemQuantity struct {
+	Unit  string `xml:"Unit,attr"`
+	Value string `xml:",chardata"`
+}
+
+type lineOrderReference struct {
+	OrderID string `xml:"OrderID"`
+}
+
+type lineItemReductions struct {
+	Reductions []reduction `xml:"ReductionListLineItem"`
+	Surcharges []reduction `xml:"SurchargeListLineItem"`
+}
+
+type reduction struct {
+	BaseAmount string `xml:"BaseAmount"`
+	Percentage string `xml:"Percentage,omitempty"`
+	Amount     string `xml:"Amount,omitempty"`
+	Comment    string `xml:"Comment,omitempty"`
+}
+
+type reductionAndSurchargeDetails struct {
+	Reductions []headerReduction `xml:"Reduction"`
+	Surcharges []headerReduction `xml:"Surcharge"`
+}
+
+type headerReduction struct {
+	BaseAmount string  `xml:"BaseAmount"`
+	Percentage string  `xml:"Percentage,omitempty"`
+	Amount     string  `xml:"Amount,omitempty"`
+	TaxItem    taxItem `xml:"TaxItem"`
+	Comment    string  `xml:"Comment,omitempty"`
+}
+
+type tax struct {
+	TaxItems []taxItem `xml:"TaxItem"`
+}
+
+type taxItem struct {
+	TaxableAmount string  `xml:"TaxableAmount"`
+	TaxPercent    taxRate `xml:"TaxPercent"`
+	TaxAmount     string  `xml:"TaxAmount,omitempty"`
+	Comment       string  `xml:"Comment,omitempty"`
+}
+
+type taxRate struct {
+	TaxCategoryCode string `xml:"TaxCategoryCode,attr"`
+	Value           string `xml:",chardata"`
+}
+
+type paymentMethod struct {
+	Comment                  string                    `xml:"Comment,omitempty"`
+	NoPayment                *struct{}                 `xml:"NoPayment"`
+	SEPADirectDebit          *sepaDirectDebit          `xml:"SEPADirectDebit"`
+	UniversalBankTransaction *universalBankTransaction `xml:"UniversalBankTransaction"`
+	PaymentCard              *struct{}                 `xml:"PaymentCard"`
+	OtherPayment             *struct{}                 `xml:"OtherPayment"`
+}
+
+type sepaDirectDebit struct {
+	MandateReference string `xml:"MandateReference,omitempty"`
+}
+
+type universalBankTransaction struct {
+	BeneficiaryAccounts []beneficiaryAccount `xml:"BeneficiaryAccount"`
+	PaymentReference    string               `xml:"PaymentReference,omitempty"`
+}
+
+type beneficiaryAccount struct {
+	BIC  string `xml:"BIC,omitempty"`
+	IBAN string `xml:"IBAN,omitempty"`
+}
+
+type paymentConditions struct {
+	DueDate   string     `xml:"DueDate,omitempty"`
+	Discounts []discount `xml:"Discount"`
+	Comment   string     `xml:"Comment,omitempty"`
+}
+
+type discount struct {
+	PaymentDate string `xml:"PaymentDate"`
+	Percentage  string `xml:"Percentage,omitempty"`
+	Amount      string `xml:"Amount,omitempty"`
+}
+
+// atuVATIDRegexp matches an Austrian VAT ID (UID-Nummer)
+var atuVATIDRegexp = regexp.MustCompile(`^ATU\d{8}$`)
+
+// vatIdentificationNumber returns the VAT ID for a VATIdentificationNumber
+// element, which is "00000000" for a party without VAT ID.
+// Austrian VAT IDs must have the format ATU12345678,
+// an ID written without the U like AT12345678 is completed.
+func vatIdentificationNumber(id vat.NullableID, field string) (string, error) {
+	if id.IsNull() {
+		return noVATID, nil
+	}
+	s := strings.ToUpper(strings.Join(strings.Fields(id.String()), ""))
+	if strings.HasPrefix(s, "AT") {
+		if !strings.HasPrefix(s, "ATU") {
+			s = "ATU" + s[2:]
+		}
+		if !atuVATIDRegexp.MatchString(s) {
+			return "", fmt.Errorf("%s %q is not an Austrian VAT ID in the format ATU12345678", field, id.String())
+		}
+	}
+	return s, nil
+}
@@ -0,0 +1,505 @@
+package ebinterface
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/domonda/go-types/money"
+	"github.com/domonda/go-types/nullable"
+
+	"github.com/docvibe-ai/api/go/einvoice"
+	"github.com/docvibe-ai/api/go/invoicing"
+)
+
+// Marshal returns the invoice as ebInterface 6.1 XML.
+// The Issuer is mapped to the Biller and the Customer
+// to the InvoiceRecipient with the OrderID as order reference.
+// Without OrderID no order reference is written,
+// which e-Rechnung.gv.at requires for invoices to federal agencies.
+//
+// Parties without VAT ID get the VAT ID "00000000" required by ebInterface.
+// An error is returned for an issuer, customer billing or shipping
+// address without country because ebInterface requires a country code.
+// Austrian VAT IDs are written in the format ATU12345678
+// and an error is returned if a VAT ID starting with AT
+// can't be brought into that format.
+// An error is also returned if the tax percentage of an item
+// can't be determined, see einvoice.ItemTaxPercent.
+//
+// The invoice period is written as delivery period, or as delivery date
+// if it is a single day. Without a complete period the issue date
+// is written as delivery date which Unmarshal returns as period of that day.
+//
+// Fields of the invoice that can't be represented in ebInterface
+// are returned as unmapped with their JSON path like "type"
+// or "items[2].position_number".
+// Marshal does not validate the invoice against the ebInterface schema,
+// an invoice without items for example results in an empty item list.
+func Marshal(inv *invoicing.Invoice) (data []byte, unmapped []string, err error) {
+	if inv == nil {
+		return nil, nil, errors.New("invoice is nil")
+	}
+	m := &marshaller{inv: inv}
+	doc, err := m.invoice()
+	if err != nil {
+		return nil, nil, err
+	}
+	data, err = xml.Marshal(doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	namespaceAttrs := []xml.Attr{einvoice.NamespaceAttr("eb", Namespace)}
+	data, err = einvoice.AddNamespacePrefixes(data, namespaceAttrs, namespacePrefix)
+	if err != nil {
+		return nil, nil, err
+	}
+	return append([]byte(xml.Header), data...), m.unmapped, nil
+}
+
+// namespacePrefix returns the prefix of the ebInterface namespace
+// that is used for all elements
+func namespacePrefix(parents []string, local string) string {
+	return "eb"
+}
+
+type marshaller struct {
+	inv      *invoicing.Invoice
+	unmapped []string
+}
+
+func (m *marshaller) unmappedIf(notNull bool, path string) {
+	if notNull {
+		m.unmapped = append(m.unmapped, path)
+	}
+}
+
+func (m *marshaller) invoice() (*invoice, error) {
+	inv := m.inv
+	doc := &invoice{
+		GeneratingSystem: GeneratingSystem,
+		DocumentType:     DocumentTypeInvoice,
+		InvoiceCurrency:  inv.Currency.String(),
+		InvoiceNumber:    inv.InvoiceID.String(),
+	}
+	if inv.CreditNote {
+		doc.DocumentType = DocumentTypeCreditMemo
+	}
+	if inv.IssueDate.IsNotNull() {
+		doc.InvoiceDate = inv.IssueDate.Get().String()
+	}
+	m.unmapped = append(m.unmapped, einvoice.UnmappedFields(inv)...)
+	m.unmappedIf(inv.IssuerTaxNumber.IsNotNull(), "issuer_tax_number")
+	m.unmappedIf(inv.BuyerReference.IsNotNull(), "buyer_reference")
+	m.unmappedIf(inv.PaidDate.IsNotNull(), "paid_date")
+
+	var err error
+	if doc.Biller, err = m.biller(); err != nil {
+		return nil, err
+	}
+	if doc.InvoiceRecipient, err = m.invoiceRecipient(); err != nil {
+		return nil, err
+	}
+	if doc.Delivery, err = m.delivery(); err != nil {
+		return nil, err
+	}
+
+	var (
+		items     itemList
+		lineTotal money.Amount
+	)
+	for i, item := range inv.Items {
+		if item == nil {
+			continue
+		}
+		line, err := m.lineItem(i, item)
+		if err != nil {
+			return nil, err
+		}
+		items.LineItems = append(items.LineItems, line)
+		amount, _ := einvoice.ParseAmount(line.LineItemAmount)
+		lineTotal += amount
+	}
+	doc.Details.ItemLists = append(doc.Details.ItemLists, items)
+	m.totals(doc, lineTotal.RoundToCents())
+	doc.PaymentMethod = m.paymentMethod()
+
+	var notes []string
+	for _, n := range inv.Notes {
+		notes = append(notes, n.String())
+	}
+	doc.Comment = strings.Join(notes, "\n")
+	return doc, nil
+}
+
+func (m *marshaller) biller() (biller, error) {
+	inv := m.inv
+	vatID, err := vatIdentificationNumber(inv.IssuerVATID, "issuer VAT ID")
+	if err != nil {
+		return biller{}, err
+	}
+	address, err := newAddress(inv.Issuer, inv.IssuerAddress, "issuer address")
+	if err != nil {
+		return biller{}, err
+	}
+	b := biller{
+		VATIdentificationNumber: vatID,
+		Address:                 address,
+	}
+	if inv.IssuerEmail.IsNotNull() {
+		b.Address.Emails = []string{inv.IssuerEmail.String()}
+	}
+	return b, nil
+}
+
+func (m *marshaller) invoiceRecipient() (invoiceRecipient, error) {
+	inv := m.inv
+	vatID, err := vatIdentificationNumber(inv.CustomerVATID, "customer VAT ID")
+	if err != nil {
+		return invoiceRecipient{}, err
+	}
+	address, err := newAddress(inv.Customer, inv.CustomerBillingAddress, "customer billing address")
+	if err != nil {
+		return invoiceRecipient{}, err
+	}
+	r := invoiceRecipient{
+		VATIdentificationNumber:   vatID,
+		BillersInvoiceRecipientID: inv.CustomerID.String(),
+		Address:                   address,
+	}
+	if inv.ContractID.IsNotNull() {
+		r.FurtherIdentifications = append(r.FurtherIdentifications, furtherIdentification{
+			IdentificationType: identificationTypeContract,
+			Value:              inv.ContractID.String(),
+		})
+	}
+	if inv.OrderID.IsNotNull() {
+		r.OrderReference = &orderReference{OrderID: inv.OrderID.String()}
+		if inv.OrderDate.IsNotNull() {
+			r.OrderReference.ReferenceDate = inv.OrderDate.Get().String()
+		}
+	} else {
+		m.unmappedIf(inv.OrderDate.IsNotNull(), "order_date")
+	}
+	if inv.CustomerPhone.IsNotNull() {
+		r.Address.Phones = []string{inv.CustomerPhone.String()}
+	}
+	if inv.CustomerEmail.IsNotNull() {
+		r.Address.Emails = []string{inv.CustomerEmail.String()}
+	}
+	return r, nil
+}
+
+// newAddress returns the address with the name
+// which is required in ebInterface addresses.
+// Returns an error if the address or its country is missing
+// because the Country element requires a CountryCode.
+func newAddress(name nullable.TrimmedString, a *invoicing.Address, description string) (address, error) {
+	if a == nil || a.Country.IsNull() {
+		return address{}, fmt.Errorf("%s has no country which ebInterface requires", description)
+	}
+	// ebInterface has no field for the state
+	return address{
+		Name:    name.String(),
+		Street:  a.Street.String(),
+		Town:    a.City.String(),
+		ZIP:     a.PostalCode.String(),
+		Country: &addressCountry{CountryCode: a.Country.String()},
+	}, nil
+}
+
+// hasPeriod returns if the invoice has a period
+// with start and end date
+func (m *marshaller) hasPeriod() bool {
+	return m.inv.PeriodStart.IsNotNull() && m.inv.PeriodEnd.IsNotNull()
+}
+
+// setDeliveryDate sets the invoice period as delivery period,
+// a period of a single day as delivery date,
+// or else the issue date as delivery date,
+// and returns false if the invoice has none of them
+func (m *marshaller) setDeliveryDate(d *delivery) bool {
+	inv := m.inv
+	switch {
+	case m.hasPeriod() && inv.PeriodStart == inv.PeriodEnd:
+		d.Date = inv.PeriodStart.Get().String()
+	case m.hasPeriod():
+		d.Period = &period{
+			FromDate: inv.PeriodStart.Get().String(),
+			ToDate:   inv.PeriodEnd.Get().String(),
+		}
+	case inv.IssueDate.IsNotNull():
+		d.Date = inv.IssueDate.Get().String()
+	default:
+		return false
+	}
+	return true
+}
+
+func (m *marshaller) delivery() (*delivery, error) {
+	inv := m.inv
+	if !m.hasPeriod() {
+		// A period with only one date can't be represented
+		m.unmappedIf(inv.PeriodStart.IsNotNull(), "period_start")
+		m.unmappedIf(inv.PeriodEnd.IsNotNull(), "period_end")
+	}
+	var d delivery
+	if !m.setDeliveryDate(&d) {
+		// A delivery needs a date or period
+		m.unmappedIf(len(inv.DeliveryNoteIDs) > 0, "delivery_note_ids")
+		m.unmappedIf(inv.CustomerShippingAddress != nil, "customer_shipping_address")
+		return nil, nil
+	}
+	for i, id := range inv.DeliveryNoteIDs {
+		if i == 0 {
+			d.DeliveryID = id.String()
+			continue
+		}
+		// ebInterface allows only one delivery ID
+		m.unmapped = append(m.unmapped, fmt.Sprintf("delivery_note_ids[%d]", i))
+	}
+	if inv.CustomerShippingAddress != nil && *inv.CustomerShippingAddress != (invoicing.Address{}) {
+		address, err := newAddress(inv.Customer, inv.CustomerShippingAddress, "customer shipping address")
+		if err != nil {
+			return nil, err
+		}
+		d.Address = &address
+		m.unmappedIf(inv.CustomerShippingAddress.State.IsNotNull(), "customer_shipping_address.state")
+	}
+	return &d, nil
+}
+
+func (m *marshaller) lineItem(index int, item *invoicing.InvoiceItem) (listLineItem, error) {
+	var (
+		inv  = m.inv
+		path = "items[" + strconv.Itoa(index) + "]"
+		sign = einvoice.ItemSign(inv, item)
+		line listLineItem
+	)
+	if item.PositionNumber.IsNotNull() {
+		// ebInterface position numbers are positive integers
+		if n, err := strconv.Atoi(item.PositionNumber.String()); err == nil && n > 0 {
+			line.PositionNumber = strconv.Itoa(n)
+		} else {
+			m.unmapped = append(m.unmapped, path+".position_number")
+		}
+	}
+	if item.Description.IsNotNull() {
+		line.Descriptions = []string{item.Description.String()}
+	}
+	if item.ProductID.IsNotNull() {
+		line.ArticleNumbers = []articleNumber{{ArticleNumberType: articleNumberTypeSeller, Value: item.ProductID.String()}}
+	}
+	if item.OrderID.IsNotNull() {
+		line.InvoiceRecipientsOrderReference = &lineOrderReference{OrderID: item.OrderID.String()}
+	}
+	if item.DeliveryID.IsNotNull() {
+		d := &delivery{DeliveryID: item.DeliveryID.String()}
+		if m.setDeliveryDate(d) {
+			line.Delivery = d
+		} else {
+			m.unmapped = append(m.unmapped, path+".delivery_id")
+		}
+	}
+	m.unmappedIf(item.Currency.IsNotNull() && item.Currency != inv.Currency, path+".currency")
+
+	quantity := 1.0
+	if item.Quantity.IsNotNull() {
+		quantity = item.Quantity.Get()
+	}
+	unitCode, ok := einvoice.UnitCode(item.Unit.String())
+	m.unmappedIf(!ok && item.Unit.IsNotNull(), path+".unit")
+	line.Quantity = itemQuantity{
+		Unit:  unitCode,
+		Value: einvoice.FormatDecimal(float64(sign) * quantity),
+	}
+
+	subtotal := item.Subtotal.Get()
+	switch {
+	case item.UnitPrice.IsNotNull():
+		line.UnitPrice = einvoice.FormatDecimal(float64(item.UnitPrice.Get()))
+	case item.Subtotal.IsNotNull() && quantity != 0 && item.DiscountPercent.IsNull() && item.DiscountAmount.IsNull():
+		line.UnitPrice = einvoice.FormatDecimal(float64(subtotal) / quantity)
+	}
+	if item.Subtotal.IsNull() && item.UnitPrice.IsNotNull() {
+		subtotal = money.Amount(quantity) * item.UnitPrice.Get()
+	}
+
+	if item.DiscountPercent.IsNotNull() || item.DiscountAmount.IsNotNull() {
+		base := money.Amount(quantity) * item.UnitPrice.Get()
+		if item.UnitPrice.IsNull() {
+			base = subtotal + item.DiscountAmount.Get()
+		}
+		r := reduction{BaseAmount: einvoice.FormatAmount(sign * base)}
+		if item.DiscountPercent.IsNotNull() {
+			r.Percentage = einvoice.FormatDecimal(float64(item.DiscountPercent.Get()))
+		}
+		if item.DiscountAmount.IsNotNull() {
+			r.Amount = einvoice.FormatAmount(sign * item.DiscountAmount.Get())
+		}
+		line.ReductionAndSurcharge = &lineItemReductions{Reductions: []reduction{r}}
+	}
+
+	taxPercent, ok := einvoice.ItemTaxPercent(inv, item)
+	if !ok {
+		return listLineItem{}, fmt.Errorf("can't determine the tax percent of %s", path)
+	}
+	line.TaxItem = taxItem{
+		TaxableAmount: einvoice.FormatAmount(sign * subtotal),
+		TaxPercent: taxRate{
+			TaxCategoryCode: string(einvoice.TaxCategoryOf(inv, taxPercent, einvoice.ExemptionReason(inv, taxPercent))),
+			Value:           einvoice.FormatDecimal(float64(taxPercent)),
+		},
+	}
+	if item.TaxAmount.IsNotNull() {
+		line.TaxItem.TaxAmount = einvoice.FormatAmount(sign * item.TaxAmount.Get())
+	}
+	line.LineItemAmount = einvoice.FormatAmount(sign * subtotal)
+	return line, nil
+}
+
+func (m *marshaller) totals(doc *invoice, lineTotal money.Amount) {
+	inv := m.inv
+	var taxBasis, taxTotal money.Amount
+	for _, t := range einvoice.TaxSubtotals(inv) {
+		category := einvoice.TaxCategoryOf(inv, t.TaxPercent.Get(), t.ExemptionReason.String())
+		item := taxItem{
+			TaxableAmount: einvoice.FormatAmount(t.TaxableAmount),
+			TaxPercent: taxRate{
+				TaxCategoryCode: string(category),
+				Value:           einvoice.FormatDecimal(float64(t.TaxPercent.Get())),
+			},
+			TaxAmount: einvoice.FormatAmount(t.TaxAmount),
+			Comment:   t.ExemptionReason.String(),
+		}
+		if category.IsReverseCharge() && item.Comment == "" {
+			item.Comment = inv.ReverseChargeClauseText.StringOr("Übergang der Steuerschuld auf den Leistungsempfänger")
+		}
+		doc.Tax.TaxItems = append(doc.Tax.TaxItems, item)
+		taxBasis += t.TaxableAmount
+		taxTotal += t.TaxAmount
+	}
+	if inv.ReverseChargeClauseText.IsNotNull() && !slices.ContainsFunc(doc.Tax.TaxItems, func(t taxItem) bool { return t.Comment == inv.ReverseChargeClauseText.String() }) {
+		m.unmapped = append(m.unmapped, "reverse_charge_clause_text")
+	}
+	if inv.Subtotal.IsNotNull() {
+		taxBasis = inv.Subtotal.Get()
+	}
+	if inv.Tax.IsNotNull() {
+		taxTotal = inv.Tax.Get()
+	}
+	if len(inv.Items) == 0 {
+		lineTotal = taxBasis
+	}
+
+	// A discount already deducted from the line total
+	// is represented as reductions per tax rate,
+	// otherwise as early payment discount
+	reductions := m.documentReductions(lineTotal, taxBasis)
+	if len(reductions) > 0 {
+		doc.ReductionAndSurchargeDetails = &reductionAndSurchargeDetails{Reductions: reductions}
+	}
+	conditions := paymentConditions{Comment: inv.PaymentTerms.String()}
+	if inv.DueDate.IsNotNull() {
+		conditions.DueDate = inv.DueDate.Get().String()
+	}
+	hasDiscount := inv.DiscountPercent.IsNotNull() || inv.DiscountAmount.IsNotNull()
+	switch {
+	case len(reductions) > 0:
+		m.unmappedIf(inv.DiscountUntilDate.IsNotNull(), "discount_until_date")
+	case hasDiscount && inv.DiscountUntilDate.IsNotNull():
+		d := discount{PaymentDate: inv.DiscountUntilDate.Get().String()}
+		if inv.DiscountPercent.IsNotNull() {
+			d.Percentage = einvoice.FormatDecimal(float64(inv.DiscountPercent.Get()))
+		}
+		if inv.DiscountAmount.IsNotNull() {
+			d.Amount = einvoice.FormatAmount(inv.DiscountAmount.Get())
+		}
+		conditions.Discounts = append(conditions.Discounts, d)
+	default:
+		m.unmappedIf(inv.DiscountPercent.IsNotNull(), "discount_percent")
+		m.unmappedIf(inv.DiscountAmount.IsNotNull(), "discount_amount")
+		m.unmappedIf(inv.DiscountUntilDate.IsNotNull(), "discount_until_date")
+	}
+	if conditions.DueDate != "" || len(conditions.Discounts) > 0 || conditions.Comment != "" {
+		doc.PaymentConditions = &conditions
+	}
+
+	grandTotal := taxBasis + taxTotal
+	if inv.Total.IsNotNull() {
+		grandTotal = inv.Total.Get()
+	}
+	doc.TotalGrossAmount = einvoice.FormatAmount(grandTotal)
+	doc.PayableAmount = einvoice.FormatAmount(grandTotal)
+	if inv.PaymentStatus.IsPaid() {
+		doc.PrepaidAmount = einvoice.FormatAmount(grandTotal)
+		doc.PayableAmount = einvoice.FormatAmount(0)
+	}
+}
+
+// documentReductions returns the einvoice.DocumentAllowances
+// of the invoice as reductions
+func (m *marshaller) documentReductions(lineTotal, taxBasis money.Amount) []headerReduction {
+	inv := m.inv
+	var reductions []headerReduction
+	for _, a := range einvoice.DocumentAllowances(inv, lineTotal, taxBasis) {
+		taxPercent := a.TaxSubtotal.TaxPercent.Get()
+		r := headerReduction{
+			BaseAmount: einvoice.FormatAmount(a.BaseAmount),
+			Amount:     einvoice.FormatAmount(a.Amount),
+			TaxItem: taxItem{
+				TaxableAmount: einvoice.FormatAmount(a.Amount),
+				TaxPercent: taxRate{
+					TaxCategoryCode: string(a.Category),
+					Value:           einvoice.FormatDecimal(float64(taxPercent)),
+				},
+				TaxAmount: einvoice.FormatAmount(a.Amount * money.Amount(taxPercent) / 100),
+			},
+			Comment: "Rabatt",
+		}
+		if inv.DiscountPercent.IsNotNull() {
+			r.Percentage = einvoice.FormatDecimal(float64(inv.DiscountPercent.Get()))
+		}
+		reductions = append(reductions, r)
+	}
+	return reductions
+}
+
+func (m *marshaller) paymentMethod() *paymentMethod {
+	var (
+		inv = m.inv
+		pm  paymentMethod
+	)
+	switch {
+	case inv.DirectDebitMandateID.IsNotNull() || inv.PaymentStatus == invoicing.PaymentStatusPaidWithDirectDebit:
+		pm.SEPADirectDebit = &sepaDirectDebit{MandateReference: inv.DirectDebitMandateID.String()}
+		// The account of a SEPA direct debit is the debtor account
+		m.unmappedIf(inv.PaymentIBAN.IsNotNull(), "payment_iban")
+		m.unmappedIf(inv.PaymentBIC.IsNotNull(), "payment_bic")
+		m.unmappedIf(inv.PaymentReference.IsNotNull(), "payment_reference")
+	case inv.PaymentIBAN.IsNotNull():
+		pm.UniversalBankTransaction = &universalBankTransaction{
+			BeneficiaryAccounts: []beneficiaryAccount{{
+				BIC:  inv.PaymentBIC.String(),
+				IBAN: inv.PaymentIBAN.String(),
+			}},
+			PaymentReference: inv.PaymentReference.String(),
+		}
+	case inv.PaymentStatus.IsPaid() || inv.PaymentStatus == invoicing.PaymentStatusNotPayable:
+		pm.NoPayment = &struct{}{}
+		m.unmappedIf(inv.PaymentBIC.IsNotNull(), "payment_bic")
+		m.unmappedIf(inv.PaymentReference.IsNotNull(), "payment_reference")
+	default:
+		m.unmappedIf(inv.PaymentBIC.IsNotNull(), "payment_bic")
+		m.unmappedIf(inv.PaymentReference.IsNotNull(), "payment_reference")
+		return nil
+	}
+	if inv.PaymentStatus.IsPaid() && pm.paidStatus() != inv.PaymentStatus {
+		// Like PAID_WITH_CASH which has no own payment method
+		m.unmapped = append(m.unmapped, "payment_status")
+	}
+	return &pm
+}
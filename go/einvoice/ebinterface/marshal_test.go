@@ -0,0 +1,277 @@
+package ebinterface
+
+import (
+	"bytes"
+	"reflect"
+	"regexp"
+	"slices"
+	"testing"
+
+	"github.com/domonda/go-types/date"
+	"github.com/domonda/go-types/money"
+	"github.com/domonda/go-types/notnull"
+	"github.com/domonda/go-types/nullable"
+
+	"github.com/docvibe-ai/api/go/einvoice/einvoicetest"
+	"github.com/docvibe-ai/api/go/invoicing"
+)
+
+// testInvoice returns an invoice that survives a round trip
+// through Marshal and Unmarshal unchanged
+func testInvoice() *invoicing.Invoice {
+	inv := einvoicetest.Invoice()
+	inv.OrderDate = "2024-01-20"
+	inv.ContractID = "V-1"
+	inv.DeliveryNoteIDs = []notnull.TrimmedString{"LS-1"}
+	inv.CustomerVATID = "ATU87654321"
+	inv.CustomerPhone = "+43 1 123456"
+	inv.PaymentBIC = "BKAUATWW"
+	inv.PaymentTerms = "30 Tage netto"
+	inv.Notes = []nullable.TrimmedString{"Vielen Dank"}
+	return inv
+}
+
+func TestMarshalUnmarshal(t *testing.T) {
+	tests := []struct {
+		name   string
+		modify func(inv *invoicing.Invoice)
+	}{
+		{name: "invoice", modify: func(inv *invoicing.Invoice) {}},
+		{name: "credit memo", modify: func(inv *invoicing.Invoice) {
+			inv.CreditNote = true
+		}},
+		{name: "delivery date at issue date", modify: func(inv *invoicing.Invoice) {
+			inv.PeriodStart = inv.IssueDate
+			inv.PeriodEnd = inv.IssueDate
+		}},
+		{name: "delivery date", modify: func(inv *invoicing.Invoice) {
+			inv.PeriodStart = date.NullableDate("2024-03-01")
+			inv.PeriodEnd = date.NullableDate("2024-03-01")
+		}},
+		{name: "paid by direct debit", modify: func(inv *invoicing.Invoice) {
+			inv.PaymentStatus = invoicing.PaymentStatusPaidWithDirectDebit
+			inv.DirectDebitMandateID = nullable.TrimmedString("M-1")
+			inv.PaymentIBAN = ""
+			inv.PaymentBIC = ""
+			inv.PaymentReference = ""
+		}},
+		{name: "item details", modify: func(inv *invoicing.Invoice) {
+			inv.Items[0].PositionNumber = nullable.TrimmedString("10")
+			inv.Items[0].ProductID = nullable.TrimmedString("ART-1")
+			inv.Items[0].OrderID = nullable.TrimmedString("PO-7")
+			inv.Items[0].DeliveryID = nullable.TrimmedString("LS-1")
+			inv.Items[0].TaxAmount = einvoicetest.Amount(19)
+			inv.Items[1].Unit = nullable.TrimmedString("HUR")
+		}},
+		{name: "intra-community supply", modify: func(inv *invoicing.Invoice) {
+			inv.ReverseCharge = true
+			inv.ReverseChargeClauseText = nullable.TrimmedString("Steuerfreie innergemeinschaftliche Lieferung")
+			inv.Tax = einvoicetest.Amount(0)
+			inv.Total = einvoicetest.Amount(150)
+			inv.TaxBreakdown = []invoicing.TaxSubtotal{{TaxPercent: einvoicetest.Rate(0), TaxableAmount: 150}}
+			for _, item := range inv.Items {
+				item.TaxPercent = einvoicetest.Rate(0)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := testInvoice()
+			tt.modify(want)
+			data, unmapped, err := Marshal(want)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			if len(unmapped) > 0 {
+				t.Errorf("Marshal() unmapped = %v, want none", unmapped)
+			}
+			got, unmapped, err := Unmarshal(data)
+			if err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if len(unmapped) > 0 {
+				t.Errorf("Unmarshal() unmapped = %v, want none", unmapped)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Unmarshal(Marshal()) =\n%+v\nwant\n%+v\nXML:\n%s", got, want, data)
+			}
+		})
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	tests := []struct {
+		name         string
+		modify       func(inv *invoicing.Invoice)
+		wantErr      bool
+		wantUnmapped []string
+		wantXML      []string
+	}{
+		{
+			name:    "delivery period",
+			modify:  func(inv *invoicing.Invoice) {},
+			wantXML: []string{"<eb:Period><eb:FromDate>2024-02-01</eb:FromDate><eb:ToDate>2024-02-29</eb:ToDate></eb:Period>"},
+		},
+		{
+			name: "issue date as delivery date without period",
+			modify: func(inv *invoicing.Invoice) {
+				inv.PeriodStart = ""
+				inv.PeriodEnd = ""
+			},
+			wantXML: []string{"<eb:Delivery><eb:DeliveryID>LS-1</eb:DeliveryID><eb:Date>2024-03-15</eb:Date></eb:Delivery>"},
+		},
+		{
+			name:         "period start only",
+			modify:       func(inv *invoicing.Invoice) { inv.PeriodEnd = "" },
+			wantUnmapped: []string{"period_start"},
+			wantXML:      []string{"<eb:Date>2024-03-15</eb:Date>"},
+		},
+		{
+			name:         "period end only",
+			modify:       func(inv *invoicing.Invoice) { inv.PeriodStart = "" },
+			wantUnmapped: []string{"period_end"},
+			wantXML:      []string{"<eb:Date>2024-03-15</eb:Date>"},
+		},
+		{
+			name:    "address without country",
+			modify:  func(inv *invoicing.Invoice) { inv.CustomerBillingAddress.Country = "" },
+			wantErr: true,
+		},
+		{
+			name:    "missing issuer address",
+			modify:  func(inv *invoicing.Invoice) { inv.IssuerAddress = nil },
+			wantErr: true,
+		},
+		{
+			name: "shipping address without country",
+			modify: func(inv *invoicing.Invoice) {
+				inv.CustomerShippingAddress = &invoicing.Address{City: "Graz"}
+			},
+			wantErr: true,
+		},
+		{
+			name:         "issuer tax number and buyer reference",
+			modify:       func(inv *invoicing.Invoice) { inv.IssuerTaxNumber = "12 345/6789"; inv.BuyerReference = "B-1" },
+			wantUnmapped: []string{"issuer_tax_number", "buyer_reference"},
+		},
+		{
+			name: "item tax percent from single tax rate",
+			modify: func(inv *invoicing.Invoice) {
+				inv.TaxBreakdown = inv.TaxBreakdown[:1]
+				inv.Items = inv.Items[:1]
+				inv.Items[0].TaxPercent = money.NullableRate{}
+			},
+			wantXML: []string{`<eb:TaxPercent TaxCategoryCode="S">19</eb:TaxPercent>`},
+		},
+		{
+			name:    "item without tax percent in multi-rate invoice",
+			modify:  func(inv *invoicing.Invoice) { inv.Items[1].TaxPercent = money.NullableRate{} },
+			wantErr: true,
+		},
+		{
+			name:    "invalid Austrian VAT ID",
+			modify:  func(inv *invoicing.Invoice) { inv.IssuerVATID = "AT1234" },
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv := testInvoice()
+			tt.modify(inv)
+			data, unmapped, err := Marshal(inv)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Marshal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !slices.Equal(unmapped, tt.wantUnmapped) {
+				t.Errorf("Marshal() unmapped = %v, want %v", unmapped, tt.wantUnmapped)
+			}
+			compact := regexp.MustCompile(`>\s+<`).ReplaceAll(data, []byte("><"))
+			for _, s := range tt.wantXML {
+				if !bytes.Contains(compact, []byte(s)) {
+					t.Errorf("Marshal() XML does not contain %s:\n%s", s, data)
+				}
+			}
+		})
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	invoiceXML := func(namespace, body string) string {
+		return `<Invoice xmlns="` + namespace + `" DocumentType="Invoice" InvoiceCurrency="EUR"><InvoiceNumber>1</InvoiceNumber><InvoiceDate>2024-03-15</InvoiceDate>` + body + `<TotalGrossAmount>0</TotalGrossAmount></Invoice>`
+	}
+	tests := []struct {
+		name            string
+		data            string
+		wantErr         bool
+		wantPeriodStart date.NullableDate
+		wantPeriodEnd   date.NullableDate
+		wantUnmapped    []string
+	}{
+		{
+			name: "ebInterface 6.0",
+			data: invoiceXML("http://www.ebinterface.at/schema/6p0/", ""),
+		},
+		{
+			name:            "delivery date at issue date",
+			data:            invoiceXML(Namespace, "<Delivery><Date>2024-03-15</Date></Delivery>"),
+			wantPeriodStart: "2024-03-15",
+			wantPeriodEnd:   "2024-03-15",
+		},
+		{
+			name:            "delivery period",
+			data:            invoiceXML(Namespace, "<Delivery><Period><FromDate>2024-02-01</FromDate><ToDate>2024-02-29</ToDate></Period></Delivery>"),
+			wantPeriodStart: "2024-02-01",
+			wantPeriodEnd:   "2024-02-29",
+		},
+		{
+			name:         "further biller emails",
+			data:         invoiceXML(Namespace, "<Biller><Address><Email>a@example.com</Email><Email>b@example.com</Email></Address></Biller>"),
+			wantUnmapped: []string{"Biller/Address/Email[1]"},
+		},
+		{
+			name:    "ebInterface 5.0",
+			data:    invoiceXML("http://www.ebinterface.at/schema/5p0/", ""),
+			wantErr: true,
+		},
+		{
+			name:    "UBL invoice",
+			data:    invoiceXML("urn:oasis:names:specification:ubl:schema:xsd:Invoice-2", ""),
+			wantErr: true,
+		},
+		{
+			name:    "no namespace",
+			data:    invoiceXML("", ""),
+			wantErr: true,
+		},
+		{
+			name:    "invalid amount",
+			data:    invoiceXML(Namespace, "<Tax><TaxItem><TaxableAmount>ten</TaxableAmount><TaxPercent>20</TaxPercent></TaxItem></Tax>"),
+			wantErr: true,
+		},
+		{
+			name:    "not XML",
+			data:    "no xml",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv, unmapped, err := Unmarshal([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unmarshal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if inv.PeriodStart != tt.wantPeriodStart || inv.PeriodEnd != tt.wantPeriodEnd {
+				t.Errorf("Unmarshal() period = %q - %q, want %q - %q", inv.PeriodStart, inv.PeriodEnd, tt.wantPeriodStart, tt.wantPeriodEnd)
+			}
+			if !slices.Equal(unmapped, tt.wantUnmapped) {
+				t.Errorf("Unmarshal() unmapped = %v, want %v", unmapped, tt.wantUnmapped)
+			}
+		})
+	}
+}
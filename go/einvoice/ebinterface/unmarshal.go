@@ -0,0 +1,451 @@
+package ebinterface
+
+import (
+	"encoding/xml"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/domonda/go-types/bank"
+	"github.com/domonda/go-types/country"
+	"github.com/domonda/go-types/email"
+	"github.com/domonda/go-types/money"
+	"github.com/domonda/go-types/notnull"
+	"github.com/domonda/go-types/nullable"
+	"github.com/domonda/go-types/vat"
+
+	"github.com/docvibe-ai/api/go/einvoice"
+	"github.com/docvibe-ai/api/go/invoicing"
+)
+
+// Unmarshal parses ebInterface 6.x XML into an invoice,
+// other ebInterface versions are rejected.
+// The Biller is mapped to the Issuer and the InvoiceRecipient
+// to the Customer, the VAT ID "00000000" of parties without VAT ID
+// is returned as null.
+//
+// Elements that are not part of the mapping are ignored.
+// Elements with values that can't be represented by the invoice
+// are returned as unmapped with their element path.
+// The Type of the returned invoice is not set because the direction
+// depends on the point of view, and the invoice is not normalized.
+func Unmarshal(data []byte) (inv *invoicing.Invoice, unmapped []string, err error) {
+	var doc invoice
+	if err = xml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, fmt.Errorf("can't parse ebInterface XML: %w", err)
+	}
+	if !strings.HasPrefix(doc.XMLName.Space, NamespacePrefix6x) {
+		return nil, nil, fmt.Errorf("root element namespace %q is not an ebInterface 6.x namespace", doc.XMLName.Space)
+	}
+	u := &unmarshaller{inv: &invoicing.Invoice{PaymentStatus: invoicing.PaymentStatusUnpaid}}
+	if err = u.invoice(&doc); err != nil {
+		return nil, nil, err
+	}
+	return u.inv, u.unmapped, nil
+}
+
+type unmarshaller struct {
+	inv      *invoicing.Invoice
+	unmapped []string
+}
+
+func (u *unmarshaller) invoice(doc *invoice) error {
+	inv := u.inv
+	inv.InvoiceID = nullable.TrimmedString(strings.TrimSpace(doc.InvoiceNumber))
+	switch doc.DocumentType {
+	case DocumentTypeCreditMemo:
+		inv.CreditNote = true
+	case DocumentTypeInvoice:
+	default:
+		u.unmapped = append(u.unmapped, "DocumentType")
+	}
+	inv.Currency = money.NullableCurrency(strings.TrimSpace(doc.InvoiceCurrency))
+	if d, ok := einvoice.ParseDate(doc.InvoiceDate); ok {
+		inv.IssueDate.Set(d)
+	}
+	if comment := strings.TrimSpace(doc.Comment); comment != "" {
+		for _, n := range strings.Split(comment, "\n") {
+			if n = strings.TrimSpace(n); n != "" {
+				inv.Notes = append(inv.Notes, nullable.TrimmedString(n))
+			}
+		}
+	}
+
+	u.biller(&doc.Biller)
+	u.invoiceRecipient(&doc.InvoiceRecipient)
+	if doc.Delivery != nil {
+		u.delivery(doc.Delivery)
+	}
+	if err := u.totals(doc); err != nil {
+		return err
+	}
+	if doc.PaymentMethod != nil {
+		u.paymentMethod(doc.PaymentMethod)
+	}
+	if doc.PaymentConditions != nil {
+		if err := u.paymentConditions(doc.PaymentConditions); err != nil {
+			return err
+		}
+	}
+
+	var index int
+	for i, list := range doc.Details.ItemLists {
+		for j := range list.LineItems {
+			path := fmt.Sprintf("Details/ItemList[%d]/ListLineItem[%d]", i, j)
+			if err := u.lineItem(path, index, &list.LineItems[j]); err != nil {
+				return err
+			}
+			index++
+		}
+	}
+	return nil
+}
+
+// vatID returns the VAT ID of a VATIdentificationNumber
+// or null for a party without VAT ID
+func vatID(s string) vat.NullableID {
+	s = strings.TrimSpace(s)
+	if strings.Trim(s, "0") == "" {
+		return ""
+	}
+	return vat.NullableID(s)
+}
+
+func (a *address) address() *invoicing.Address {
+	result := &invoicing.Address{
+		Street:     nullable.TrimmedString(strings.TrimSpace(a.Street)),
+		City:       nullable.TrimmedString(strings.TrimSpace(a.Town)),
+		PostalCode: nullable.TrimmedString(strings.TrimSpace(a.ZIP)),
+	}
+	if a.Country != nil {
+		result.Country = country.NullableCode(strings.TrimSpace(a.Country.CountryCode))
+	}
+	if *result == (invoicing.Address{}) {
+		return nil
+	}
+	return result
+}
+
+func (u *unmarshaller) biller(b *biller) {
+	inv := u.inv
+	inv.Issuer = nullable.TrimmedString(strings.TrimSpace(b.Address.Name))
+	inv.IssuerVATID = vatID(b.VATIdentificationNumber)
+	inv.IssuerAddress = b.Address.address()
+	for i, mail := range b.Address.Emails {
+		if i > 0 {
+			u.unmapped = append(u.unmapped, fmt.Sprintf("Biller/Address/Email[%d]", i))
+			continue
+		}
+		inv.IssuerEmail = email.NullableAddress(strings.TrimSpace(mail))
+	}
+}
+
+func (u *unmarshaller) invoiceRecipient(r *invoiceRecipient) {
+	inv := u.inv
+	inv.Customer = nullable.TrimmedString(strings.TrimSpace(r.Address.Name))
+	inv.CustomerVATID = vatID(r.VATIdentificationNumber)
+	inv.CustomerID = nullable.TrimmedString(strings.TrimSpace(r.BillersInvoiceRecipientID))
+	inv.CustomerBillingAddress = r.Address.address()
+	for i, id := range r.FurtherIdentifications {
+		if id.IdentificationType != identificationTypeContract || inv.ContractID.IsNotNull() {
+			u.unmapped = append(u.unmapped, fmt.Sprintf("InvoiceRecipient/FurtherIdentification[%d]", i))
+			continue
+		}
+		inv.ContractID = nullable.TrimmedString(strings.TrimSpace(id.Value))
+	}
+	if r.OrderReference != nil {
+		inv.OrderID = nullable.TrimmedString(strings.TrimSpace(r.OrderReference.OrderID))
+		if d, ok := einvoice.ParseDate(r.OrderReference.ReferenceDate); ok {
+			inv.OrderDate.Set(d)
+		}
+	}
+	for i, phone := range r.Address.Phones {
+		if i > 0 {
+			u.unmapped = append(u.unmapped, fmt.Sprintf("InvoiceRecipient/Address/Phone[%d]", i))
+			continue
+		}
+		inv.CustomerPhone = nullable.TrimmedString(strings.TrimSpace(phone))
+	}
+	for i, mail := range r.Address.Emails {
+		if i > 0 {
+			u.unmapped = append(u.unmapped, fmt.Sprintf("InvoiceRecipient/Address/Email[%d]", i))
+			continue
+		}
+		inv.CustomerEmail = email.NullableAddress(strings.TrimSpace(mail))
+	}
+}
+
+func (u *unmarshaller) delivery(d *delivery) {
+	inv := u.inv
+	if id := strings.TrimSpace(d.DeliveryID); id != "" {
+		inv.DeliveryNoteIDs = append(inv.DeliveryNoteIDs, notnull.TrimmedString(id))
+	}
+	if d.Period != nil {
+		if from, ok := einvoice.ParseDate(d.Period.FromDate); ok {
+			inv.PeriodStart.Set(from)
+		}
+		if to, ok := einvoice.ParseDate(d.Period.ToDate); ok {
+			inv.PeriodEnd.Set(to)
+		}
+	}
+	if date, ok := einvoice.ParseDate(d.Date); ok {
+		inv.PeriodStart.Set(date)
+		inv.PeriodEnd.Set(date)
+	}
+	if d.Address != nil {
+		inv.CustomerShippingAddress = d.Address.address()
+	}
+}
+
+func (u *unmarshaller) totals(doc *invoice) (err error) {
+	inv := u.inv
+	var taxBasis, taxTotal money.Amount
+	for _, item := range doc.Tax.TaxItems {
+		t := invoicing.TaxSubtotal{
+			ExemptionReason: nullable.TrimmedString(strings.TrimSpace(item.Comment)),
+		}
+		if t.TaxableAmount, err = einvoice.ParseAmountAt(item.TaxableAmount, "Tax/TaxItem/TaxableAmount"); err != nil {
+			return err
+		}
+		if t.TaxAmount, err = einvoice.ParseAmountAt(item.TaxAmount, "Tax/TaxItem/TaxAmount"); err != nil {
+			return err
+		}
+		percent, err := einvoice.ParseDecimalAt(item.TaxPercent.Value, "Tax/TaxItem/TaxPercent")
+		if err != nil {
+			return err
+		}
+		t.TaxPercent.Set(money.Rate(percent))
+		if einvoice.TaxCategory(strings.TrimSpace(item.TaxPercent.TaxCategoryCode)).IsReverseCharge() {
+			inv.ReverseCharge = true
+			inv.ReverseChargeClauseText = t.ExemptionReason
+			t.ExemptionReason = ""
+		}
+		inv.TaxBreakdown = append(inv.TaxBreakdown, t)
+		taxBasis += t.TaxableAmount
+		taxTotal += t.TaxAmount
+	}
+	if len(doc.Tax.TaxItems) > 0 {
+		inv.Subtotal.Set(taxBasis.RoundToCents())
+		inv.Tax.Set(taxTotal.RoundToCents())
+	}
+
+	if details := doc.ReductionAndSurchargeDetails; details != nil {
+		var reductionTotal money.Amount
+		for _, r := range details.Reductions {
+			amount, err := einvoice.ParseAmountAt(r.Amount, "ReductionAndSurchargeDetails/Reduction/Amount")
+			if err != nil {
+				return err
+			}
+			reductionTotal += amount
+			if r.Percentage != "" {
+				percent, err := einvoice.ParseDecimalAt(r.Percentage, "ReductionAndSurchargeDetails/Reduction/Percentage")
+				if err != nil {
+					return err
+				}
+				inv.DiscountPercent.Set(money.Rate(percent))
+			}
+		}
+		if reductionTotal != 0 {
+			inv.DiscountAmount.Set(reductionTotal.RoundToCents())
+		}
+		for i := range details.Surcharges {
+			u.unmapped = append(u.unmapped, fmt.Sprintf("ReductionAndSurchargeDetails/Surcharge[%d]", i))
+		}
+	}
+
+	total, err := einvoice.ParseAmountAt(doc.TotalGrossAmount, "TotalGrossAmount")
+	if err != nil {
+		return err
+	}
+	inv.Total.Set(total)
+	if doc.PrepaidAmount != "" {
+		prepaid, err := einvoice.ParseAmountAt(doc.PrepaidAmount, "PrepaidAmount")
+		if err != nil {
+			return err
+		}
+		payable, err := einvoice.ParseAmountAt(doc.PayableAmount, "PayableAmount")
+		if err != nil {
+			return err
+		}
+		switch {
+		case prepaid != 0 && payable == 0:
+			inv.PaymentStatus = doc.PaymentMethod.paidStatus()
+		case prepaid != 0:
+			// Partial prepayments can't be represented
+			u.unmapped = append(u.unmapped, "PrepaidAmount")
+		}
+	}
+	return nil
+}
+
+// paidStatus returns the paid payment status for the payment method
+func (pm *paymentMethod) paidStatus() invoicing.PaymentStatus {
+	switch {
+	case pm == nil:
+		return invoicing.PaymentStatusPaidWithElectronicPaymentMethod
+	case pm.SEPADirectDebit != nil:
+		return invoicing.PaymentStatusPaidWithDirectDebit
+	case pm.UniversalBankTransaction != nil:
+		return invoicing.PaymentStatusPaidWithBankTransfer
+	case pm.PaymentCard != nil:
+		return invoicing.PaymentStatusPaidWithCreditcard
+	}
+	return invoicing.PaymentStatusPaidWithElectronicPaymentMethod
+}
+
+func (u *unmarshaller) paymentMethod(pm *paymentMethod) {
+	inv := u.inv
+	switch {
+	case pm.NoPayment != nil:
+		if !inv.PaymentStatus.IsPaid() {
+			inv.PaymentStatus = invoicing.PaymentStatusNotPayable
+		}
+	case pm.SEPADirectDebit != nil:
+		inv.DirectDebitMandateID = nullable.TrimmedString(strings.TrimSpace(pm.SEPADirectDebit.MandateReference))
+	case pm.UniversalBankTransaction != nil:
+		transaction := pm.UniversalBankTransaction
+		inv.PaymentReference = nullable.TrimmedString(strings.TrimSpace(transaction.PaymentReference))
+		for i, account := range transaction.BeneficiaryAccounts {
+			if i > 0 {
+				// Only one payment account can be represented
+				u.unmapped = append(u.unmapped, fmt.Sprintf("PaymentMethod/UniversalBankTransaction/BeneficiaryAccount[%d]", i))
+				continue
+			}
+			inv.PaymentIBAN = bank.NullableIBAN(strings.TrimSpace(account.IBAN))
+			inv.PaymentBIC = bank.NullableBIC(strings.TrimSpace(account.BIC))
+		}
+	}
+	if strings.TrimSpace(pm.Comment) != "" {
+		u.unmapped = append(u.unmapped, "PaymentMethod/Comment")
+	}
+}
+
+func (u *unmarshaller) paymentConditions(c *paymentConditions) error {
+	inv := u.inv
+	if d, ok := einvoice.ParseDate(c.DueDate); ok {
+		inv.DueDate.Set(d)
+	}
+	inv.PaymentTerms = nullable.TrimmedString(strings.TrimSpace(c.Comment))
+	for i, d := range c.Discounts {
+		if i > 0 || inv.DiscountPercent.IsNotNull() || inv.DiscountAmount.IsNotNull() {
+			// Only one discount can be represented
+			u.unmapped = append(u.unmapped, fmt.Sprintf("PaymentConditions/Discount[%d]", i))
+			continue
+		}
+		if date, ok := einvoice.ParseDate(d.PaymentDate); ok {
+			inv.DiscountUntilDate.Set(date)
+		}
+		if d.Percentage != "" {
+			percent, err := einvoice.ParseDecimalAt(d.Percentage, "PaymentConditions/Discount/Percentage")
+			if err != nil {
+				return err
+			}
+			inv.DiscountPercent.Set(money.Rate(percent))
+		}
+		if d.Amount != "" {
+			amount, err := einvoice.ParseAmountAt(d.Amount, "PaymentConditions/Discount/Amount")
+			if err != nil {
+				return err
+			}
+			inv.DiscountAmount.Set(amount)
+		}
+	}
+	return nil
+}
+
+func (u *unmarshaller) lineItem(path string, index int, line *listLineItem) (err error) {
+	var (
+		inv          = u.inv
+		item         = &invoicing.InvoiceItem{}
+		descriptions []string
+	)
+	if pos := strings.TrimSpace(line.PositionNumber); pos != "" && pos != strconv.Itoa(index+1) {
+		item.PositionNumber = nullable.TrimmedString(pos)
+	}
+	for _, d := range line.Descriptions {
+		if d = strings.TrimSpace(d); d != "" {
+			descriptions = append(descriptions, d)
+		}
+	}
+	item.Description = nullable.TrimmedString(strings.Join(descriptions, "\n"))
+	for i, a := range line.ArticleNumbers {
+		if item.ProductID.IsNotNull() {
+			u.unmapped = append(u.unmapped, fmt.Sprintf("%s/ArticleNumber[%d]", path, i))
+			continue
+		}
+		item.ProductID = nullable.TrimmedString(strings.TrimSpace(a.Value))
+	}
+	if line.InvoiceRecipientsOrderReference != nil {
+		item.OrderID = nullable.TrimmedString(strings.TrimSpace(line.InvoiceRecipientsOrderReference.OrderID))
+	}
+	if line.Delivery != nil {
+		item.DeliveryID = nullable.TrimmedString(strings.TrimSpace(line.Delivery.DeliveryID))
+	}
+
+	quantity, err := einvoice.ParseDecimalAt(line.Quantity.Value, path+"/Quantity")
+	if err != nil {
+		return err
+	}
+	lineTotal, err := einvoice.ParseAmountAt(line.LineItemAmount, path+"/LineItemAmount")
+	if err != nil {
+		return err
+	}
+	if (quantity < 0 || lineTotal < 0) && !inv.CreditNote {
+		item.CreditNote = true
+	}
+	item.Quantity.Set(math.Abs(quantity))
+	item.Subtotal.Set(lineTotal.Abs())
+	if code := strings.TrimSpace(line.Quantity.Unit); code != "" && code != einvoice.DefaultUnitCode {
+		item.Unit = nullable.TrimmedString(code)
+	}
+	if line.UnitPrice != "" {
+		price, err := einvoice.ParseAmountAt(line.UnitPrice, path+"/UnitPrice")
+		if err != nil {
+			return err
+		}
+		item.UnitPrice.Set(price)
+	}
+	if line.TaxItem.TaxPercent.Value != "" {
+		percent, err := einvoice.ParseDecimalAt(line.TaxItem.TaxPercent.Value, path+"/TaxItem/TaxPercent")
+		if err != nil {
+			return err
+		}
+		item.TaxPercent.Set(money.Rate(percent))
+	}
+	if line.TaxItem.TaxAmount != "" {
+		tax, err := einvoice.ParseAmountAt(line.TaxItem.TaxAmount, path+"/TaxItem/TaxAmount")
+		if err != nil {
+			return err
+		}
+		item.TaxAmount.Set(tax.Abs())
+	}
+
+	if details := line.ReductionAndSurcharge; details != nil {
+		var discount money.Amount
+		for _, r := range details.Reductions {
+			if r.Amount != "" {
+				amount, err := einvoice.ParseAmountAt(r.Amount, path+"/ReductionAndSurchargeListLineItemDetails/ReductionListLineItem/Amount")
+				if err != nil {
+					return err
+				}
+				discount += amount.Abs()
+			}
+			if r.Percentage != "" {
+				percent, err := einvoice.ParseDecimalAt(r.Percentage, path+"/ReductionAndSurchargeListLineItemDetails/ReductionListLineItem/Percentage")
+				if err != nil {
+					return err
+				}
+				item.DiscountPercent.Set(money.Rate(percent))
+			}
+		}
+		if discount != 0 {
+			item.DiscountAmount.Set(discount.RoundToCents())
+		}
+		for i := range details.Surcharges {
+			u.unmapped = append(u.unmapped, fmt.Sprintf("%s/ReductionAndSurchargeListLineItemDetails/SurchargeListLineItem[%d]", path, i))
+		}
+	}
+	inv.Items = append(inv.Items, item)
+	return nil
+}
@@ -0,0 +1,87 @@
+// Package einvoicetest provides the invoice used by the round trip
+// tests of the e-invoice formats in the packages below einvoice.
+package einvoicetest
+
+import (
+	"github.com/domonda/go-types/bank"
+	"github.com/domonda/go-types/country"
+	"github.com/domonda/go-types/date"
+	"github.com/domonda/go-types/email"
+	"github.com/domonda/go-types/money"
+	"github.com/domonda/go-types/nullable"
+	"github.com/domonda/go-types/vat"
+
+	"github.com/docvibe-ai/api/go/invoicing"
+)
+
+// Amount returns v as non null amount
+func Amount(v float64) (a money.NullableAmount) {
+	a.Set(money.Amount(v))
+	return a
+}
+
+// Rate returns v as non null rate
+func Rate(v float64) (r money.NullableRate) {
+	r.Set(money.Rate(v))
+	return r
+}
+
+// Quantity returns v as non null quantity
+func Quantity(v float64) (q nullable.Type[float64]) {
+	q.Set(v)
+	return q
+}
+
+// Item returns an item as returned by the Unmarshal functions
+// with the subtotal of qty times unitPrice
+func Item(description string, qty, unitPrice, taxPercent float64) *invoicing.InvoiceItem {
+	return &invoicing.InvoiceItem{
+		Description: nullable.TrimmedString(description),
+		Quantity:    Quantity(qty),
+		UnitPrice:   Amount(unitPrice),
+		Subtotal:    Amount(qty * unitPrice),
+		TaxPercent:  Rate(taxPercent),
+	}
+}
+
+// Invoice returns an invoice of a German seller to an Austrian buyer
+// with the fields that all e-invoice formats map.
+// The tests of the formats add the fields that only their format maps.
+func Invoice() *invoicing.Invoice {
+	return &invoicing.Invoice{
+		InvoiceID:     nullable.TrimmedString("RE-2024-001"),
+		IssueDate:     date.NullableDate("2024-03-15"),
+		DueDate:       date.NullableDate("2024-04-14"),
+		PeriodStart:   date.NullableDate("2024-02-01"),
+		PeriodEnd:     date.NullableDate("2024-02-29"),
+		OrderID:       nullable.TrimmedString("PO-7"),
+		CustomerID:    nullable.TrimmedString("K-42"),
+		Issuer:        nullable.TrimmedString("Muster GmbH"),
+		IssuerVATID:   vat.NullableID("DE123456789"),
+		IssuerEmail:   email.NullableAddress("rechnung@muster.de"),
+		IssuerAddress: &invoicing.Address{Street: "Hauptstraße 1", City: "Berlin", PostalCode: "10115", Country: country.NullableCode("DE")},
+		Customer:      nullable.TrimmedString("Kunde GmbH"),
+		CustomerEmail: email.NullableAddress("eingang@kunde.at"),
+		CustomerBillingAddress: &invoicing.Address{
+			Street:     "Ringstraße 5",
+			City:       "Wien",
+			PostalCode: "1010",
+			Country:    country.NullableCode("AT"),
+		},
+		Currency:         money.NullableCurrency("EUR"),
+		Subtotal:         Amount(150),
+		Tax:              Amount(22.5),
+		Total:            Amount(172.5),
+		PaymentIBAN:      bank.NullableIBAN("DE02120300000000202051"),
+		PaymentReference: nullable.TrimmedString("RE-2024-001"),
+		PaymentStatus:    invoicing.PaymentStatusUnpaid,
+		TaxBreakdown: []invoicing.TaxSubtotal{
+			{TaxPercent: Rate(19), TaxableAmount: 100, TaxAmount: 19},
+			{TaxPercent: Rate(7), TaxableAmount: 50, TaxAmount: 3.5},
+		},
+		Items: []*invoicing.InvoiceItem{
+			Item("Beratung", 2, 50, 19),
+			Item("Buch", 1, 50, 7),
+		},
+	}
+}
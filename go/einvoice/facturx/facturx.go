@@ -0,0 +1,222 @@
+package facturx
+
+import (
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/docvibe-ai/api/go/einvoice/cii"
+	"github.com/docvibe-ai/api/go/einvoice/ebinterface"
+	"github.com/docvibe-ai/api/go/einvoice/ubl"
+	"github.com/docvibe-ai/api/go/invoicing"
+)
+
+// FileNames are the names of embedded invoice XML files
+// in the order of precedence:
+// Factur-X and ZUGFeRD 2.1+ use factur-x.xml,
+// ZUGFeRD 2.0 zugferd-invoice.xml and XRechnung hybrids xrechnung.xml.
+// Names are compared case-insensitive.
+var FileNames = []string{
+	"factur-x.xml",
+	"zugferd-invoice.xml",
+	"xrechnung.xml",
+}
+
+// IsPDF returns if the data starts with a PDF header.
+// The header may be preceded by up to 1024 bytes of garbage.
+func IsPDF(data []byte) bool {
+	return bytes.Contains(data[:min(len(data), 1024+len("%PDF-"))], []byte("%PDF-"))
+}
+
+// EmbeddedXML returns the file name and data of the invoice XML
+// embedded in a ZUGFeRD, Factur-X or XRechnung PDF/A-3 hybrid invoice.
+// Files with one of the FileNames are preferred, else the first
+// embedded XML file with a supported invoice root element is returned.
+// Returns an empty filename and nil data without error
+// if the PDF has no embedded invoice XML.
+func EmbeddedXML(pdf []byte) (filename string, data []byte, err error) {
+	if !IsPDF(pdf) {
+		return "", nil, errors.New("data is not a PDF")
+	}
+	if encryptRegexp.Match(pdf) {
+		return "", nil, errors.New("encrypted PDFs are not supported")
+	}
+	decoder := newStreamDecoder()
+	objects := readObjects(pdf, decoder)
+	files := embeddedFiles(objects)
+	sort.SliceStable(files, func(i, j int) bool {
+		return fileNameRank(files[i].name) < fileNameRank(files[j].name)
+	})
+	var errs []error
+	for _, file := range files {
+		rank := fileNameRank(file.name)
+		if rank == len(FileNames) && !strings.HasSuffix(strings.ToLower(file.name), ".xml") {
+			continue
+		}
+		data, err := file.data(objects, decoder)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("can't read embedded file %s: %w", file.name, err))
+			continue
+		}
+		if rank == len(FileNames) {
+			if _, err := formatOf(data); err != nil {
+				continue
+			}
+		}
+		return file.name, data, nil
+	}
+	return "", nil, errors.Join(errs...)
+}
+
+// encryptRegexp matches the Encrypt entry of a trailer dictionary
+var encryptRegexp = regexp.MustCompile(`/Encrypt\s*(\d+\s+\d+\s+R|<<)`)
+
+// Unmarshal parses the invoice XML embedded in a PDF hybrid invoice.
+// The XML is parsed by cii.Unmarshal for Cross Industry Invoice documents
+// like ZUGFeRD and Factur-X, by ubl.Unmarshal for UBL documents
+// and by ebinterface.Unmarshal for ebInterface documents.
+// Returns a nil invoice without error if the PDF
+// has no embedded invoice XML.
+// See the parsers for the returned unmapped element paths.
+func Unmarshal(pdf []byte) (inv *invoicing.Invoice, unmapped []string, err error) {
+	filename, data, err := EmbeddedXML(pdf)
+	if err != nil || data == nil {
+		return nil, nil, err
+	}
+	format, err := formatOf(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("embedded file %s: %w", filename, err)
+	}
+	switch format {
+	case formatCII:
+		return cii.Unmarshal(data)
+	case formatUBL:
+		return ubl.Unmarshal(data)
+	default:
+		return ebinterface.Unmarshal(data)
+	}
+}
+
+type xmlFormat int
+
+const (
+	formatCII xmlFormat = iota
+	formatUBL
+	formatEbInterface
+)
+
+// formatOf returns the invoice format of XML data by its root element
+func formatOf(data []byte) (xmlFormat, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return 0, fmt.Errorf("can't find XML root element: %w", err)
+		}
+		root, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch {
+		case root.Name.Local == "CrossIndustryInvoice":
+			return formatCII, nil
+		case root.Name.Local == "CrossIndustryDocument":
+			return 0, errors.New("ZUGFeRD 1.0 CrossIndustryDocument is not supported")
+		case root.Name.Space == ubl.NamespaceInvoice || root.Name.Space == ubl.NamespaceCreditNote:
+			return formatUBL, nil
+		case root.Name.Local == "Invoice" && strings.HasPrefix(root.Name.Space, "http://www.ebinterface.at/schema/"):
+			return formatEbInterface, nil
+		}
+		return 0, fmt.Errorf("root element %s in namespace %q is not a supported invoice", root.Name.Local, root.Name.Space)
+	}
+}
+
+// fileNameRank returns the index of the name in FileNames
+// or len(FileNames) for other names
+func fileNameRank(name string) int {
+	index := slices.IndexFunc(FileNames, func(n string) bool { return strings.EqualFold(n, name) })
+	if index == -1 {
+		return len(FileNames)
+	}
+	return index
+}
+
+type embeddedFile struct {
+	name string
+	// stream is the raw value of the embedded file stream reference
+	stream []byte
+}
+
+// data returns the decoded data of the embedded file stream
+func (f *embeddedFile) data(objects map[int]*pdfObject, decoder *streamDecoder) ([]byte, error) {
+	obj := resolve(objects, f.stream)
+	if obj == nil || obj.stream == nil {
+		return nil, errors.New("embedded file stream not found")
+	}
+	dict, ok := parseDict(obj.value)
+	if !ok {
+		return nil, errors.New("invalid embedded file stream dictionary")
+	}
+	data, err := decoder.decode(dict, obj.stream)
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimPrefix(data, []byte("\xEF\xBB\xBF")), nil
+}
+
+// embeddedFiles returns the file specifications with embedded files
+// found in all objects ordered by object number.
+// File specifications can be indirect objects or be nested
+// in the embedded files name tree or the associated files array.
+func embeddedFiles(objects map[int]*pdfObject) []embeddedFile {
+	nums := make([]int, 0, len(objects))
+	for num := range objects {
+		nums = append(nums, num)
+	}
+	slices.Sort(nums)
+	var files []embeddedFile
+	seen := make(map[string]bool)
+	var walk func(value []byte)
+	walk = func(value []byte) {
+		if bytes.HasPrefix(value, []byte("[")) {
+			for _, v := range arrayValues(value) {
+				walk(v)
+			}
+			return
+		}
+		dict, ok := parseDict(value)
+		if !ok {
+			return
+		}
+		if dict["EF"] != nil {
+			if efDict, ok := parseDict(resolveValue(objects, dict["EF"])); ok {
+				filename := text(resolveValue(objects, dict["UF"]))
+				if filename == "" {
+					filename = text(resolveValue(objects, dict["F"]))
+				}
+				stream := efDict["UF"]
+				if stream == nil {
+					stream = efDict["F"]
+				}
+				if stream != nil && !seen[string(stream)] {
+					seen[string(stream)] = true
+					files = append(files, embeddedFile{name: filename, stream: stream})
+				}
+			}
+			return
+		}
+		for _, key := range slices.Sorted(maps.Keys(dict)) {
+			walk(dict[key])
+		}
+	}
+	for _, num := range nums {
+		walk(objects[num].value)
+	}
+	return files
+}
@@ -0,0 +1,256 @@
+package facturx
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/docvibe-ai/api/go/einvoice/cii"
+	"github.com/docvibe-ai/api/go/einvoice/ebinterface"
+	"github.com/docvibe-ai/api/go/einvoice/einvoicetest"
+	"github.com/docvibe-ai/api/go/einvoice/ubl"
+	"github.com/docvibe-ai/api/go/invoicing"
+)
+
+// marshalled returns the XML of the test invoice of package einvoicetest
+// in the format of the marshal function
+func marshalled(t *testing.T, marshal func(*invoicing.Invoice) ([]byte, []string, error)) []byte {
+	t.Helper()
+	data, _, err := marshal(einvoicetest.Invoice())
+	if err != nil {
+		t.Fatalf("can't marshal test invoice: %v", err)
+	}
+	return data
+}
+
+func deflate(data []byte) []byte {
+	var buf bytes.Buffer
+	w := zlib.NewWriter(&buf)
+	w.Write(data)
+	w.Close()
+	return buf.Bytes()
+}
+
+// stream returns a stream object with the entries of the dictionary
+// and the Length of the data
+func stream(dict string, data []byte) string {
+	return fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(data), data)
+}
+
+// buildPDF returns a PDF with the objects numbered from 1
+func buildPDF(objects ...string) []byte {
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.7\n%\xE2\xE3\xCF\xD3\n")
+	for i, obj := range objects {
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	b.WriteString("trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	return b.Bytes()
+}
+
+type attachment struct {
+	name string
+	data []byte
+}
+
+// hybridPDF returns a PDF/A-3 with the attachments as embedded files
+// referenced by the EmbeddedFiles name tree and the AF array
+// of the catalog, compressed by FlateDecode if compress is true
+func hybridPDF(compress bool, attachments ...attachment) []byte {
+	var names, af []string
+	objects := []string{""}
+	for _, a := range attachments {
+		spec := len(objects) + 1
+		names = append(names, fmt.Sprintf("(%s) %d 0 R", a.name, spec))
+		af = append(af, fmt.Sprintf("%d 0 R", spec))
+		objects = append(objects, fmt.Sprintf("<< /Type /Filespec /F (%s) /UF (%s) /AFRelationship /Alternative /EF << /F %d 0 R /UF %d 0 R >> >>", a.name, a.name, spec+1, spec+1))
+		if compress {
+			objects = append(objects, stream("/Type /EmbeddedFile /Subtype /text#2Fxml /Filter /FlateDecode", deflate(a.data)))
+		} else {
+			objects = append(objects, stream("/Type /EmbeddedFile /Subtype /text#2Fxml", a.data))
+		}
+	}
+	objects[0] = fmt.Sprintf("<< /Type /Catalog /Names << /EmbeddedFiles << /Names [%s] >> >> /AF [%s] >>", strings.Join(names, " "), strings.Join(af, " "))
+	return buildPDF(objects...)
+}
+
+// objectStreamPDF returns a PDF with the catalog and file specification
+// of the attachment as objects 10 and 11 in a compressed object stream
+func objectStreamPDF(a attachment) []byte {
+	catalog := "<< /Type /Catalog /Names << /EmbeddedFiles << /Names [(" + a.name + ") 11 0 R] >> >> >>"
+	spec := "<< /Type /Filespec /F (" + a.name + ") /EF << /F 1 0 R >> >>"
+	header := fmt.Sprintf("10 0 11 %d ", len(catalog)+1)
+	objects := header + catalog + " " + spec
+	return buildPDF(
+		stream("/Type /EmbeddedFile", a.data),
+		stream(fmt.Sprintf("/Type /ObjStm /N 2 /First %d /Filter /FlateDecode", len(header)), deflate([]byte(objects))),
+	)
+}
+
+func TestUnmarshal(t *testing.T) {
+	ciiXML := marshalled(t, cii.Marshal)
+	ublXML := marshalled(t, ubl.Marshal)
+	ebXML := marshalled(t, ebinterface.Marshal)
+	tests := []struct {
+		name         string
+		pdf          []byte
+		wantFilename string
+		wantXML      []byte
+		unmarshal    func([]byte) (*invoicing.Invoice, []string, error)
+	}{
+		{
+			name:         "Factur-X",
+			pdf:          hybridPDF(true, attachment{"factur-x.xml", ciiXML}),
+			wantFilename: "factur-x.xml",
+			wantXML:      ciiXML,
+			unmarshal:    cii.Unmarshal,
+		},
+		{
+			name:         "ZUGFeRD 2.0",
+			pdf:          hybridPDF(false, attachment{"zugferd-invoice.xml", ciiXML}),
+			wantFilename: "zugferd-invoice.xml",
+			wantXML:      ciiXML,
+			unmarshal:    cii.Unmarshal,
+		},
+		{
+			name:         "XRechnung hybrid",
+			pdf:          hybridPDF(true, attachment{"xrechnung.xml", ublXML}),
+			wantFilename: "xrechnung.xml",
+			wantXML:      ublXML,
+			unmarshal:    ubl.Unmarshal,
+		},
+		{
+			name:         "ebInterface attachment",
+			pdf:          hybridPDF(true, attachment{"rechnung.xml", ebXML}),
+			wantFilename: "rechnung.xml",
+			wantXML:      ebXML,
+			unmarshal:    ebinterface.Unmarshal,
+		},
+		{
+			name:         "Factur-X preferred over other XML",
+			pdf:          hybridPDF(true, attachment{"other.xml", ublXML}, attachment{"FACTUR-X.XML", ciiXML}),
+			wantFilename: "FACTUR-X.XML",
+			wantXML:      ciiXML,
+			unmarshal:    cii.Unmarshal,
+		},
+		{
+			name:         "other XML without invoice skipped",
+			pdf:          hybridPDF(true, attachment{"metadata.xml", []byte("<metadata/>")}, attachment{"invoice.xml", ublXML}),
+			wantFilename: "invoice.xml",
+			wantXML:      ublXML,
+			unmarshal:    ubl.Unmarshal,
+		},
+		{
+			name:         "UTF-8 byte order mark",
+			pdf:          hybridPDF(false, attachment{"factur-x.xml", append([]byte("\xEF\xBB\xBF"), ciiXML...)}),
+			wantFilename: "factur-x.xml",
+			wantXML:      ciiXML,
+			unmarshal:    cii.Unmarshal,
+		},
+		{
+			name:         "object stream",
+			pdf:          objectStreamPDF(attachment{"factur-x.xml", ciiXML}),
+			wantFilename: "factur-x.xml",
+			wantXML:      ciiXML,
+			unmarshal:    cii.Unmarshal,
+		},
+		{
+			name:         "overflowing stream length",
+			pdf:          buildPDF("<< /Type /Filespec /F (factur-x.xml) /EF << /F 2 0 R >> >>", "<< /Length 9223372036854775807 >>\nstream\n"+string(ciiXML)+"\nendstream"),
+			wantFilename: "factur-x.xml",
+			wantXML:      ciiXML,
+			unmarshal:    cii.Unmarshal,
+		},
+		{
+			name: "no embedded files",
+			pdf:  buildPDF("<< /Type /Catalog /Pages 2 0 R >>", "<< /Type /Pages /Kids [] /Count 0 >>"),
+		},
+		{
+			name: "only non-XML attachments",
+			pdf:  hybridPDF(true, attachment{"invoice.txt", ciiXML}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			filename, data, err := EmbeddedXML(tt.pdf)
+			if err != nil {
+				t.Fatalf("EmbeddedXML() error = %v", err)
+			}
+			if filename != tt.wantFilename || !bytes.Equal(data, tt.wantXML) {
+				t.Errorf("EmbeddedXML() = %q, %d bytes, want %q, %d bytes", filename, len(data), tt.wantFilename, len(tt.wantXML))
+			}
+			inv, unmapped, err := Unmarshal(tt.pdf)
+			if err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if tt.unmarshal == nil {
+				if inv != nil {
+					t.Errorf("Unmarshal() = %+v, want nil", inv)
+				}
+				return
+			}
+			wantInv, wantUnmapped, _ := tt.unmarshal(tt.wantXML)
+			if !reflect.DeepEqual(inv, wantInv) || !reflect.DeepEqual(unmapped, wantUnmapped) {
+				t.Errorf("Unmarshal() = %+v, %v, want %+v, %v", inv, unmapped, wantInv, wantUnmapped)
+			}
+		})
+	}
+}
+
+func TestUnmarshal_malformed(t *testing.T) {
+	ciiXML := marshalled(t, cii.Marshal)
+	objectStream := func(dict, objects string) []byte {
+		return buildPDF(stream("/Type /ObjStm "+dict, []byte(objects)))
+	}
+	// Object streams that each decode to maxStreamSize bytes
+	// use up the decoding budget of all streams
+	// before the embedded file is decoded
+	largeObjectStream := stream("/Type /ObjStm /Filter /FlateDecode", deflate(make([]byte, maxStreamSize)))
+	budgetExceeded := []string{
+		"<< /Type /Filespec /F (factur-x.xml) /EF << /F 2 0 R >> >>",
+		stream("/Filter /FlateDecode", deflate(ciiXML)),
+	}
+	for range maxDecodedSize/maxStreamSize + 1 {
+		budgetExceeded = append(budgetExceeded, largeObjectStream)
+	}
+	tests := []struct {
+		name    string
+		pdf     []byte
+		wantErr bool
+	}{
+		{name: "empty", pdf: nil, wantErr: true},
+		{name: "not a PDF", pdf: ciiXML, wantErr: true},
+		{name: "encrypted", pdf: append(hybridPDF(false, attachment{"factur-x.xml", ciiXML}), "trailer\n<< /Encrypt 9 0 R >>\n"...), wantErr: true},
+		{name: "compression bomb", pdf: hybridPDF(true, attachment{"factur-x.xml", make([]byte, maxStreamSize+1)}), wantErr: true},
+		{name: "total decoded size exceeded", pdf: buildPDF(budgetExceeded...), wantErr: true},
+		{name: "unsupported filter", pdf: buildPDF("<< /Type /Filespec /F (factur-x.xml) /EF << /F 2 0 R >> >>", stream("/Filter /LZWDecode", ciiXML)), wantErr: true},
+		{name: "unsupported predictor", pdf: buildPDF("<< /Type /Filespec /F (factur-x.xml) /EF << /F 2 0 R >> >>", stream("/Filter /FlateDecode /DecodeParms << /Predictor 12 >>", deflate(ciiXML))), wantErr: true},
+		{name: "invalid compressed data", pdf: buildPDF("<< /Type /Filespec /F (factur-x.xml) /EF << /F 2 0 R >> >>", stream("/Filter /FlateDecode", ciiXML)), wantErr: true},
+		{name: "missing embedded file stream", pdf: buildPDF("<< /Type /Filespec /F (factur-x.xml) /EF << /F 9 0 R >> >>"), wantErr: true},
+		{name: "invalid embedded XML", pdf: hybridPDF(false, attachment{"factur-x.xml", []byte("no xml")}), wantErr: true},
+		{name: "truncated", pdf: hybridPDF(false, attachment{"factur-x.xml", ciiXML})[:200]},
+		{name: "negative object stream first", pdf: objectStream("/N 1 /First -5", "1 0 << /EF << /F 2 0 R >> >>")},
+		{name: "negative object stream offset", pdf: objectStream("/N 1 /First 6", "1 -10 << /EF << /F 2 0 R >> >>")},
+		{name: "overflowing object stream offset", pdf: objectStream("/N 1 /First 22", "1 9223372036854775807 << /EF << /F 2 0 R >> >>")},
+		{name: "object stream first beyond data", pdf: objectStream("/N 1 /First 1000", "1 0 << >>")},
+		{name: "deeply nested arrays", pdf: buildPDF(strings.Repeat("[", 100000) + strings.Repeat("]", 100000))},
+		{name: "deeply nested dictionaries", pdf: buildPDF(strings.Repeat("<< /A ", 100000) + strings.Repeat(">>", 100000))},
+		{name: "unterminated string", pdf: buildPDF("<< /F (factur-x.xml /EF << /F 2 0 R >> >>")},
+		{name: "repeated unterminated strings", pdf: []byte("%PDF-1.7\n" + strings.Repeat("1 0 obj (", 100000))},
+		{name: "repeated unterminated hex strings", pdf: []byte("%PDF-1.7\n" + strings.Repeat("1 0 obj <", 100000))},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv, _, err := Unmarshal(tt.pdf)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unmarshal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if inv != nil {
+				t.Errorf("Unmarshal() = %+v, want nil", inv)
+			}
+		})
+	}
+}
@@ -0,0 +1,530 @@
+package facturx
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"unicode/utf16"
+)
+
+// The PDF reader only implements what is needed to find
+// embedded files: it scans the file for indirect objects
+// instead of following the cross-reference tables,
+// which also works for damaged or incrementally updated files,
+// and reads the objects of compressed object streams.
+// Encrypted PDFs are not supported.
+
+type pdfObject struct {
+	// value is the raw object value,
+	// the dictionary for a stream object
+	value []byte
+	// stream is the undecoded stream data
+	// or nil if the object is not a stream
+	stream []byte
+}
+
+const (
+	// maxStreamSize is the maximum size of decoded stream data
+	// to protect against compression bombs
+	maxStreamSize = 32 << 20
+
+	// maxDecodedSize is the maximum total size of the decoded data
+	// of all streams of a PDF to protect against many streams
+	// that each stay below maxStreamSize
+	maxDecodedSize = 2 * maxStreamSize
+
+	// maxNestingDepth is the maximum nesting depth
+	// of arrays and dictionaries
+	maxNestingDepth = 100
+)
+
+var objHeaderRegexp = regexp.MustCompile(`(\d+)\s+\d+\s+obj\b`)
+
+// readObjects returns the indirect objects of a PDF by object number.
+// For objects defined multiple times by incremental updates
+// the last definition is used.
+// The object streams are decoded with the decoder.
+func readObjects(pdf []byte, decoder *streamDecoder) map[int]*pdfObject {
+	objects := make(map[int]*pdfObject)
+	for pos := 0; pos < len(pdf); {
+		loc := objHeaderRegexp.FindSubmatchIndex(pdf[pos:])
+		if loc == nil {
+			break
+		}
+		num, _ := strconv.Atoi(string(pdf[pos+loc[2] : pos+loc[3]]))
+		pos += loc[1]
+		// The value ends before the next endobj or object header
+		// so that an unterminated string or dictionary
+		// is not scanned to the end of the file for every object
+		end := len(pdf)
+		if next := objHeaderRegexp.FindIndex(pdf[pos:]); next != nil {
+			end = pos + next[0]
+		}
+		if i := bytes.Index(pdf[pos:end], []byte("endobj")); i != -1 {
+			end = pos + i
+		}
+		l := &lexer{data: pdf[:end], pos: pos}
+		value, ok := l.value()
+		if !ok {
+			continue
+		}
+		obj := &pdfObject{value: value}
+		l.skipSpace()
+		pos = l.pos
+		if bytes.HasPrefix(pdf[pos:], []byte("stream")) {
+			obj.stream, pos = streamData(pdf, pos+len("stream"), value)
+		}
+		objects[num] = obj
+	}
+	for _, obj := range objects {
+		readObjectStream(objects, obj, decoder)
+	}
+	return objects
+}
+
+// streamData returns the data of a stream starting after the stream keyword
+// and the position after the data
+func streamData(pdf []byte, start int, dict []byte) (data []byte, end int) {
+	// The stream keyword is followed by CRLF or LF
+	if bytes.HasPrefix(pdf[start:], []byte("\r\n")) {
+		start += 2
+	} else if start < len(pdf) && (pdf[start] == '\n' || pdf[start] == '\r') {
+		start++
+	}
+	if d, ok := parseDict(dict); ok {
+		// An indirect length can't be resolved
+		// before all objects are read
+		if length, err := strconv.Atoi(string(d["Length"])); err == nil && length >= 0 && length <= len(pdf)-start {
+			rest := bytes.TrimLeft(pdf[start+length:], "\x00\t\n\f\r ")
+			if bytes.HasPrefix(rest, []byte("endstream")) {
+				return pdf[start : start+length], start + length
+			}
+		}
+	}
+	end = bytes.Index(pdf[start:], []byte("endstream"))
+	if end == -1 {
+		return pdf[start:], len(pdf)
+	}
+	return bytes.TrimRight(pdf[start:start+end], "\r\n"), start + end
+}
+
+// readObjectStream adds the objects of a compressed object stream
+// that are not already defined as indirect objects
+func readObjectStream(objects map[int]*pdfObject, obj *pdfObject, decoder *streamDecoder) {
+	dict, ok := parseDict(obj.value)
+	if !ok || obj.stream == nil || name(dict["Type"]) != "ObjStm" {
+		return
+	}
+	data, err := decoder.decode(dict, obj.stream)
+	if err != nil {
+		return
+	}
+	count, _ := strconv.Atoi(string(dict["N"]))
+	first, err := strconv.Atoi(string(dict["First"]))
+	if err != nil || first < 0 || first > len(data) {
+		return
+	}
+	header := bytes.Fields(data[:first])
+	for i := 0; i < count && 2*i+1 < len(header); i++ {
+		num, err1 := strconv.Atoi(string(header[2*i]))
+		offset, err2 := strconv.Atoi(string(header[2*i+1]))
+		if err1 != nil || err2 != nil || offset < 0 || offset >= len(data)-first || objects[num] != nil {
+			continue
+		}
+		l := &lexer{data: data, pos: first + offset}
+		if value, ok := l.value(); ok {
+			// Copy the value to not keep the decoded stream in memory
+			objects[num] = &pdfObject{value: bytes.Clone(value)}
+		}
+	}
+}
+
+// resolve returns the object value for an indirect reference
+// or the passed value if it is not a reference
+func resolve(objects map[int]*pdfObject, value []byte) *pdfObject {
+	if num, ok := reference(value); ok {
+		return objects[num]
+	}
+	return &pdfObject{value: value}
+}
+
+// resolveValue returns the value of an indirect reference
+// or the passed value if it is not a reference.
+// Returns nil for a reference to a missing object.
+func resolveValue(objects map[int]*pdfObject, value []byte) []byte {
+	if obj := resolve(objects, value); obj != nil {
+		return obj.value
+	}
+	return nil
+}
+
+// streamDecoder decodes the streams of a PDF
+// and limits the total size of the decoded data to maxDecodedSize
+type streamDecoder struct {
+	// remaining is the number of bytes that may still be decoded
+	remaining int
+}
+
+func newStreamDecoder() *streamDecoder {
+	return &streamDecoder{remaining: maxDecodedSize}
+}
+
+// decode returns the decoded data of a stream
+// with the dictionary of the stream object.
+// Returns an error if the decoded data exceeds maxStreamSize
+// or the remaining size of all decoded data.
+func (d *streamDecoder) decode(dict map[string][]byte, data []byte) ([]byte, error) {
+	var filters []string
+	if f := dict["Filter"]; bytes.HasPrefix(f, []byte("[")) {
+		for _, v := range arrayValues(f) {
+			filters = append(filters, name(v))
+		}
+	} else if f != nil {
+		filters = append(filters, name(f))
+	}
+	if params, ok := parseDict(dict["DecodeParms"]); ok {
+		if predictor, _ := strconv.Atoi(string(params["Predictor"])); predictor > 1 {
+			return nil, fmt.Errorf("unsupported stream predictor %d", predictor)
+		}
+	}
+	for _, filter := range filters {
+		switch filter {
+		case "FlateDecode", "Fl":
+			r, err := zlib.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return nil, fmt.Errorf("can't decode stream: %w", err)
+			}
+			data, err = io.ReadAll(io.LimitReader(r, int64(min(maxStreamSize, d.remaining))+1))
+			if err != nil && len(data) == 0 {
+				return nil, fmt.Errorf("can't decode stream: %w", err)
+			}
+			if len(data) > maxStreamSize {
+				return nil, fmt.Errorf("decoded stream exceeds %d bytes", maxStreamSize)
+			}
+			if len(data) > d.remaining {
+				return nil, fmt.Errorf("decoded streams exceed %d bytes in total", maxDecodedSize)
+			}
+			d.remaining -= len(data)
+		default:
+			return nil, fmt.Errorf("unsupported stream filter %s", filter)
+		}
+	}
+	return data, nil
+}
+
+// lexer reads raw PDF values without interpreting them
+type lexer struct {
+	data []byte
+	pos  int
+	// depth is the nesting depth of the current value
+	depth int
+}
+
+func isSpace(c byte) bool {
+	switch c {
+	case 0, '\t', '\n', '\f', '\r', ' ':
+		return true
+	}
+	return false
+}
+
+func isDelimiter(c byte) bool {
+	switch c {
+	case '(', ')', '<', '>', '[', ']', '{', '}', '/', '%':
+		return true
+	}
+	return isSpace(c)
+}
+
+// skipSpace skips whitespace and comments
+func (l *lexer) skipSpace() {
+	for l.pos < len(l.data) {
+		switch c := l.data[l.pos]; {
+		case isSpace(c):
+			l.pos++
+		case c == '%':
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// token returns the regular characters at the current position
+func (l *lexer) token() []byte {
+	start := l.pos
+	for l.pos < len(l.data) && !isDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	return l.data[start:l.pos]
+}
+
+// value returns the raw bytes of the next value,
+// an indirect reference like "12 0 R" is returned as one value.
+// Arrays and dictionaries nested deeper than maxNestingDepth are invalid.
+func (l *lexer) value() ([]byte, bool) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, false
+	}
+	start := l.pos
+	switch l.data[l.pos] {
+	case '<':
+		if bytes.HasPrefix(l.data[l.pos:], []byte("<<")) {
+			if !l.enter() {
+				return nil, false
+			}
+			defer l.leave()
+			l.pos += 2
+			for {
+				l.skipSpace()
+				if bytes.HasPrefix(l.data[l.pos:], []byte(">>")) {
+					l.pos += 2
+					return l.data[start:l.pos], true
+				}
+				if _, ok := l.value(); !ok {
+					return nil, false
+				}
+			}
+		}
+		end := bytes.IndexByte(l.data[l.pos:], '>')
+		if end == -1 {
+			return nil, false
+		}
+		l.pos += end + 1
+		return l.data[start:l.pos], true
+	case '[':
+		if !l.enter() {
+			return nil, false
+		}
+		defer l.leave()
+		l.pos++
+		for {
+			l.skipSpace()
+			if l.pos < len(l.data) && l.data[l.pos] == ']' {
+				l.pos++
+				return l.data[start:l.pos], true
+			}
+			if _, ok := l.value(); !ok {
+				return nil, false
+			}
+		}
+	case '(':
+		depth := 0
+		for ; l.pos < len(l.data); l.pos++ {
+			switch l.data[l.pos] {
+			case '\\':
+				l.pos++
+			case '(':
+				depth++
+			case ')':
+				depth--
+				if depth == 0 {
+					l.pos++
+					return l.data[start:l.pos], true
+				}
+			}
+		}
+		return nil, false
+	case '/':
+		l.pos++
+		l.token()
+		return l.data[start:l.pos], true
+	case ')', '>', ']', '{', '}':
+		return nil, false
+	}
+	tok := l.token()
+	if len(tok) == 0 {
+		return nil, false
+	}
+	if _, err := strconv.Atoi(string(tok)); err == nil {
+		// Look ahead for the generation number and R of a reference
+		end := l.pos
+		l.skipSpace()
+		if _, err := strconv.Atoi(string(l.token())); err == nil {
+			l.skipSpace()
+			if r := l.token(); string(r) == "R" {
+				return l.data[start:l.pos], true
+			}
+		}
+		l.pos = end
+	}
+	return tok, true
+}
+
+// enter increments the nesting depth for an array or dictionary
+// and returns false if it would exceed maxNestingDepth
+func (l *lexer) enter() bool {
+	if l.depth >= maxNestingDepth {
+		return false
+	}
+	l.depth++
+	return true
+}
+
+func (l *lexer) leave() {
+	l.depth--
+}
+
+// parseDict returns the raw values of a dictionary by key
+func parseDict(value []byte) (map[string][]byte, bool) {
+	if !bytes.HasPrefix(value, []byte("<<")) {
+		return nil, false
+	}
+	l := &lexer{data: value, pos: 2}
+	dict := make(map[string][]byte)
+	for {
+		l.skipSpace()
+		if bytes.HasPrefix(value[l.pos:], []byte(">>")) || l.pos >= len(value) {
+			return dict, true
+		}
+		key, ok := l.value()
+		if !ok || key[0] != '/' {
+			return nil, false
+		}
+		v, ok := l.value()
+		if !ok {
+			return nil, false
+		}
+		dict[name(key)] = v
+	}
+}
+
+// arrayValues returns the raw values of an array
+func arrayValues(value []byte) [][]byte {
+	if !bytes.HasPrefix(value, []byte("[")) {
+		return nil
+	}
+	var values [][]byte
+	l := &lexer{data: value, pos: 1}
+	for {
+		l.skipSpace()
+		if l.pos >= len(value) || value[l.pos] == ']' {
+			return values
+		}
+		v, ok := l.value()
+		if !ok {
+			return values
+		}
+		values = append(values, v)
+	}
+}
+
+// reference returns the object number of an indirect reference
+func reference(value []byte) (int, bool) {
+	fields := bytes.Fields(value)
+	if len(fields) != 3 || string(fields[2]) != "R" {
+		return 0, false
+	}
+	num, err := strconv.Atoi(string(fields[0]))
+	return num, err == nil
+}
+
+// name returns a name value without the slash
+// and with #xx escapes decoded
+func name(value []byte) string {
+	if !bytes.HasPrefix(value, []byte("/")) {
+		return ""
+	}
+	value = value[1:]
+	if bytes.IndexByte(value, '#') == -1 {
+		return string(value)
+	}
+	var b []byte
+	for i := 0; i < len(value); i++ {
+		if value[i] == '#' && i+2 < len(value) {
+			if c, err := strconv.ParseUint(string(value[i+1:i+3]), 16, 8); err == nil {
+				b = append(b, byte(c))
+				i += 2
+				continue
+			}
+		}
+		b = append(b, value[i])
+	}
+	return string(b)
+}
+
+// text returns the text of a literal or hexadecimal string value.
+// Strings with byte order mark are UTF-16BE, other strings
+// are interpreted as Latin-1 which matches PDFDocEncoding
+// for the characters used in file names.
+func text(value []byte) string {
+	var b []byte
+	switch {
+	case bytes.HasPrefix(value, []byte("(")) && bytes.HasSuffix(value, []byte(")")):
+		b = unescapeLiteral(value[1 : len(value)-1])
+	case bytes.HasPrefix(value, []byte("<")) && bytes.HasSuffix(value, []byte(">")):
+		digits := bytes.Join(bytes.Fields(value[1:len(value)-1]), nil)
+		if len(digits)%2 == 1 {
+			digits = append(digits, '0')
+		}
+		b = make([]byte, hex.DecodedLen(len(digits)))
+		if _, err := hex.Decode(b, digits); err != nil {
+			return ""
+		}
+	default:
+		return ""
+	}
+	if bytes.HasPrefix(b, []byte{0xFE, 0xFF}) {
+		b = b[2:]
+		u := make([]uint16, len(b)/2)
+		for i := range u {
+			u[i] = uint16(b[2*i])<<8 | uint16(b[2*i+1])
+		}
+		return string(utf16.Decode(u))
+	}
+	r := make([]rune, len(b))
+	for i, c := range b {
+		r[i] = rune(c)
+	}
+	return string(r)
+}
+
+func unescapeLiteral(s []byte) []byte {
+	var b []byte
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b = append(b, s[i])
+			continue
+		}
+		i++
+		switch c := s[i]; c {
+		case 'n':
+			b = append(b, '\n')
+		case 'r':
+			b = append(b, '\r')
+		case 't':
+			b = append(b, '\t')
+		case 'b':
+			b = append(b, '\b')
+		case 'f':
+			b = append(b, '\f')
+		case '\r':
+			// Line continuation
+			if i+1 < len(s) && s[i+1] == '\n' {
+				i++
+			}
+		case '\n':
+			// Line continuation
+		default:
+			if c >= '0' && c <= '7' {
+				n := 0
+				for j := 0; j < 3 && i < len(s) && s[i] >= '0' && s[i] <= '7'; j++ {
+					n = n*8 + int(s[i]-'0')
+					i++
+				}
+				i--
+				b = append(b, byte(n))
+				continue
+			}
+			b = append(b, c)
+		}
+	}
+	return b
+}
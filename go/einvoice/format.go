@@ -0,0 +1,108 @@
+package einvoice
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/domonda/go-types/date"
+	"github.com/domonda/go-types/money"
+
+	"github.com/docvibe-ai/api/go/invoicing"
+)
+
+// ElectronicAddressSchemeEmail is the electronic address scheme (EAS)
+// code for email addresses as seller and buyer electronic address
+const ElectronicAddressSchemeEmail = "EM"
+
+// FormatAmount formats an amount with two decimals
+// as required for amounts in e-invoices
+func FormatAmount(amount money.Amount) string {
+	return strconv.FormatFloat(float64(amount.RoundToCents()), 'f', 2, 64)
+}
+
+// FormatDecimal formats a quantity, price or percentage
+// with as many decimals as necessary up to four
+func FormatDecimal(f float64) string {
+	return strconv.FormatFloat(roundTo(f, 4), 'f', -1, 64)
+}
+
+// ParseAmount parses an amount from an e-invoice
+func ParseAmount(s string) (money.Amount, error) {
+	f, err := parseDecimal(s)
+	return money.Amount(f), err
+}
+
+// ParseAmountAt parses an amount of the element at path
+// from an e-invoice, returning zero for an empty string
+func ParseAmountAt(s, path string) (money.Amount, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	amount, err := ParseAmount(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid amount %q in %s: %w", s, path, err)
+	}
+	return amount, nil
+}
+
+// ParseDecimalAt parses a quantity, price or percentage of the element
+// at path from an e-invoice, returning zero for an empty string
+func ParseDecimalAt(s, path string) (float64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, nil
+	}
+	f, err := parseDecimal(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q in %s: %w", s, path, err)
+	}
+	return f, nil
+}
+
+// decimalRegexp matches the lexical representation of xsd:decimal
+// used for the amounts, quantities and percentages of e-invoices
+var decimalRegexp = regexp.MustCompile(`^[+-]?(\d+(\.\d*)?|\.\d+)$`)
+
+// parseDecimal parses s as xsd:decimal and rejects the exponents,
+// hex floats, underscores, NaN and Inf accepted by strconv.ParseFloat
+func parseDecimal(s string) (float64, error) {
+	if !decimalRegexp.MatchString(s) {
+		return 0, errors.New("not a decimal number")
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+// ParseDate returns the date of an e-invoice date in the format YYYY-MM-DD
+func ParseDate(s string) (date.Date, bool) {
+	t, err := time.Parse(time.DateOnly, strings.TrimSpace(s))
+	if err != nil {
+		return "", false
+	}
+	return date.Date(t.Format(time.DateOnly)), true
+}
+
+// UnmappedFields returns the JSON paths of the non-null invoice fields
+// that can't be represented in any of the e-invoice formats
+func UnmappedFields(inv *invoicing.Invoice) (unmapped []string) {
+	unmappedIf := func(notNull bool, path string) {
+		if notNull {
+			unmapped = append(unmapped, path)
+		}
+	}
+	// The direction of the invoice depends on the point of view
+	// and is not part of the e-invoice
+	unmappedIf(inv.Type.IsNotNull(), "type")
+	unmappedIf(inv.CreditNoteClauseText.IsNotNull(), "credit_note_clause_text")
+	unmappedIf(inv.ReverseChargeReason.IsNotNull(), "reverse_charge_reason")
+	unmappedIf(inv.ReverseChargeProblems.IsNotNull(), "reverse_charge_problems")
+	unmappedIf(len(inv.AccountingEntries) > 0, "accounting_entries")
+	// TaxSubtotals ignores a tax breakdown with entries without tax percentage
+	unmappedIf(slices.ContainsFunc(inv.TaxBreakdown, func(t invoicing.TaxSubtotal) bool { return t.TaxPercent.IsNull() }), "tax_breakdown")
+	return unmapped
+}
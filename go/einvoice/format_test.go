@@ -0,0 +1,162 @@
+package einvoice
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/domonda/go-types/date"
+	"github.com/domonda/go-types/money"
+	"github.com/domonda/go-types/nullable"
+
+	"github.com/docvibe-ai/api/go/invoicing"
+)
+
+func TestFormatAmount(t *testing.T) {
+	tests := []struct {
+		amount money.Amount
+		want   string
+	}{
+		{0, "0.00"},
+		{12.5, "12.50"},
+		{-3.456, "-3.46"},
+		{1000000.004, "1000000.00"},
+	}
+	for _, tt := range tests {
+		if got := FormatAmount(tt.amount); got != tt.want {
+			t.Errorf("FormatAmount(%v) = %q, want %q", float64(tt.amount), got, tt.want)
+		}
+	}
+}
+
+func TestFormatDecimal(t *testing.T) {
+	tests := []struct {
+		f    float64
+		want string
+	}{
+		{19, "19"},
+		{2.5, "2.5"},
+		{0.33333, "0.3333"},
+		{-1, "-1"},
+	}
+	for _, tt := range tests {
+		if got := FormatDecimal(tt.f); got != tt.want {
+			t.Errorf("FormatDecimal(%v) = %q, want %q", tt.f, got, tt.want)
+		}
+	}
+}
+
+func TestParseAmountAt(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    money.Amount
+		wantErr bool
+	}{
+		{s: "", want: 0},
+		{s: " 12.50 ", want: 12.5},
+		{s: "-3", want: -3},
+		{s: "+0.99", want: 0.99},
+		{s: "12,50", wantErr: true},
+		{s: "abc", wantErr: true},
+		{s: "NaN", wantErr: true},
+		{s: "Inf", wantErr: true},
+		{s: "-Infinity", wantErr: true},
+		{s: "0x1p4", wantErr: true},
+		{s: "1_000", wantErr: true},
+		{s: "1e3", wantErr: true},
+		{s: "1" + strings.Repeat("0", 400), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, err := ParseAmountAt(tt.s, "Amount")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseAmountAt(%q) error = %v, wantErr %v", tt.s, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseAmountAt(%q) = %v, want %v", tt.s, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDecimalAt(t *testing.T) {
+	tests := []struct {
+		s       string
+		want    float64
+		wantErr bool
+	}{
+		{s: "", want: 0},
+		{s: "19.00", want: 19},
+		{s: " 0.5", want: 0.5},
+		{s: ".5", want: 0.5},
+		{s: "7.", want: 7},
+		{s: "1e", wantErr: true},
+		{s: "1E2", wantErr: true},
+		{s: "nan", wantErr: true},
+		{s: "+inf", wantErr: true},
+		{s: "0X1.8P1", wantErr: true},
+		{s: ".", wantErr: true},
+		{s: "-", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, err := ParseDecimalAt(tt.s, "Percent")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseDecimalAt(%q) error = %v, wantErr %v", tt.s, err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseDecimalAt(%q) = %v, want %v", tt.s, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseDate(t *testing.T) {
+	tests := []struct {
+		s      string
+		want   date.Date
+		wantOK bool
+	}{
+		{s: "2024-02-29", want: "2024-02-29", wantOK: true},
+		{s: " 2024-01-05\n", want: "2024-01-05", wantOK: true},
+		{s: "2023-02-29"},
+		{s: "20240105"},
+		{s: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			got, ok := ParseDate(tt.s)
+			if got != tt.want || ok != tt.wantOK {
+				t.Errorf("ParseDate(%q) = %q, %v, want %q, %v", tt.s, got, ok, tt.want, tt.wantOK)
+			}
+		})
+	}
+}
+
+func TestUnmappedFields(t *testing.T) {
+	tests := []struct {
+		name string
+		inv  *invoicing.Invoice
+		want []string
+	}{
+		{name: "none", inv: &invoicing.Invoice{}},
+		{
+			name: "all",
+			inv: &invoicing.Invoice{
+				Type:                  invoicing.InvoiceTypeIncoming,
+				CreditNoteClauseText:  nullable.TrimmedString("Gutschrift"),
+				ReverseChargeReason:   nullable.TrimmedString("reason"),
+				ReverseChargeProblems: nullable.TrimmedString("problems"),
+				AccountingEntries:     []*invoicing.AccountingEntry{{}},
+			},
+			want: []string{"type", "credit_note_clause_text", "reverse_charge_reason", "reverse_charge_problems", "accounting_entries"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := UnmappedFields(tt.inv); !slices.Equal(got, tt.want) {
+				t.Errorf("UnmappedFields() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
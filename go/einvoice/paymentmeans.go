@@ -0,0 +1,48 @@
+package einvoice
+
+import "github.com/docvibe-ai/api/go/invoicing"
+
+// Payment means type codes (UNTDID 4461)
+const (
+	PaymentMeansNotDefined   = "1"
+	PaymentMeansCash         = "10"
+	PaymentMeansCreditCard   = "54"
+	PaymentMeansSEPATransfer = "58"
+	PaymentMeansSEPADebit    = "59"
+	PaymentMeansOnline       = "68"
+)
+
+// PaymentMeansCode returns the payment means type code
+// for the payment status and payment fields of the invoice
+// or an empty string if the payment means are unknown
+func PaymentMeansCode(inv *invoicing.Invoice) string {
+	switch {
+	case inv.DirectDebitMandateID.IsNotNull() || inv.PaymentStatus == invoicing.PaymentStatusPaidWithDirectDebit:
+		return PaymentMeansSEPADebit
+	case inv.PaymentStatus == invoicing.PaymentStatusPaidWithCash:
+		return PaymentMeansCash
+	case inv.PaymentStatus == invoicing.PaymentStatusPaidWithCreditcard:
+		return PaymentMeansCreditCard
+	case inv.PaymentStatus == invoicing.PaymentStatusPaidWithBankTransfer || inv.PaymentIBAN.IsNotNull():
+		return PaymentMeansSEPATransfer
+	case inv.PaymentStatus.IsPaid():
+		return PaymentMeansOnline
+	}
+	return ""
+}
+
+// PaidStatusOfMeansCode returns the paid payment status
+// for a payment means type code
+func PaidStatusOfMeansCode(code string) invoicing.PaymentStatus {
+	switch code {
+	case PaymentMeansCash:
+		return invoicing.PaymentStatusPaidWithCash
+	case PaymentMeansCreditCard:
+		return invoicing.PaymentStatusPaidWithCreditcard
+	case PaymentMeansSEPATransfer:
+		return invoicing.PaymentStatusPaidWithBankTransfer
+	case PaymentMeansSEPADebit:
+		return invoicing.PaymentStatusPaidWithDirectDebit
+	}
+	return invoicing.PaymentStatusPaidWithElectronicPaymentMethod
+}
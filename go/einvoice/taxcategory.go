@@ -0,0 +1,163 @@
+package einvoice
+
+import (
+	"slices"
+
+	"github.com/domonda/go-types/money"
+
+	"github.com/docvibe-ai/api/go/invoicing"
+)
+
+// TaxCategory is the VAT category code of EN 16931 (UNTDID 5305)
+type TaxCategory string
+
+const (
+	TaxCategoryStandard       TaxCategory = "S"  // Standard rate
+	TaxCategoryZeroRated      TaxCategory = "Z"  // Zero rated goods
+	TaxCategoryExempt         TaxCategory = "E"  // Exempt from tax
+	TaxCategoryReverseCharge  TaxCategory = "AE" // VAT reverse charge
+	TaxCategoryIntraCommunity TaxCategory = "K"  // Intra-community supply of goods and services
+	TaxCategoryExport         TaxCategory = "G"  // Free export item, tax not charged
+	TaxCategoryOutOfScope     TaxCategory = "O"  // Services outside scope of tax
+)
+
+// IsReverseCharge returns true for the categories
+// where the customer is liable for the VAT
+func (c TaxCategory) IsReverseCharge() bool {
+	return c == TaxCategoryReverseCharge || c == TaxCategoryIntraCommunity
+}
+
+// TaxCategoryOf returns the TaxCategory for a tax percentage of the invoice.
+// Reverse charge invoices between VAT IDs of different EU countries
+// are intra-community supplies, other reverse charge invoices
+// are domestic reverse charge.
+// A non-zero tax percentage is standard rated, also a negative one
+// so that it is reported by the validation as invalid standard rate.
+// A zero tax percentage with exemption reason is exempt, without zero rated.
+func TaxCategoryOf(inv *invoicing.Invoice, taxPercent money.Rate, exemptionReason string) TaxCategory {
+	switch {
+	case inv.ReverseCharge && taxPercent == 0:
+		if IsIntraCommunity(inv) {
+			return TaxCategoryIntraCommunity
+		}
+		return TaxCategoryReverseCharge
+	case taxPercent != 0:
+		return TaxCategoryStandard
+	case exemptionReason != "":
+		return TaxCategoryExempt
+	}
+	return TaxCategoryZeroRated
+}
+
+// ExemptionReason returns the exemption reason
+// of the tax breakdown entry of the invoice with the tax percent
+func ExemptionReason(inv *invoicing.Invoice, taxPercent money.Rate) string {
+	for _, t := range inv.TaxBreakdown {
+		if t.TaxPercent.IsNotNull() && t.TaxPercent.Get() == taxPercent {
+			return t.ExemptionReason.String()
+		}
+	}
+	return ""
+}
+
+// IsIntraCommunity returns if the issuer and customer VAT IDs
+// are from different EU member states
+func IsIntraCommunity(inv *invoicing.Invoice) bool {
+	if inv.IssuerVATID.IsNull() || inv.CustomerVATID.IsNull() {
+		return false
+	}
+	issuer := vatIDPrefix(inv.IssuerVATID.String())
+	customer := vatIDPrefix(inv.CustomerVATID.String())
+	return issuer != customer && slices.Contains(euVATPrefixes, issuer) && slices.Contains(euVATPrefixes, customer)
+}
+
+// VAT ID prefixes of the EU member states,
+// Greece uses EL and Northern Ireland XI
+var euVATPrefixes = []string{
+	"AT", "BE", "BG", "CY", "CZ", "DE", "DK", "EE", "EL", "ES", "FI", "FR", "HR", "HU",
+	"IE", "IT", "LT", "LU", "LV", "MT", "NL", "PL", "PT", "RO", "SE", "SI", "SK", "XI",
+}
+
+func vatIDPrefix(id string) string {
+	if len(id) < 2 {
+		return ""
+	}
+	return id[:2]
+}
+
+// TaxSubtotals returns the TaxBreakdown of the invoice
+// if all its entries have a tax percentage,
+// else the item subtotals and tax amounts summed up
+// per tax percentage if all items have a tax percentage,
+// or a single tax subtotal from the invoice Subtotal and Tax
+// if their ratio matches a VAT rate of the seller's country.
+// Returns nil if the tax subtotals can't be determined.
+func TaxSubtotals(inv *invoicing.Invoice) []invoicing.TaxSubtotal {
+	if len(inv.TaxBreakdown) > 0 && !slices.ContainsFunc(inv.TaxBreakdown, func(t invoicing.TaxSubtotal) bool { return t.TaxPercent.IsNull() }) {
+		return inv.TaxBreakdown
+	}
+	var result []invoicing.TaxSubtotal
+	for _, item := range inv.Items {
+		if item == nil {
+			continue
+		}
+		if item.TaxPercent.IsNull() || item.Subtotal.IsNull() {
+			result = nil
+			break
+		}
+		index := slices.IndexFunc(result, func(t invoicing.TaxSubtotal) bool { return t.TaxPercent == item.TaxPercent })
+		if index == -1 {
+			index = len(result)
+			result = append(result, invoicing.TaxSubtotal{TaxPercent: item.TaxPercent})
+		}
+		result[index].TaxableAmount += ItemSign(inv, item) * item.Subtotal.Get()
+		if item.TaxAmount.IsNotNull() {
+			result[index].TaxAmount += ItemSign(inv, item) * item.TaxAmount.Get()
+		} else {
+			result[index].TaxAmount += ItemSign(inv, item) * (item.Subtotal.Get() * money.Amount(item.TaxPercent.Get()) / 100).RoundToCents()
+		}
+	}
+	if len(result) > 0 {
+		for i := range result {
+			result[i].TaxableAmount = result[i].TaxableAmount.RoundToCents()
+			result[i].TaxAmount = result[i].TaxAmount.RoundToCents()
+		}
+		return result
+	}
+	if inv.Subtotal.IsNull() || inv.Tax.IsNull() {
+		return nil
+	}
+	rate, ok := blendedVATRate(inv, inv.Subtotal.Get(), inv.Tax.Get())
+	if !ok {
+		return nil
+	}
+	subtotal := invoicing.TaxSubtotal{
+		TaxableAmount: inv.Subtotal.Get(),
+		TaxAmount:     inv.Tax.Get(),
+	}
+	subtotal.TaxPercent.Set(rate)
+	return []invoicing.TaxSubtotal{subtotal}
+}
+
+// ItemTaxPercent returns the TaxPercent of the item,
+// or else the tax percentage of the TaxSubtotals
+// if the invoice has a single tax rate.
+// Returns false if the tax percentage can't be determined.
+func ItemTaxPercent(inv *invoicing.Invoice, item *invoicing.InvoiceItem) (money.Rate, bool) {
+	if item.TaxPercent.IsNotNull() {
+		return item.TaxPercent.Get(), true
+	}
+	if subtotals := TaxSubtotals(inv); len(subtotals) == 1 && subtotals[0].TaxPercent.IsNotNull() {
+		return subtotals[0].TaxPercent.Get(), true
+	}
+	return 0, false
+}
+
+// ItemSign returns -1 for a credit note item
+// within an invoice that is not a credit note, else 1
+func ItemSign(inv *invoicing.Invoice, item *invoicing.InvoiceItem) money.Amount {
+	if item.CreditNote && !inv.CreditNote {
+		return -1
+	}
+	return 1
+}
@@ -0,0 +1,135 @@
+package einvoice
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/domonda/go-types/country"
+	"github.com/domonda/go-types/money"
+	"github.com/domonda/go-types/vat"
+
+	"github.com/docvibe-ai/api/go/einvoice/einvoicetest"
+	"github.com/docvibe-ai/api/go/invoicing"
+)
+
+func TestTaxCategoryOf(t *testing.T) {
+	tests := []struct {
+		name            string
+		inv             *invoicing.Invoice
+		taxPercent      money.Rate
+		exemptionReason string
+		want            TaxCategory
+	}{
+		{name: "standard", inv: &invoicing.Invoice{}, taxPercent: 19, want: TaxCategoryStandard},
+		{name: "zero rated", inv: &invoicing.Invoice{}, taxPercent: 0, want: TaxCategoryZeroRated},
+		{name: "negative rate", inv: &invoicing.Invoice{}, taxPercent: -19, want: TaxCategoryStandard},
+		{name: "exempt", inv: &invoicing.Invoice{}, taxPercent: 0, exemptionReason: "§ 4 UStG", want: TaxCategoryExempt},
+		{name: "domestic reverse charge", inv: &invoicing.Invoice{ReverseCharge: true, IssuerVATID: "DE123456789", CustomerVATID: "DE987654321"}, want: TaxCategoryReverseCharge},
+		{name: "intra-community", inv: &invoicing.Invoice{ReverseCharge: true, IssuerVATID: "DE123456789", CustomerVATID: "ATU12345678"}, want: TaxCategoryIntraCommunity},
+		{name: "reverse charge with tax", inv: &invoicing.Invoice{ReverseCharge: true}, taxPercent: 19, want: TaxCategoryStandard},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TaxCategoryOf(tt.inv, tt.taxPercent, tt.exemptionReason); got != tt.want {
+				t.Errorf("TaxCategoryOf() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTaxSubtotals(t *testing.T) {
+	item := func(subtotal float64, taxPercent ...float64) *invoicing.InvoiceItem {
+		i := &invoicing.InvoiceItem{Subtotal: einvoicetest.Amount(subtotal)}
+		if len(taxPercent) > 0 {
+			i.TaxPercent = einvoicetest.Rate(taxPercent[0])
+		}
+		return i
+	}
+	subtotal := func(taxPercent, taxable, tax float64) invoicing.TaxSubtotal {
+		return invoicing.TaxSubtotal{TaxPercent: einvoicetest.Rate(taxPercent), TaxableAmount: money.Amount(taxable), TaxAmount: money.Amount(tax)}
+	}
+	germanIssuer := &invoicing.Address{Country: country.NullableCode("DE")}
+	tests := []struct {
+		name string
+		inv  *invoicing.Invoice
+		want []invoicing.TaxSubtotal
+	}{
+		{
+			name: "tax breakdown",
+			inv: &invoicing.Invoice{
+				TaxBreakdown: []invoicing.TaxSubtotal{subtotal(19, 100, 19)},
+				Items:        []*invoicing.InvoiceItem{item(50, 7)},
+			},
+			want: []invoicing.TaxSubtotal{subtotal(19, 100, 19)},
+		},
+		{
+			name: "breakdown without tax percent uses items",
+			inv: &invoicing.Invoice{
+				TaxBreakdown: []invoicing.TaxSubtotal{{TaxableAmount: 150, TaxAmount: 22.5}},
+				Items:        []*invoicing.InvoiceItem{item(100, 19), item(50, 7), nil},
+			},
+			want: []invoicing.TaxSubtotal{subtotal(19, 100, 19), subtotal(7, 50, 3.5)},
+		},
+		{
+			name: "credit note items",
+			inv: &invoicing.Invoice{
+				Items: []*invoicing.InvoiceItem{item(100, 19), {Subtotal: einvoicetest.Amount(20), TaxPercent: einvoicetest.Rate(19), CreditNote: true}},
+			},
+			want: []invoicing.TaxSubtotal{subtotal(19, 80, 15.2)},
+		},
+		{
+			name: "blended rate of seller country",
+			inv: &invoicing.Invoice{
+				IssuerAddress: germanIssuer,
+				Subtotal:      einvoicetest.Amount(100),
+				Tax:           einvoicetest.Amount(7),
+				Items:         []*invoicing.InvoiceItem{item(100)},
+			},
+			want: []invoicing.TaxSubtotal{subtotal(7, 100, 7)},
+		},
+		{
+			name: "blended rate with rounding difference",
+			inv: &invoicing.Invoice{
+				IssuerVATID: vat.NullableID("ATU12345678"),
+				Subtotal:    einvoicetest.Amount(33.33),
+				Tax:         einvoicetest.Amount(6.67),
+			},
+			want: []invoicing.TaxSubtotal{subtotal(20, 33.33, 6.67)},
+		},
+		{
+			name: "blended rate of mixed rates",
+			inv: &invoicing.Invoice{
+				IssuerAddress: germanIssuer,
+				Subtotal:      einvoicetest.Amount(150),
+				Tax:           einvoicetest.Amount(22.5),
+				Items:         []*invoicing.InvoiceItem{item(100), item(50)},
+			},
+		},
+		{
+			name: "blended rate of unknown seller country",
+			inv: &invoicing.Invoice{
+				Subtotal: einvoicetest.Amount(100),
+				Tax:      einvoicetest.Amount(19),
+			},
+		},
+		{
+			name: "zero subtotal",
+			inv: &invoicing.Invoice{
+				IssuerAddress: germanIssuer,
+				Subtotal:      einvoicetest.Amount(0),
+				Tax:           einvoicetest.Amount(0),
+			},
+		},
+		{
+			name: "no amounts",
+			inv:  &invoicing.Invoice{IssuerAddress: germanIssuer},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := TaxSubtotals(tt.inv); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("TaxSubtotals() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,491 @@
+package ubl
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/domonda/go-types/money"
+
+	"github.com/docvibe-ai/api/go/einvoice"
+	"github.com/docvibe-ai/api/go/invoicing"
+)
+
+// Marshal returns the invoice as UBL 2.1 XML
+// with a CreditNote document for a credit note
+// and an Invoice document otherwise.
+//
+// The document follows the EN 16931 UBL syntax binding but also
+// contains the fields of the invoice that EN 16931 can't represent
+// where UBL has an element for them, like the OrderID, DeliveryID
+// and TaxAmount of items, the OrderDate and the PaidDate,
+// so that Unmarshal returns the same invoice.
+// Items without Quantity are written with the quantity 1
+// and items without UnitPrice with the price of their Subtotal
+// because EN 16931 requires both for every line.
+// Fields that can't be represented at all are returned as unmapped
+// with their JSON path like "type" or "items[2].unit".
+// Returns an error if the tax percentage of an item can't be determined,
+// see einvoice.ItemTaxPercent.
+// Marshal does not validate the invoice against the business rules of EN 16931.
+func Marshal(inv *invoicing.Invoice) (data []byte, unmapped []string, err error) {
+	return marshal(inv, CustomizationEN16931, "")
+}
+
+// MarshalPeppol returns the invoice as UBL 2.1 XML like Marshal
+// with the specification identifier of Peppol BIS Billing 3.0.
+// Returns an error if the invoice has neither BuyerReference nor OrderID,
+// or no IssuerEmail or CustomerEmail which Peppol requires
+// as electronic addresses of seller and buyer.
+func MarshalPeppol(inv *invoicing.Invoice) (data []byte, unmapped []string, err error) {
+	if inv == nil {
+		return nil, nil, errors.New("invoice is nil")
+	}
+	var errs []error
+	if inv.BuyerReference.IsNull() && inv.OrderID.IsNull() {
+		errs = append(errs, errors.New("Peppol requires a buyer reference or order ID"))
+	}
+	if inv.IssuerEmail.IsNull() {
+		errs = append(errs, errors.New("Peppol requires an issuer email as seller electronic address"))
+	}
+	if inv.CustomerEmail.IsNull() {
+		errs = append(errs, errors.New("Peppol requires a customer email as buyer electronic address"))
+	}
+	if len(errs) > 0 {
+		return nil, nil, errors.Join(errs...)
+	}
+	return marshal(inv, CustomizationPeppol, ProfilePeppolBilling)
+}
+
+func marshal(inv *invoicing.Invoice, customizationID, profileID string) (data []byte, unmapped []string, err error) {
+	if inv == nil {
+		return nil, nil, errors.New("invoice is nil")
+	}
+	for i, item := range inv.Items {
+		if item == nil {
+			continue
+		}
+		if _, ok := einvoice.ItemTaxPercent(inv, item); !ok {
+			return nil, nil, fmt.Errorf("can't determine the tax percent of items[%d]", i)
+		}
+	}
+	m := &marshaller{inv: inv}
+	doc := m.document()
+	doc.CustomizationID = customizationID
+	doc.ProfileID = profileID
+	data, err = xml.Marshal(doc)
+	if err != nil {
+		return nil, nil, err
+	}
+	namespace := NamespaceInvoice
+	if inv.CreditNote {
+		namespace = NamespaceCreditNote
+	}
+	namespaceAttrs := []xml.Attr{
+		einvoice.NamespaceAttr("", namespace),
+		einvoice.NamespaceAttr("cac", NamespaceCAC),
+		einvoice.NamespaceAttr("cbc", NamespaceCBC),
+	}
+	data, err = einvoice.AddNamespacePrefixes(data, namespaceAttrs, namespacePrefix)
+	if err != nil {
+		return nil, nil, err
+	}
+	return append([]byte(xml.Header), data...), m.unmapped, nil
+}
+
+type marshaller struct {
+	inv      *invoicing.Invoice
+	unmapped []string
+}
+
+func (m *marshaller) unmappedIf(notNull bool, path string) {
+	if notNull {
+		m.unmapped = append(m.unmapped, path)
+	}
+}
+
+// amount returns the amount in the invoice currency
+func (m *marshaller) amount(a money.Amount) amount {
+	return amount{CurrencyID: m.inv.Currency.String(), Value: einvoice.FormatAmount(a)}
+}
+
+func (m *marshaller) document() *document {
+	inv := m.inv
+	doc := &document{
+		XMLName:              xml.Name{Local: rootInvoice},
+		ID:                   inv.InvoiceID.String(),
+		InvoiceTypeCode:      TypeCodeInvoice,
+		DocumentCurrencyCode: inv.Currency.String(),
+		BuyerReference:       inv.BuyerReference.String(),
+	}
+	if inv.CreditNote {
+		doc.XMLName.Local = rootCreditNote
+		doc.InvoiceTypeCode = ""
+		doc.CreditNoteTypeCode = TypeCodeCreditNote
+	}
+	if inv.IssueDate.IsNotNull() {
+		doc.IssueDate = inv.IssueDate.Get().String()
+	}
+	if inv.DueDate.IsNotNull() && !inv.CreditNote {
+		// A UBL 2.1 CreditNote has the due date
+		// in the payment means instead
+		doc.DueDate = inv.DueDate.Get().String()
+	}
+	for _, n := range inv.Notes {
+		doc.Notes = append(doc.Notes, n.String())
+	}
+	if inv.PeriodStart.IsNotNull() || inv.PeriodEnd.IsNotNull() {
+		doc.InvoicePeriod = &period{}
+		if inv.PeriodStart.IsNotNull() {
+			doc.InvoicePeriod.StartDate = inv.PeriodStart.Get().String()
+		}
+		if inv.PeriodEnd.IsNotNull() {
+			doc.InvoicePeriod.EndDate = inv.PeriodEnd.Get().String()
+		}
+	}
+	if inv.OrderID.IsNotNull() {
+		doc.OrderReference = &orderReference{ID: inv.OrderID.String()}
+		if inv.OrderDate.IsNotNull() {
+			doc.OrderReference.IssueDate = inv.OrderDate.Get().String()
+		}
+	} else {
+		m.unmappedIf(inv.OrderDate.IsNotNull(), "order_date")
+	}
+	for i, id := range inv.DeliveryNoteIDs {
+		if i == 0 {
+			doc.DespatchDocumentReferences = append(doc.DespatchDocumentReferences, identifier{ID: id.String()})
+			continue
+		}
+		// EN 16931 allows only one despatch advice reference
+		m.unmapped = append(m.unmapped, fmt.Sprintf("delivery_note_ids[%d]", i))
+	}
+	if inv.ContractID.IsNotNull() {
+		doc.ContractDocumentReference = &identifier{ID: inv.ContractID.String()}
+	}
+	m.unmapped = append(m.unmapped, einvoice.UnmappedFields(inv)...)
+
+	doc.AccountingSupplierParty.Party = m.supplierParty()
+	doc.AccountingCustomerParty.Party = m.customerParty()
+	if address := newAddress(inv.CustomerShippingAddress); address != nil {
+		doc.Delivery = &delivery{Address: address}
+	}
+	if means, ok := m.paymentMeans(); ok {
+		doc.PaymentMeans = append(doc.PaymentMeans, means)
+	}
+
+	var lineTotal money.Amount
+	for i, item := range inv.Items {
+		if item == nil {
+			continue
+		}
+		l := m.line(i, item)
+		if inv.CreditNote {
+			doc.CreditNoteLines = append(doc.CreditNoteLines, l)
+		} else {
+			doc.InvoiceLines = append(doc.InvoiceLines, l)
+		}
+		if item.Currency.IsNull() || item.Currency == inv.Currency {
+			amount, _ := einvoice.ParseAmount(l.LineExtensionAmount.Value)
+			lineTotal += amount
+		}
+	}
+	m.totals(doc, lineTotal.RoundToCents())
+	return doc
+}
+
+func (m *marshaller) supplierParty() party {
+	inv := m.inv
+	p := party{
+		EndpointID:    newEmailEndpointID(inv.IssuerEmail.String()),
+		PostalAddress: newAddress(inv.IssuerAddress),
+	}
+	if inv.IssuerVATID.IsNotNull() {
+		p.TaxSchemes = append(p.TaxSchemes, partyTaxScheme{CompanyID: inv.IssuerVATID.String(), TaxSchemeID: taxSchemeVAT})
+	}
+	if inv.IssuerTaxNumber.IsNotNull() {
+		p.TaxSchemes = append(p.TaxSchemes, partyTaxScheme{CompanyID: inv.IssuerTaxNumber.String(), TaxSchemeID: taxSchemeTaxNumber})
+	}
+	p.LegalEntity = &partyLegalEntity{RegistrationName: inv.Issuer.String()}
+	return p
+}
+
+func (m *marshaller) customerParty() party {
+	inv := m.inv
+	p := party{
+		EndpointID:    newEmailEndpointID(inv.CustomerEmail.String()),
+		PostalAddress: newAddress(inv.CustomerBillingAddress),
+	}
+	if inv.CustomerID.IsNotNull() {
+		p.Identifications = []identifier{{ID: inv.CustomerID.String()}}
+	}
+	if inv.CustomerVATID.IsNotNull() {
+		p.TaxSchemes = append(p.TaxSchemes, partyTaxScheme{CompanyID: inv.CustomerVATID.String(), TaxSchemeID: taxSchemeVAT})
+	}
+	p.LegalEntity = &partyLegalEntity{RegistrationName: inv.Customer.String()}
+	if inv.CustomerPhone.IsNotNull() || inv.CustomerEmail.IsNotNull() {
+		p.Contact = &contact{
+			Telephone:      inv.CustomerPhone.String(),
+			ElectronicMail: inv.CustomerEmail.String(),
+		}
+	}
+	return p
+}
+
+func newAddress(a *invoicing.Address) *address {
+	if a == nil || *a == (invoicing.Address{}) {
+		return nil
+	}
+	result := &address{
+		StreetName:       a.Street.String(),
+		CityName:         a.City.String(),
+		PostalZone:       a.PostalCode.String(),
+		CountrySubentity: a.State.String(),
+	}
+	if a.Country.IsNotNull() {
+		result.Country = &addressCountry{IdentificationCode: a.Country.String()}
+	}
+	return result
+}
+
+func (m *marshaller) paymentMeans() (paymentMeans, bool) {
+	inv := m.inv
+	code := einvoice.PaymentMeansCode(inv)
+	if inv.PaymentStatus.IsPaid() && einvoice.PaidStatusOfMeansCode(code) != inv.PaymentStatus {
+		// Like PAID_WITH_STRIPE which has no own payment means code
+		m.unmapped = append(m.unmapped, "payment_status")
+	}
+	m.unmappedIf(inv.PaymentStatus == invoicing.PaymentStatusNotPayable, "payment_status")
+	hasDueDate := inv.CreditNote && inv.DueDate.IsNotNull()
+	if code == "" && (hasDueDate || inv.PaymentReference.IsNotNull() || inv.PaymentBIC.IsNotNull()) {
+		code = einvoice.PaymentMeansNotDefined
+	}
+	if code == "" {
+		return paymentMeans{}, false
+	}
+	means := paymentMeans{
+		Code:           code,
+		PaymentID:      inv.PaymentReference.String(),
+		PaymentMandate: newIdentifier(inv.DirectDebitMandateID.String()),
+	}
+	if hasDueDate {
+		means.PaymentDueDate = inv.DueDate.Get().String()
+	}
+	switch {
+	case inv.PaymentIBAN.IsNotNull():
+		means.PayeeAccount = &financialAccount{
+			ID:     inv.PaymentIBAN.String(),
+			Branch: newIdentifier(inv.PaymentBIC.String()),
+		}
+	case inv.PaymentBIC.IsNotNull():
+		// The account ID is mandatory in a payee financial account
+		m.unmapped = append(m.unmapped, "payment_bic")
+	}
+	return means, true
+}
+
+func (m *marshaller) line(index int, item *invoicing.InvoiceItem) line {
+	var (
+		inv  = m.inv
+		path = "items[" + strconv.Itoa(index) + "]"
+		sign = einvoice.ItemSign(inv, item)
+		l    line
+	)
+	// Amounts of the item are in the item currency
+	currency := inv.Currency.String()
+	if item.Currency.IsNotNull() {
+		currency = item.Currency.String()
+	}
+	itemAmount := func(a money.Amount) amount {
+		return amount{CurrencyID: currency, Value: einvoice.FormatAmount(a)}
+	}
+
+	l.ID = item.PositionNumber.StringOr(strconv.Itoa(index + 1))
+	l.Item.Name = item.Description.String()
+	l.Item.SellersItemID = newIdentifier(item.ProductID.String())
+	if item.OrderID.IsNotNull() {
+		l.OrderLineReference = &orderLineRef{LineID: unknownLineID, OrderID: item.OrderID.String()}
+	}
+	if item.DeliveryID.IsNotNull() {
+		l.DespatchLineReference = &despatchLineRef{LineID: unknownLineID, DocumentID: item.DeliveryID.String()}
+	}
+
+	quantityValue := 1.0
+	if item.Quantity.IsNotNull() {
+		quantityValue = item.Quantity.Get()
+	}
+	unitCode, ok := einvoice.UnitCode(item.Unit.String())
+	m.unmappedIf(!ok && item.Unit.IsNotNull(), path+".unit")
+	q := &quantity{
+		UnitCode: unitCode,
+		Value:    einvoice.FormatDecimal(float64(sign) * quantityValue),
+	}
+	if inv.CreditNote {
+		l.CreditedQuantity = q
+	} else {
+		l.InvoicedQuantity = q
+	}
+
+	subtotal := item.Subtotal.Get()
+	switch {
+	case item.UnitPrice.IsNotNull():
+		l.Price = &price{PriceAmount: amount{CurrencyID: currency, Value: einvoice.FormatDecimal(float64(item.UnitPrice.Get()))}}
+	case item.Subtotal.IsNotNull() && quantityValue != 0 && item.DiscountPercent.IsNull() && item.DiscountAmount.IsNull():
+		l.Price = &price{PriceAmount: amount{CurrencyID: currency, Value: einvoice.FormatDecimal(float64(subtotal) / quantityValue)}}
+	}
+	if item.Subtotal.IsNull() && item.UnitPrice.IsNotNull() {
+		subtotal = money.Amount(quantityValue) * item.UnitPrice.Get()
+	}
+	l.LineExtensionAmount = itemAmount(sign * subtotal)
+
+	if item.DiscountPercent.IsNotNull() || item.DiscountAmount.IsNotNull() {
+		allowance := allowanceCharge{Amount: itemAmount(sign * item.DiscountAmount.Get())}
+		if item.DiscountPercent.IsNotNull() {
+			allowance.MultiplierFactorNumeric = einvoice.FormatDecimal(float64(item.DiscountPercent.Get()))
+			if item.UnitPrice.IsNotNull() {
+				base := money.Amount(quantityValue) * item.UnitPrice.Get()
+				baseAmount := itemAmount(sign * base)
+				allowance.BaseAmount = &baseAmount
+				if item.DiscountAmount.IsNull() {
+					allowance.Amount = itemAmount(sign * base * money.Amount(item.DiscountPercent.Get()) / 100)
+				}
+			}
+		}
+		l.AllowanceCharges = append(l.AllowanceCharges, allowance)
+	}
+
+	if item.TaxAmount.IsNotNull() {
+		l.TaxTotal = &taxTotal{TaxAmount: itemAmount(sign * item.TaxAmount.Get())}
+	}
+	// Marshal checked that the tax percent can be determined
+	taxPercent, _ := einvoice.ItemTaxPercent(inv, item)
+	l.Item.ClassifiedTaxCategory = taxCategory{
+		ID:          string(einvoice.TaxCategoryOf(inv, taxPercent, einvoice.ExemptionReason(inv, taxPercent))),
+		Percent:     einvoice.FormatDecimal(float64(taxPercent)),
+		TaxSchemeID: taxSchemeVAT,
+	}
+	return l
+}
+
+func (m *marshaller) totals(doc *document, lineTotal money.Amount) {
+	inv := m.inv
+	var (
+		total    taxTotal
+		taxBasis money.Amount
+		taxSum   money.Amount
+	)
+	for _, t := range einvoice.TaxSubtotals(inv) {
+		category := einvoice.TaxCategoryOf(inv, t.TaxPercent.Get(), t.ExemptionReason.String())
+		sub := taxSubtotal{
+			TaxableAmount: m.amount(t.TaxableAmount),
+			TaxAmount:     m.amount(t.TaxAmount),
+			TaxCategory: taxCategory{
+				ID:                 string(category),
+				Percent:            einvoice.FormatDecimal(float64(t.TaxPercent.Get())),
+				TaxExemptionReason: t.ExemptionReason.String(),
+				TaxSchemeID:        taxSchemeVAT,
+			},
+		}
+		if category.IsReverseCharge() && sub.TaxCategory.TaxExemptionReason == "" {
+			sub.TaxCategory.TaxExemptionReason = inv.ReverseChargeClauseText.StringOr("Reverse charge")
+		}
+		total.TaxSubtotals = append(total.TaxSubtotals, sub)
+		taxBasis += t.TaxableAmount
+		taxSum += t.TaxAmount
+	}
+	if inv.ReverseChargeClauseText.IsNotNull() && !slices.ContainsFunc(total.TaxSubtotals, func(t taxSubtotal) bool {
+		return t.TaxCategory.TaxExemptionReason == inv.ReverseChargeClauseText.String()
+	}) {
+		m.unmapped = append(m.unmapped, "reverse_charge_clause_text")
+	}
+	if inv.Subtotal.IsNotNull() {
+		taxBasis = inv.Subtotal.Get()
+	}
+	if inv.Tax.IsNotNull() {
+		taxSum = inv.Tax.Get()
+	}
+	if len(inv.Items) == 0 {
+		lineTotal = taxBasis
+	}
+	total.TaxAmount = m.amount(taxSum)
+	doc.TaxTotals = append(doc.TaxTotals, total)
+
+	// A discount already deducted from the line total
+	// is represented as document level allowances per tax rate
+	doc.AllowanceCharges = m.documentAllowances(lineTotal, taxBasis)
+	var allowanceTotal money.Amount
+	for _, a := range doc.AllowanceCharges {
+		amount, _ := einvoice.ParseAmount(a.Amount.Value)
+		allowanceTotal += amount
+	}
+
+	terms := inv.PaymentTerms.String()
+	hasDiscount := inv.DiscountPercent.IsNotNull() || inv.DiscountAmount.IsNotNull()
+	if hasDiscount && len(doc.AllowanceCharges) == 0 {
+		if discountTerms, ok := einvoice.DiscountTermsOf(inv); ok {
+			terms = discountTerms.String() + terms
+		} else {
+			m.unmappedIf(inv.DiscountPercent.IsNotNull(), "discount_percent")
+			m.unmappedIf(inv.DiscountAmount.IsNotNull(), "discount_amount")
+			m.unmappedIf(inv.DiscountUntilDate.IsNotNull(), "discount_until_date")
+		}
+	} else {
+		m.unmappedIf(inv.DiscountUntilDate.IsNotNull(), "discount_until_date")
+	}
+	if terms != "" {
+		doc.PaymentTerms = append(doc.PaymentTerms, paymentTerms{Note: terms})
+	}
+
+	grandTotal := taxBasis + taxSum
+	if inv.Total.IsNotNull() {
+		grandTotal = inv.Total.Get()
+	}
+	doc.LegalMonetaryTotal = monetaryTotal{
+		LineExtensionAmount: m.amount(lineTotal),
+		TaxExclusiveAmount:  m.amount(taxBasis),
+		TaxInclusiveAmount:  m.amount(grandTotal),
+		PayableAmount:       m.amount(grandTotal),
+	}
+	if allowanceTotal != 0 {
+		a := m.amount(allowanceTotal)
+		doc.LegalMonetaryTotal.AllowanceTotalAmount = &a
+	}
+	if inv.PaymentStatus.IsPaid() {
+		prepaid := m.amount(grandTotal)
+		doc.LegalMonetaryTotal.PrepaidAmount = &prepaid
+		doc.LegalMonetaryTotal.PayableAmount = m.amount(0)
+		doc.PrepaidPayment = &payment{PaidAmount: &prepaid}
+	}
+	if inv.PaidDate.IsNotNull() {
+		if doc.PrepaidPayment == nil {
+			doc.PrepaidPayment = &payment{}
+		}
+		doc.PrepaidPayment.PaidDate = inv.PaidDate.Get().String()
+	}
+}
+
+// documentAllowances returns the einvoice.DocumentAllowances
+// of the invoice as allowance charges
+func (m *marshaller) documentAllowances(lineTotal, taxBasis money.Amount) []allowanceCharge {
+	inv := m.inv
+	var allowances []allowanceCharge
+	for _, a := range einvoice.DocumentAllowances(inv, lineTotal, taxBasis) {
+		allowance := allowanceCharge{
+			AllowanceChargeReason: "Discount",
+			Amount:                m.amount(a.Amount),
+			TaxCategory: &taxCategory{
+				ID:          string(a.Category),
+				Percent:     einvoice.FormatDecimal(float64(a.TaxSubtotal.TaxPercent.Get())),
+				TaxSchemeID: taxSchemeVAT,
+			},
+		}
+		if inv.DiscountPercent.IsNotNull() {
+			allowance.MultiplierFactorNumeric = einvoice.FormatDecimal(float64(inv.DiscountPercent.Get()))
+			base := m.amount(a.BaseAmount)
+			allowance.BaseAmount = &base
+		}
+		allowances = append(allowances, allowance)
+	}
+	return allowances
+}
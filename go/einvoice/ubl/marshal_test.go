@@ -0,0 +1,301 @@
+package ubl
+
+import (
+	"bytes"
+	"reflect"
+	"regexp"
+	"slices"
+	"testing"
+
+	"github.com/domonda/go-types/date"
+	"github.com/domonda/go-types/email"
+	"github.com/domonda/go-types/money"
+	"github.com/domonda/go-types/notnull"
+	"github.com/domonda/go-types/nullable"
+	"github.com/domonda/go-types/vat"
+
+	"github.com/docvibe-ai/api/go/einvoice/einvoicetest"
+	"github.com/docvibe-ai/api/go/invoicing"
+)
+
+// testInvoice returns an invoice that survives a round trip
+// through Marshal and Unmarshal unchanged
+func testInvoice() *invoicing.Invoice {
+	inv := einvoicetest.Invoice()
+	inv.OrderDate = "2024-01-20"
+	inv.ContractID = "V-1"
+	inv.BuyerReference = "04011000-12345-67"
+	inv.DeliveryNoteIDs = []notnull.TrimmedString{"LS-1"}
+	inv.IssuerTaxNumber = "12/345/67890"
+	inv.CustomerPhone = "+43 1 123456"
+	inv.Notes = []nullable.TrimmedString{"Vielen Dank"}
+	inv.Items[0].TaxAmount = einvoicetest.Amount(19)
+	inv.Items[1].TaxAmount = einvoicetest.Amount(3.5)
+	return inv
+}
+
+func TestMarshalUnmarshal(t *testing.T) {
+	tests := []struct {
+		name    string
+		marshal func(*invoicing.Invoice) ([]byte, []string, error)
+		modify  func(inv *invoicing.Invoice)
+	}{
+		{name: "invoice", marshal: Marshal, modify: func(inv *invoicing.Invoice) {}},
+		{name: "Peppol", marshal: MarshalPeppol, modify: func(inv *invoicing.Invoice) {}},
+		{name: "credit note", marshal: Marshal, modify: func(inv *invoicing.Invoice) {
+			inv.CreditNote = true
+		}},
+		{name: "paid by direct debit", marshal: Marshal, modify: func(inv *invoicing.Invoice) {
+			inv.PaymentStatus = invoicing.PaymentStatusPaidWithDirectDebit
+			inv.DirectDebitMandateID = nullable.TrimmedString("M-1")
+			inv.PaidDate = date.NullableDate("2024-03-20")
+		}},
+		{name: "intra-community supply", marshal: Marshal, modify: func(inv *invoicing.Invoice) {
+			inv.ReverseCharge = true
+			inv.ReverseChargeClauseText = nullable.TrimmedString("Intra-community supply")
+			inv.CustomerVATID = vat.NullableID("ATU12345678")
+			inv.Tax = einvoicetest.Amount(0)
+			inv.Total = einvoicetest.Amount(150)
+			inv.TaxBreakdown = []invoicing.TaxSubtotal{{TaxPercent: einvoicetest.Rate(0), TaxableAmount: 150, ExemptionReason: inv.ReverseChargeClauseText}}
+			for _, item := range inv.Items {
+				item.TaxPercent = einvoicetest.Rate(0)
+				item.TaxAmount = einvoicetest.Amount(0)
+			}
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want := testInvoice()
+			tt.modify(want)
+			data, unmapped, err := tt.marshal(want)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			if len(unmapped) > 0 {
+				t.Errorf("Marshal() unmapped = %v, want none", unmapped)
+			}
+			got, unmapped, err := Unmarshal(data)
+			if err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if len(unmapped) > 0 {
+				t.Errorf("Unmarshal() unmapped = %v, want none", unmapped)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Unmarshal(Marshal()) =\n%+v\nwant\n%+v\nXML:\n%s", got, want, data)
+			}
+		})
+	}
+}
+
+func TestMarshal(t *testing.T) {
+	tests := []struct {
+		name         string
+		marshal      func(*invoicing.Invoice) ([]byte, []string, error)
+		modify       func(inv *invoicing.Invoice)
+		wantErr      bool
+		wantUnmapped []string
+		wantXML      []string
+	}{
+		{
+			name:    "EN 16931",
+			marshal: Marshal,
+			modify:  func(inv *invoicing.Invoice) {},
+			wantXML: []string{
+				"<cbc:CustomizationID>" + CustomizationEN16931 + "</cbc:CustomizationID>",
+				"<cbc:BuyerReference>04011000-12345-67</cbc:BuyerReference>",
+				`<cbc:EndpointID schemeID="EM">rechnung@muster.de</cbc:EndpointID>`,
+				`<cbc:EndpointID schemeID="EM">eingang@kunde.at</cbc:EndpointID>`,
+			},
+		},
+		{
+			name:    "Peppol with order ID instead of buyer reference",
+			marshal: MarshalPeppol,
+			modify:  func(inv *invoicing.Invoice) { inv.BuyerReference = "" },
+			wantXML: []string{
+				"<cbc:CustomizationID>" + CustomizationPeppol + "</cbc:CustomizationID>",
+				"<cbc:ProfileID>" + ProfilePeppolBilling + "</cbc:ProfileID>",
+			},
+		},
+		{
+			name:    "Peppol without issuer email",
+			marshal: MarshalPeppol,
+			modify:  func(inv *invoicing.Invoice) { inv.IssuerEmail = "" },
+			wantErr: true,
+		},
+		{
+			name:    "Peppol without buyer reference and order ID",
+			marshal: MarshalPeppol,
+			modify: func(inv *invoicing.Invoice) {
+				inv.BuyerReference = ""
+				inv.OrderID = ""
+				inv.OrderDate = ""
+			},
+			wantErr: true,
+		},
+		{
+			name:    "Peppol without customer email",
+			marshal: MarshalPeppol,
+			modify:  func(inv *invoicing.Invoice) { inv.CustomerEmail = "" },
+			wantErr: true,
+		},
+		{
+			name:    "item tax percent from single tax rate",
+			marshal: Marshal,
+			modify: func(inv *invoicing.Invoice) {
+				inv.TaxBreakdown = inv.TaxBreakdown[:1]
+				inv.Items = inv.Items[:1]
+				inv.Items[0].TaxPercent = money.NullableRate{}
+			},
+			wantXML: []string{"<cac:ClassifiedTaxCategory><cbc:ID>S</cbc:ID><cbc:Percent>19</cbc:Percent>"},
+		},
+		{
+			name:    "zero rated item writes percent",
+			marshal: Marshal,
+			modify: func(inv *invoicing.Invoice) {
+				inv.Items[1].TaxPercent = einvoicetest.Rate(0)
+				inv.TaxBreakdown[1] = invoicing.TaxSubtotal{TaxPercent: einvoicetest.Rate(0), TaxableAmount: 50}
+			},
+			wantXML: []string{"<cac:ClassifiedTaxCategory><cbc:ID>Z</cbc:ID><cbc:Percent>0</cbc:Percent>"},
+		},
+		{
+			name:    "item without tax percent in multi-rate invoice",
+			marshal: Marshal,
+			modify:  func(inv *invoicing.Invoice) { inv.Items[1].TaxPercent = money.NullableRate{} },
+			wantErr: true,
+		},
+		{
+			name:    "item without quantity and unit price",
+			marshal: Marshal,
+			modify: func(inv *invoicing.Invoice) {
+				inv.Items[0].Quantity = nullable.Type[float64]{}
+				inv.Items[0].UnitPrice = money.NullableAmount{}
+			},
+			wantXML: []string{
+				`>1</cbc:InvoicedQuantity><cbc:LineExtensionAmount currencyID="EUR">100.00</cbc:LineExtensionAmount>`,
+				`<cac:Price><cbc:PriceAmount currencyID="EUR">100</cbc:PriceAmount></cac:Price>`,
+			},
+		},
+		{
+			name:         "only first delivery note as despatch document reference",
+			marshal:      Marshal,
+			modify:       func(inv *invoicing.Invoice) { inv.DeliveryNoteIDs = append(inv.DeliveryNoteIDs, "LS-2") },
+			wantUnmapped: []string{"delivery_note_ids[1]"},
+			wantXML:      []string{"<cac:DespatchDocumentReference><cbc:ID>LS-1</cbc:ID></cac:DespatchDocumentReference><cac:"},
+		},
+		{
+			name:    "unmapped fields",
+			marshal: Marshal,
+			modify: func(inv *invoicing.Invoice) {
+				inv.Type = invoicing.InvoiceTypeOutgoing
+				inv.ReverseChargeReason = nullable.TrimmedString("reason")
+			},
+			wantUnmapped: []string{"type", "reverse_charge_reason"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv := testInvoice()
+			tt.modify(inv)
+			data, unmapped, err := tt.marshal(inv)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Marshal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !slices.Equal(unmapped, tt.wantUnmapped) {
+				t.Errorf("Marshal() unmapped = %v, want %v", unmapped, tt.wantUnmapped)
+			}
+			compact := regexp.MustCompile(`>\s+<`).ReplaceAll(data, []byte("><"))
+			for _, s := range tt.wantXML {
+				if !bytes.Contains(compact, []byte(s)) {
+					t.Errorf("Marshal() XML does not contain %s:\n%s", s, data)
+				}
+			}
+		})
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	const (
+		header = `<Invoice xmlns="` + NamespaceInvoice + `" xmlns:cac="` + NamespaceCAC + `" xmlns:cbc="` + NamespaceCBC + `"><cbc:ID>1</cbc:ID>`
+		footer = `</Invoice>`
+	)
+	tests := []struct {
+		name              string
+		data              string
+		wantErr           bool
+		wantIssuerEmail   email.NullableAddress
+		wantCustomerEmail email.NullableAddress
+		wantUnmapped      []string
+	}{
+		{
+			name: "endpoint IDs",
+			data: header +
+				`<cac:AccountingSupplierParty><cac:Party><cbc:EndpointID schemeID="EM"> seller@example.com </cbc:EndpointID></cac:Party></cac:AccountingSupplierParty>` +
+				`<cac:AccountingCustomerParty><cac:Party><cbc:EndpointID schemeID="EM">buyer@example.com</cbc:EndpointID></cac:Party></cac:AccountingCustomerParty>` +
+				footer,
+			wantIssuerEmail:   "seller@example.com",
+			wantCustomerEmail: "buyer@example.com",
+		},
+		{
+			name: "endpoint IDs of other schemes",
+			data: header +
+				`<cac:AccountingSupplierParty><cac:Party><cbc:EndpointID schemeID="0088">4000001000005</cbc:EndpointID></cac:Party></cac:AccountingSupplierParty>` +
+				`<cac:AccountingCustomerParty><cac:Party><cbc:EndpointID schemeID="0204">04011000-12345-67</cbc:EndpointID></cac:Party></cac:AccountingCustomerParty>` +
+				footer,
+			wantUnmapped: []string{"AccountingSupplierParty/Party/EndpointID", "AccountingCustomerParty/Party/EndpointID"},
+		},
+		{
+			name: "contact email differing from endpoint ID",
+			data: header +
+				`<cac:AccountingCustomerParty><cac:Party><cbc:EndpointID schemeID="EM">buyer@example.com</cbc:EndpointID><cac:Contact><cbc:ElectronicMail>accounting@example.com</cbc:ElectronicMail></cac:Contact></cac:Party></cac:AccountingCustomerParty>` +
+				footer,
+			wantCustomerEmail: "accounting@example.com",
+			wantUnmapped:      []string{"AccountingCustomerParty/Party/EndpointID"},
+		},
+		{
+			name:    "not XML",
+			data:    "no xml",
+			wantErr: true,
+		},
+		{
+			name:    "no namespace",
+			data:    `<Invoice><ID>1</ID></Invoice>`,
+			wantErr: true,
+		},
+		{
+			name:    "credit note namespace of invoice root element",
+			data:    `<Invoice xmlns="` + NamespaceCreditNote + `"></Invoice>`,
+			wantErr: true,
+		},
+		{
+			name:    "other root element",
+			data:    `<Order xmlns="urn:oasis:names:specification:ubl:schema:xsd:Order-2"></Order>`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid amount",
+			data:    header + `<cac:LegalMonetaryTotal><cbc:TaxInclusiveAmount currencyID="EUR">one</cbc:TaxInclusiveAmount></cac:LegalMonetaryTotal>` + footer,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			inv, unmapped, err := Unmarshal([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Unmarshal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if inv.IssuerEmail != tt.wantIssuerEmail || inv.CustomerEmail != tt.wantCustomerEmail {
+				t.Errorf("Unmarshal() emails = %q, %q, want %q, %q", inv.IssuerEmail, inv.CustomerEmail, tt.wantIssuerEmail, tt.wantCustomerEmail)
+			}
+			if !slices.Equal(unmapped, tt.wantUnmapped) {
+				t.Errorf("Unmarshal() unmapped = %v, want %v", unmapped, tt.wantUnmapped)
+			}
+		})
+	}
+}
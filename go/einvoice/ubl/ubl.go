@@ -0,0 +1,342 @@
+package ubl
+
+import (
+	"encoding/xml"
+	"strings"
+
+	"github.com/docvibe-ai/api/go/einvoice"
+)
+
+const (
+	// CustomizationEN16931 is the specification identifier
+	// of the EN 16931 core invoice
+	CustomizationEN16931 = "urn:cen.eu:en16931:2017"
+	// CustomizationPeppol is the specification identifier
+	// of Peppol BIS Billing 3.0
+	CustomizationPeppol = "urn:cen.eu:en16931:2017#compliant#urn:fdc:peppol.eu:2017:poacc:billing:3.0"
+	// ProfilePeppolBilling is the business process type
+	// of Peppol BIS Billing 3.0, also used for XRechnung
+	ProfilePeppolBilling = "urn:fdc:peppol.eu:2017:poacc:billing:01:1.0"
+
+	NamespaceInvoice    = "urn:oasis:names:specification:ubl:schema:xsd:Invoice-2"
+	NamespaceCreditNote = "urn:oasis:names:specification:ubl:schema:xsd:CreditNote-2"
+	NamespaceCAC        = "urn:oasis:names:specification:ubl:schema:xsd:CommonAggregateComponents-2"
+	NamespaceCBC        = "urn:oasis:names:specification:ubl:schema:xsd:CommonBasicComponents-2"
+)
+
+// Document type codes (UNTDID 1001)
+const (
+	TypeCodeInvoice    = "380"
+	TypeCodeCreditNote = "381"
+)
+
+// Root element names of the document types
+const (
+	rootInvoice    = "Invoice"
+	rootCreditNote = "CreditNote"
+)
+
+// The XML types use element names without namespace
+// so that unmarshalling accepts any namespace prefix.
+// Marshal adds the UBL namespace prefixes afterwards.
+// The same type is used for Invoice and CreditNote documents,
+// fields that exist only in one of them are omitted if empty.
+
+type document struct {
+	XMLName                    xml.Name
+	CustomizationID            string            `xml:"CustomizationID"`
+	ProfileID                  string            `xml:"ProfileID,omitempty"`
+	ID                         string            `xml:"ID"`
+	IssueDate                  string            `xml:"IssueDate,omitempty"`
+	DueDate                    string            `xml:"DueDate,omitempty"`
+	InvoiceTypeCode            string            `xml:"InvoiceTypeCode,omitempty"`
+	CreditNoteTypeCode         string            `xml:"CreditNoteTypeCode,omitempty"`
+	Notes                      []string          `xml:"Note"`
+	DocumentCurrencyCode       string            `xml:"DocumentCurrencyCode,omitempty"`
+	BuyerReference             string            `xml:"BuyerReference,omitempty"`
+	InvoicePeriod              *period           `xml:"InvoicePeriod"`
+	OrderReference             *orderReference   `xml:"OrderReference"`
+	DespatchDocumentReferences []identifier      `xml:"DespatchDocumentReference"`
+	ContractDocumentReference  *identifier       `xml:"ContractDocumentReference"`
+	AccountingSupplierParty    partyWrapper      `xml:"AccountingSupplierParty"`
+	AccountingCustomerParty    partyWrapper      `xml:"AccountingCustomerParty"`
+	Delivery                   *delivery         `xml:"Delivery"`
+	PaymentMeans               []paymentMeans    `xml:"PaymentMeans"`
+	PaymentTerms               []paymentTerms    `xml:"PaymentTerms"`
+	PrepaidPayment             *payment          `xml:"PrepaidPayment"`
+	AllowanceCharges           []allowanceCharge `xml:"AllowanceCharge"`
+	TaxTotals                  []taxTotal        `xml:"TaxTotal"`
+	LegalMonetaryTotal         monetaryTotal     `xml:"LegalMonetaryTotal"`
+	InvoiceLines               []line            `xml:"InvoiceLine"`
+	CreditNoteLines            []line            `xml:"CreditNoteLine"`
+}
+
+type period struct {
+	StartDate string `xml:"StartDate,omitempty"`
+	EndDate   string `xml:"EndDate,omitempty"`
+}
+
+type orderReference struct {
+	ID        string `xml:"ID"`
+	IssueDate string `xml:"IssueDate,omitempty"`
+}
+
+// identifier is an aggregate with just an ID
+// like a document reference or party identification
+type identifier struct {
+	ID string `xml:"ID"`
+}
+
+type partyWrapper struct {
+	Party party `xml:"Party"`
+}
+
+type party struct {
+	EndpointID      *endpointID       `xml:"EndpointID"`
+	Identifications []identifier      `xml:"PartyIdentification"`
+	Names           []partyName       `xml:"PartyName"`
+	PostalAddress   *address          `xml:"PostalAddress"`
+	TaxSchemes      []partyTaxScheme  `xml:"PartyTaxScheme"`
+	LegalEntity     *partyLegalEntity `xml:"PartyLegalEntity"`
+	Contact         *contact          `xml:"Contact"`
+}
+
+// endpointID is the electronic address of a party
+// with the electronic address scheme (EAS) code
+type endpointID struct {
+	SchemeID string `xml:"schemeID,attr"`
+	Value    string `xml:",chardata"`
+}
+
+// newEmailEndpointID returns the email as endpoint ID
+// or nil if the email is empty
+func newEmailEndpointID(email string) *endpointID {
+	if email == "" {
+		return nil
+	}
+	return &endpointID{SchemeID: einvoice.ElectronicAddressSchemeEmail, Value: email}
+}
+
+type address struct {
+	StreetName       string          `xml:"StreetName,omitempty"`
+	CityName         string          `xml:"CityName,omitempty"`
+	PostalZone       string          `xml:"PostalZone,omitempty"`
+	CountrySubentity string          `xml:"CountrySubentity,omitempty"`
+	Country          *addressCountry `xml:"Country"`
+}
+
+type addressCountry struct {
+	IdentificationCode string `xml:"IdentificationCode"`
+}
+
+// newIdentifier returns an identifier with the ID
+// or nil if the ID is empty
+func newIdentifier(id string) *identifier {
+	if id == "" {
+		return nil
+	}
+	return &identifier{ID: id}
+}
+
+// id returns the trimmed ID or an empty string for nil
+func (i *identifier) id() string {
+	if i == nil {
+		return ""
+	}
+	return strings.TrimSpace(i.ID)
+}
+
+type partyName struct {
+	Name string `xml:"Name"`
+}
+
+type partyTaxScheme struct {
+	CompanyID   string `xml:"CompanyID"`
+	TaxSchemeID string `xml:"TaxScheme>ID"`
+}
+
+// Tax schemes of party tax registrations
+const (
+	taxSchemeVAT = "VAT"
+	// Any other scheme than VAT is a tax registration
+	// like the German Steuernummer
+	taxSchemeTaxNumber = "FC"
+)
+
+type partyLegalEntity struct {
+	RegistrationName string `xml:"RegistrationName"`
+}
+
+type contact struct {
+	Telephone      string `xml:"Telephone,omitempty"`
+	ElectronicMail string `xml:"ElectronicMail,omitempty"`
+}
+
+type delivery struct {
+	Address *address `xml:"DeliveryLocation>Address"`
+}
+
+type paymentMeans struct {
+	Code           string            `xml:"PaymentMeansCode"`
+	PaymentDueDate string            `xml:"PaymentDueDate,omitempty"`
+	PaymentID      string            `xml:"PaymentID,omitempty"`
+	PayeeAccount   *financialAccount `xml:"PayeeFinancialAccount"`
+	PaymentMandate *identifier       `xml:"PaymentMandate"`
+}
+
+type financialAccount struct {
+	ID     string      `xml:"ID"`
+	Branch *identifier `xml:"FinancialInstitutionBranch"`
+}
+
+type paymentTerms struct {
+	Note string `xml:"Note"`
+}
+
+type payment struct {
+	PaidAmount *amount `xml:"PaidAmount"`
+	PaidDate   string  `xml:"PaidDate,omitempty"`
+}
+
+type allowanceCharge struct {
+	ChargeIndicator         bool         `xml:"ChargeIndicator"`
+	AllowanceChargeReason   string       `xml:"AllowanceChargeReason,omitempty"`
+	MultiplierFactorNumeric string       `xml:"MultiplierFactorNumeric,omitempty"`
+	Amount                  amount       `xml:"Amount"`
+	BaseAmount              *amount      `xml:"BaseAmount"`
+	TaxCategory             *taxCategory `xml:"TaxCategory"`
+}
+
+type taxTotal struct {
+	TaxAmount    amount        `xml:"TaxAmount"`
+	TaxSubtotals []taxSubtotal `xml:"TaxSubtotal"`
+}
+
+type taxSubtotal struct {
+	TaxableAmount amount      `xml:"TaxableAmount"`
+	TaxAmount     amount      `xml:"TaxAmount"`
+	TaxCategory   taxCategory `xml:"TaxCategory"`
+}
+
+type taxCategory struct {
+	ID                 string `xml:"ID"`
+	Percent            string `xml:"Percent,omitempty"`
+	TaxExemptionReason string `xml:"TaxExemptionReason,omitempty"`
+	TaxSchemeID        string `xml:"TaxScheme>ID"`
+}
+
+type monetaryTotal struct {
+	LineExtensionAmount  amount  `xml:"LineExtensionAmount"`
+	TaxExclusiveAmount   amount  `xml:"TaxExclusiveAmount"`
+	TaxInclusiveAmount   amount  `xml:"TaxInclusiveAmount"`
+	AllowanceTotalAmount *amount `xml:"AllowanceTotalAmount"`
+	ChargeTotalAmount    *amount `xml:"ChargeTotalAmount"`
+	PrepaidAmount        *amount `xml:"PrepaidAmount"`
+	PayableAmount        amount  `xml:"PayableAmount"`
+}
+
+type amount struct {
+	CurrencyID string `xml:"currencyID,attr,omitempty"`
+	Value      string `xml:",chardata"`
+}
+
+type line struct {
+	ID                    string            `xml:"ID"`
+	Notes                 []string          `xml:"Note"`
+	InvoicedQuantity      *quantity         `xml:"InvoicedQuantity"`
+	CreditedQuantity      *quantity         `xml:"CreditedQuantity"`
+	LineExtensionAmount   amount            `xml:"LineExtensionAmount"`
+	InvoicePeriod         *period           `xml:"InvoicePeriod"`
+	OrderLineReference    *orderLineRef     `xml:"OrderLineReference"`
+	DespatchLineReference *despatchLineRef  `xml:"DespatchLineReference"`
+	AllowanceCharges      []allowanceCharge `xml:"AllowanceCharge"`
+	TaxTotal              *taxTotal         `xml:"TaxTotal"`
+	Item                  item              `xml:"Item"`
+	Price                 *price            `xml:"Price"`
+}
+
+type quantity struct {
+	UnitCode string `xml:"unitCode,attr,omitempty"`
+	Value    string `xml:",chardata"`
+}
+
+// unknownLineID is the line ID of line references
+// to documents without known line
+const unknownLineID = "NA"
+
+type orderLineRef struct {
+	LineID  string `xml:"LineID"`
+	OrderID string `xml:"OrderReference>ID,omitempty"`
+}
+
+type despatchLineRef struct {
+	LineID     string `xml:"LineID"`
+	DocumentID string `xml:"DocumentReference>ID,omitempty"`
+}
+
+type item struct {
+	Description           string      `xml:"Description,omitempty"`
+	Name                  string      `xml:"Name"`
+	SellersItemID         *identifier `xml:"SellersItemIdentification"`
+	ClassifiedTaxCategory taxCategory `xml:"ClassifiedTaxCategory"`
+}
+
+type price struct {
+	PriceAmount amount `xml:"PriceAmount"`
+}
+
+// aggregates are the elements in the CommonAggregateComponents namespace,
+// all other elements below the root are CommonBasicComponents
+var aggregates = map[string]bool{
+	"InvoicePeriod":              true,
+	"OrderReference":             true,
+	"DespatchDocumentReference":  true,
+	"ContractDocumentReference":  true,
+	"DocumentReference":          true,
+	"AccountingSupplierParty":    true,
+	"AccountingCustomerParty":    true,
+	"Party":                      true,
+	"PartyIdentification":        true,
+	"PartyName":                  true,
+	"PostalAddress":              true,
+	"Address":                    true,
+	"Country":                    true,
+	"PartyTaxScheme":             true,
+	"TaxScheme":                  true,
+	"PartyLegalEntity":           true,
+	"Contact":                    true,
+	"Delivery":                   true,
+	"DeliveryLocation":           true,
+	"PaymentMeans":               true,
+	"PayeeFinancialAccount":      true,
+	"FinancialInstitutionBranch": true,
+	"PaymentMandate":             true,
+	"PaymentTerms":               true,
+	"PrepaidPayment":             true,
+	"AllowanceCharge":            true,
+	"TaxTotal":                   true,
+	"TaxSubtotal":                true,
+	"TaxCategory":                true,
+	"ClassifiedTaxCategory":      true,
+	"LegalMonetaryTotal":         true,
+	"InvoiceLine":                true,
+	"CreditNoteLine":             true,
+	"OrderLineReference":         true,
+	"DespatchLineReference":      true,
+	"Item":                       true,
+	"SellersItemIdentification":  true,
+	"Price":                      true,
+}
+
+// namespacePrefix returns the namespace prefix
+// for an element with the passed parent elements,
+// the root element uses the default namespace
+func namespacePrefix(parents []string, local string) string {
+	switch {
+	case len(parents) == 0:
+		return ""
+	case aggregates[local]:
+		return "cac"
+	}
+	return "cbc"
+}
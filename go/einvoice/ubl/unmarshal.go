@@ -0,0 +1,514 @@
+package ubl
+
+import (
+	"encoding/xml"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/domonda/go-types/bank"
+	"github.com/domonda/go-types/country"
+	"github.com/domonda/go-types/email"
+	"github.com/domonda/go-types/money"
+	"github.com/domonda/go-types/notnull"
+	"github.com/domonda/go-types/nullable"
+	"github.com/domonda/go-types/vat"
+
+	"github.com/docvibe-ai/api/go/einvoice"
+	"github.com/docvibe-ai/api/go/invoicing"
+)
+
+// Unmarshal parses a UBL 2.1 Invoice or CreditNote document into an invoice.
+//
+// Elements that are not part of the mapping are ignored.
+// Elements with values that can't be represented by the invoice
+// are returned as unmapped with their element path.
+// The Type of the returned invoice is not set because the direction
+// depends on the point of view, and the invoice is not normalized.
+func Unmarshal(data []byte) (inv *invoicing.Invoice, unmapped []string, err error) {
+	var doc document
+	if err = xml.Unmarshal(data, &doc); err != nil {
+		return nil, nil, fmt.Errorf("can't parse UBL XML: %w", err)
+	}
+	if doc.XMLName.Local != rootInvoice && doc.XMLName.Local != rootCreditNote {
+		return nil, nil, fmt.Errorf("root element %s is not a UBL Invoice or CreditNote", doc.XMLName.Local)
+	}
+	if namespace := rootNamespaces[doc.XMLName.Local]; doc.XMLName.Space != namespace {
+		return nil, nil, fmt.Errorf("root element namespace %q is not the UBL %s namespace %q", doc.XMLName.Space, doc.XMLName.Local, namespace)
+	}
+	u := &unmarshaller{inv: &invoicing.Invoice{PaymentStatus: invoicing.PaymentStatusUnpaid}}
+	if err = u.document(&doc); err != nil {
+		return nil, nil, err
+	}
+	return u.inv, u.unmapped, nil
+}
+
+// rootNamespaces are the namespaces of the root elements
+var rootNamespaces = map[string]string{
+	rootInvoice:    NamespaceInvoice,
+	rootCreditNote: NamespaceCreditNote,
+}
+
+type unmarshaller struct {
+	inv      *invoicing.Invoice
+	unmapped []string
+}
+
+const (
+	pathSupplierParty = "AccountingSupplierParty/Party"
+	pathCustomerParty = "AccountingCustomerParty/Party"
+	pathTaxSubtotal   = "TaxTotal/TaxSubtotal"
+	pathMonetaryTotal = "LegalMonetaryTotal"
+)
+
+func (u *unmarshaller) document(doc *document) error {
+	inv := u.inv
+	inv.InvoiceID = nullable.TrimmedString(strings.TrimSpace(doc.ID))
+	inv.CreditNote = doc.XMLName.Local == rootCreditNote
+	switch {
+	case doc.InvoiceTypeCode == TypeCodeCreditNote || doc.CreditNoteTypeCode == TypeCodeCreditNote:
+		inv.CreditNote = true
+	case doc.InvoiceTypeCode != "" && doc.InvoiceTypeCode != TypeCodeInvoice:
+		u.unmapped = append(u.unmapped, "InvoiceTypeCode")
+	case doc.CreditNoteTypeCode != "" && doc.CreditNoteTypeCode != TypeCodeCreditNote:
+		u.unmapped = append(u.unmapped, "CreditNoteTypeCode")
+	}
+	if d, ok := einvoice.ParseDate(doc.IssueDate); ok {
+		inv.IssueDate.Set(d)
+	}
+	if d, ok := einvoice.ParseDate(doc.DueDate); ok {
+		inv.DueDate.Set(d)
+	}
+	for _, n := range doc.Notes {
+		if n = strings.TrimSpace(n); n != "" {
+			inv.Notes = append(inv.Notes, nullable.TrimmedString(n))
+		}
+	}
+	inv.Currency = money.NullableCurrency(strings.TrimSpace(doc.DocumentCurrencyCode))
+	inv.BuyerReference = nullable.TrimmedString(strings.TrimSpace(doc.BuyerReference))
+	if doc.InvoicePeriod != nil {
+		if d, ok := einvoice.ParseDate(doc.InvoicePeriod.StartDate); ok {
+			inv.PeriodStart.Set(d)
+		}
+		if d, ok := einvoice.ParseDate(doc.InvoicePeriod.EndDate); ok {
+			inv.PeriodEnd.Set(d)
+		}
+	}
+	if doc.OrderReference != nil {
+		inv.OrderID = nullable.TrimmedString(strings.TrimSpace(doc.OrderReference.ID))
+		if d, ok := einvoice.ParseDate(doc.OrderReference.IssueDate); ok {
+			inv.OrderDate.Set(d)
+		}
+	}
+	for _, ref := range doc.DespatchDocumentReferences {
+		if id := strings.TrimSpace(ref.ID); id != "" {
+			inv.DeliveryNoteIDs = append(inv.DeliveryNoteIDs, notnull.TrimmedString(id))
+		}
+	}
+	if doc.ContractDocumentReference != nil {
+		inv.ContractID = nullable.TrimmedString(strings.TrimSpace(doc.ContractDocumentReference.ID))
+	}
+
+	u.supplierParty(&doc.AccountingSupplierParty.Party)
+	u.customerParty(&doc.AccountingCustomerParty.Party)
+	if doc.Delivery != nil {
+		inv.CustomerShippingAddress = doc.Delivery.Address.address()
+	}
+	u.paymentMeans(doc.PaymentMeans)
+	if err := u.taxTotals(doc.TaxTotals); err != nil {
+		return err
+	}
+	if err := u.allowances(doc.AllowanceCharges); err != nil {
+		return err
+	}
+	if err := u.monetaryTotal(doc); err != nil {
+		return err
+	}
+	// Applied after parsing the total
+	// because the base amount is compared with it
+	u.paymentTerms(doc.PaymentTerms)
+
+	lines, pathLine := doc.InvoiceLines, "InvoiceLine"
+	if len(doc.CreditNoteLines) > 0 {
+		lines, pathLine = doc.CreditNoteLines, "CreditNoteLine"
+	}
+	for i := range lines {
+		if err := u.line(fmt.Sprintf("%s[%d]", pathLine, i), i, &lines[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// name returns the registration name of the party
+// or its first party name
+func (p *party) name() string {
+	if p.LegalEntity != nil && strings.TrimSpace(p.LegalEntity.RegistrationName) != "" {
+		return strings.TrimSpace(p.LegalEntity.RegistrationName)
+	}
+	if len(p.Names) > 0 {
+		return strings.TrimSpace(p.Names[0].Name)
+	}
+	return ""
+}
+
+func (u *unmarshaller) supplierParty(p *party) {
+	inv := u.inv
+	inv.Issuer = nullable.TrimmedString(p.name())
+	inv.IssuerAddress = p.PostalAddress.address()
+	if mail, ok := p.EndpointID.email(); ok {
+		inv.IssuerEmail = email.NullableAddress(mail)
+	} else if p.EndpointID != nil {
+		u.unmapped = append(u.unmapped, pathSupplierParty+"/EndpointID")
+	}
+	for i, scheme := range p.TaxSchemes {
+		id := strings.TrimSpace(scheme.CompanyID)
+		switch {
+		case scheme.TaxSchemeID == taxSchemeVAT && inv.IssuerVATID.IsNull():
+			inv.IssuerVATID = vat.NullableID(id)
+		case scheme.TaxSchemeID != taxSchemeVAT && inv.IssuerTaxNumber.IsNull():
+			inv.IssuerTaxNumber = nullable.TrimmedString(id)
+		default:
+			u.unmapped = append(u.unmapped, fmt.Sprintf("%s/PartyTaxScheme[%d]", pathSupplierParty, i))
+		}
+	}
+}
+
+func (u *unmarshaller) customerParty(p *party) {
+	inv := u.inv
+	inv.Customer = nullable.TrimmedString(p.name())
+	inv.CustomerBillingAddress = p.PostalAddress.address()
+	for i, id := range p.Identifications {
+		if i > 0 {
+			u.unmapped = append(u.unmapped, fmt.Sprintf("%s/PartyIdentification[%d]", pathCustomerParty, i))
+			continue
+		}
+		inv.CustomerID = nullable.TrimmedString(id.id())
+	}
+	for i, scheme := range p.TaxSchemes {
+		if scheme.TaxSchemeID != taxSchemeVAT || inv.CustomerVATID.IsNotNull() {
+			u.unmapped = append(u.unmapped, fmt.Sprintf("%s/PartyTaxScheme[%d]", pathCustomerParty, i))
+			continue
+		}
+		inv.CustomerVATID = vat.NullableID(strings.TrimSpace(scheme.CompanyID))
+	}
+	if p.Contact != nil {
+		inv.CustomerPhone = nullable.TrimmedString(strings.TrimSpace(p.Contact.Telephone))
+		inv.CustomerEmail = email.NullableAddress(strings.TrimSpace(p.Contact.ElectronicMail))
+	}
+	// The electronic address is used as email
+	// if the contact has no other email
+	mail, ok := p.EndpointID.email()
+	switch {
+	case ok && (inv.CustomerEmail.IsNull() || strings.EqualFold(inv.CustomerEmail.String(), mail)):
+		inv.CustomerEmail = email.NullableAddress(mail)
+	case p.EndpointID != nil:
+		u.unmapped = append(u.unmapped, pathCustomerParty+"/EndpointID")
+	}
+}
+
+// email returns the endpoint ID if it is an email address
+func (e *endpointID) email() (string, bool) {
+	if e == nil || strings.TrimSpace(e.SchemeID) != einvoice.ElectronicAddressSchemeEmail {
+		return "", false
+	}
+	mail := strings.TrimSpace(e.Value)
+	return mail, mail != ""
+}
+
+func (a *address) address() *invoicing.Address {
+	if a == nil {
+		return nil
+	}
+	result := &invoicing.Address{
+		Street:     nullable.TrimmedString(strings.TrimSpace(a.StreetName)),
+		City:       nullable.TrimmedString(strings.TrimSpace(a.CityName)),
+		State:      nullable.TrimmedString(strings.TrimSpace(a.CountrySubentity)),
+		PostalCode: nullable.TrimmedString(strings.TrimSpace(a.PostalZone)),
+	}
+	if a.Country != nil {
+		result.Country = country.NullableCode(strings.TrimSpace(a.Country.IdentificationCode))
+	}
+	return result
+}
+
+func (u *unmarshaller) paymentMeans(means []paymentMeans) {
+	inv := u.inv
+	for i, m := range means {
+		if i > 0 {
+			// Only one payment account can be represented
+			u.unmapped = append(u.unmapped, fmt.Sprintf("PaymentMeans[%d]", i))
+			continue
+		}
+		if d, ok := einvoice.ParseDate(m.PaymentDueDate); ok {
+			inv.DueDate.Set(d)
+		}
+		inv.PaymentReference = nullable.TrimmedString(strings.TrimSpace(m.PaymentID))
+		inv.DirectDebitMandateID = nullable.TrimmedString(m.PaymentMandate.id())
+		if m.PayeeAccount != nil {
+			inv.PaymentIBAN = bank.NullableIBAN(strings.TrimSpace(m.PayeeAccount.ID))
+			inv.PaymentBIC = bank.NullableBIC(m.PayeeAccount.Branch.id())
+		}
+	}
+}
+
+func (u *unmarshaller) paymentTerms(terms []paymentTerms) {
+	inv := u.inv
+	var (
+		notes    []string
+		discount *einvoice.DiscountTerms
+	)
+	for i, t := range terms {
+		discountTerms, rest := einvoice.ParseDiscountTerms(t.Note)
+		if rest != "" {
+			notes = append(notes, rest)
+		}
+		for j, d := range discountTerms {
+			if i > 0 || j > 0 {
+				// Only one early payment discount can be represented
+				u.unmapped = append(u.unmapped, fmt.Sprintf("PaymentTerms[%d]/Note", i))
+				continue
+			}
+			discount = &d
+		}
+	}
+	inv.PaymentTerms = nullable.TrimmedString(strings.Join(notes, "\n"))
+	if discount != nil {
+		discount.Apply(inv)
+	}
+}
+
+func (u *unmarshaller) taxTotals(totals []taxTotal) (err error) {
+	inv := u.inv
+	for i, total := range totals {
+		if i > 0 {
+			// A second tax total in the tax currency
+			// has no tax subtotals and can't be represented
+			u.unmapped = append(u.unmapped, fmt.Sprintf("TaxTotal[%d]", i))
+			continue
+		}
+		if total.TaxAmount.Value != "" {
+			tax, err := einvoice.ParseAmountAt(total.TaxAmount.Value, "TaxTotal/TaxAmount")
+			if err != nil {
+				return err
+			}
+			inv.Tax.Set(tax)
+		}
+		for _, sub := range total.TaxSubtotals {
+			t := invoicing.TaxSubtotal{
+				ExemptionReason: nullable.TrimmedString(strings.TrimSpace(sub.TaxCategory.TaxExemptionReason)),
+			}
+			if t.TaxableAmount, err = einvoice.ParseAmountAt(sub.TaxableAmount.Value, pathTaxSubtotal+"/TaxableAmount"); err != nil {
+				return err
+			}
+			if t.TaxAmount, err = einvoice.ParseAmountAt(sub.TaxAmount.Value, pathTaxSubtotal+"/TaxAmount"); err != nil {
+				return err
+			}
+			percent, err := einvoice.ParseDecimalAt(sub.TaxCategory.Percent, pathTaxSubtotal+"/TaxCategory/Percent")
+			if err != nil {
+				return err
+			}
+			t.TaxPercent.Set(money.Rate(percent))
+			if einvoice.TaxCategory(strings.TrimSpace(sub.TaxCategory.ID)).IsReverseCharge() {
+				inv.ReverseCharge = true
+				inv.ReverseChargeClauseText = t.ExemptionReason
+			}
+			inv.TaxBreakdown = append(inv.TaxBreakdown, t)
+		}
+	}
+	return nil
+}
+
+func (u *unmarshaller) allowances(allowances []allowanceCharge) error {
+	inv := u.inv
+	var allowanceTotal money.Amount
+	for i, allowance := range allowances {
+		if allowance.ChargeIndicator {
+			u.unmapped = append(u.unmapped, fmt.Sprintf("AllowanceCharge[%d]", i))
+			continue
+		}
+		amount, err := einvoice.ParseAmountAt(allowance.Amount.Value, "AllowanceCharge/Amount")
+		if err != nil {
+			return err
+		}
+		allowanceTotal += amount
+		if allowance.MultiplierFactorNumeric != "" {
+			percent, err := einvoice.ParseDecimalAt(allowance.MultiplierFactorNumeric, "AllowanceCharge/MultiplierFactorNumeric")
+			if err != nil {
+				return err
+			}
+			inv.DiscountPercent.Set(money.Rate(percent))
+		}
+	}
+	if allowanceTotal != 0 {
+		inv.DiscountAmount.Set(allowanceTotal.RoundToCents())
+	}
+	return nil
+}
+
+func (u *unmarshaller) monetaryTotal(doc *document) error {
+	var (
+		inv   = u.inv
+		total = &doc.LegalMonetaryTotal
+	)
+	if total.TaxExclusiveAmount.Value != "" {
+		amount, err := einvoice.ParseAmountAt(total.TaxExclusiveAmount.Value, pathMonetaryTotal+"/TaxExclusiveAmount")
+		if err != nil {
+			return err
+		}
+		inv.Subtotal.Set(amount)
+	}
+	if total.TaxInclusiveAmount.Value != "" {
+		amount, err := einvoice.ParseAmountAt(total.TaxInclusiveAmount.Value, pathMonetaryTotal+"/TaxInclusiveAmount")
+		if err != nil {
+			return err
+		}
+		inv.Total.Set(amount)
+	}
+	if total.ChargeTotalAmount != nil {
+		u.unmapped = append(u.unmapped, pathMonetaryTotal+"/ChargeTotalAmount")
+	}
+	if total.PrepaidAmount != nil {
+		prepaid, err := einvoice.ParseAmountAt(total.PrepaidAmount.Value, pathMonetaryTotal+"/PrepaidAmount")
+		if err != nil {
+			return err
+		}
+		due, err := einvoice.ParseAmountAt(total.PayableAmount.Value, pathMonetaryTotal+"/PayableAmount")
+		if err != nil {
+			return err
+		}
+		switch {
+		case prepaid != 0 && due == 0:
+			inv.PaymentStatus = invoicing.PaymentStatusPaidWithElectronicPaymentMethod
+			if len(doc.PaymentMeans) > 0 {
+				inv.PaymentStatus = einvoice.PaidStatusOfMeansCode(strings.TrimSpace(doc.PaymentMeans[0].Code))
+			}
+		case prepaid != 0:
+			// Partial prepayments can't be represented
+			u.unmapped = append(u.unmapped, pathMonetaryTotal+"/PrepaidAmount")
+		}
+	}
+	if doc.PrepaidPayment != nil {
+		if d, ok := einvoice.ParseDate(doc.PrepaidPayment.PaidDate); ok {
+			inv.PaidDate.Set(d)
+		}
+	}
+	return nil
+}
+
+func (u *unmarshaller) line(path string, index int, l *line) (err error) {
+	var (
+		inv  = u.inv
+		item = &invoicing.InvoiceItem{
+			Description: nullable.TrimmedString(strings.TrimSpace(l.Item.Name)),
+			ProductID:   nullable.TrimmedString(l.Item.SellersItemID.id()),
+		}
+	)
+	if lineID := strings.TrimSpace(l.ID); lineID != strconv.Itoa(index+1) {
+		item.PositionNumber = nullable.TrimmedString(lineID)
+	}
+	if strings.TrimSpace(l.Item.Description) != "" {
+		u.unmapped = append(u.unmapped, path+"/Item/Description")
+	}
+	if len(l.Notes) > 0 {
+		u.unmapped = append(u.unmapped, path+"/Note")
+	}
+	if l.InvoicePeriod != nil {
+		u.unmapped = append(u.unmapped, path+"/InvoicePeriod")
+	}
+	if ref := l.OrderLineReference; ref != nil {
+		if id := strings.TrimSpace(ref.OrderID); id != "" {
+			item.OrderID = nullable.TrimmedString(id)
+		} else if strings.TrimSpace(ref.LineID) != unknownLineID {
+			u.unmapped = append(u.unmapped, path+"/OrderLineReference/LineID")
+		}
+	}
+	if ref := l.DespatchLineReference; ref != nil {
+		if id := strings.TrimSpace(ref.DocumentID); id != "" {
+			item.DeliveryID = nullable.TrimmedString(id)
+		} else if strings.TrimSpace(ref.LineID) != unknownLineID {
+			u.unmapped = append(u.unmapped, path+"/DespatchLineReference/LineID")
+		}
+	}
+
+	if currency := strings.TrimSpace(l.LineExtensionAmount.CurrencyID); currency != "" && currency != inv.Currency.String() {
+		item.Currency = money.NullableCurrency(currency)
+	}
+	lineTotal, err := einvoice.ParseAmountAt(l.LineExtensionAmount.Value, path+"/LineExtensionAmount")
+	if err != nil {
+		return err
+	}
+	negative := lineTotal < 0
+	item.Subtotal.Set(lineTotal.Abs())
+
+	q, quantityPath := l.InvoicedQuantity, path+"/InvoicedQuantity"
+	if l.CreditedQuantity != nil {
+		q, quantityPath = l.CreditedQuantity, path+"/CreditedQuantity"
+	}
+	if q != nil {
+		quantity, err := einvoice.ParseDecimalAt(q.Value, quantityPath)
+		if err != nil {
+			return err
+		}
+		negative = negative || quantity < 0
+		item.Quantity.Set(math.Abs(quantity))
+		if code := strings.TrimSpace(q.UnitCode); code != "" && code != einvoice.DefaultUnitCode {
+			item.Unit = nullable.TrimmedString(code)
+		}
+	}
+	if negative && !inv.CreditNote {
+		item.CreditNote = true
+	}
+	if l.Price != nil {
+		price, err := einvoice.ParseAmountAt(l.Price.PriceAmount.Value, path+"/Price/PriceAmount")
+		if err != nil {
+			return err
+		}
+		item.UnitPrice.Set(price)
+	}
+	if l.TaxTotal != nil {
+		tax, err := einvoice.ParseAmountAt(l.TaxTotal.TaxAmount.Value, path+"/TaxTotal/TaxAmount")
+		if err != nil {
+			return err
+		}
+		item.TaxAmount.Set(tax.Abs())
+	}
+	if l.Item.ClassifiedTaxCategory.Percent != "" {
+		percent, err := einvoice.ParseDecimalAt(l.Item.ClassifiedTaxCategory.Percent, path+"/Item/ClassifiedTaxCategory/Percent")
+		if err != nil {
+			return err
+		}
+		item.TaxPercent.Set(money.Rate(percent))
+	}
+
+	var discount money.Amount
+	for i, allowance := range l.AllowanceCharges {
+		if allowance.ChargeIndicator {
+			u.unmapped = append(u.unmapped, fmt.Sprintf("%s/AllowanceCharge[%d]", path, i))
+			continue
+		}
+		amount, err := einvoice.ParseAmountAt(allowance.Amount.Value, path+"/AllowanceCharge/Amount")
+		if err != nil {
+			return err
+		}
+		discount += amount.Abs()
+		if allowance.MultiplierFactorNumeric != "" {
+			percent, err := einvoice.ParseDecimalAt(allowance.MultiplierFactorNumeric, path+"/AllowanceCharge/MultiplierFactorNumeric")
+			if err != nil {
+				return err
+			}
+			item.DiscountPercent.Set(money.Rate(percent))
+		}
+	}
+	if discount != 0 {
+		// The discount amount is derived from the percentage
+		// if both are given and consistent
+		expected := money.Amount(item.Quantity.Get()) * item.UnitPrice.Get() * money.Amount(item.DiscountPercent.Get()) / 100
+		if item.DiscountPercent.IsNull() || !expected.RoundToCents().WithinOneCent(discount) {
+			item.DiscountAmount.Set(discount.RoundToCents())
+		}
+	}
+	inv.Items = append(inv.Items, item)
+	return nil
+}
@@ -0,0 +1,87 @@
+package einvoice
+
+import (
+	"regexp"
+	"strings"
+)
+
+// DefaultUnitCode is the UN/ECE Recommendation 20 code "one"
+// used for items without a known unit
+const DefaultUnitCode = "C62"
+
+// unitCodes maps lower case unit names and abbreviations
+// to UN/ECE Recommendation 20 unit codes
+var unitCodes = map[string]string{
+	"stk":       "H87",
+	"stk.":      "H87",
+	"stück":     "H87",
+	"st":        "H87",
+	"st.":       "H87",
+	"pcs":       "H87",
+	"pc":        "H87",
+	"piece":     "H87",
+	"pieces":    "H87",
+	"h":         "HUR",
+	"std":       "HUR",
+	"std.":      "HUR",
+	"stunde":    "HUR",
+	"stunden":   "HUR",
+	"hour":      "HUR",
+	"hours":     "HUR",
+	"min":       "MIN",
+	"minute":    "MIN",
+	"minuten":   "MIN",
+	"tag":       "DAY",
+	"tage":      "DAY",
+	"day":       "DAY",
+	"days":      "DAY",
+	"woche":     "WEE",
+	"week":      "WEE",
+	"monat":     "MON",
+	"monate":    "MON",
+	"month":     "MON",
+	"months":    "MON",
+	"jahr":      "ANN",
+	"year":      "ANN",
+	"kg":        "KGM",
+	"g":         "GRM",
+	"t":         "TNE",
+	"m":         "MTR",
+	"lfm":       "MTR",
+	"km":        "KMT",
+	"m2":        "MTK",
+	"m²":        "MTK",
+	"qm":        "MTK",
+	"m3":        "MTQ",
+	"m³":        "MTQ",
+	"cbm":       "MTQ",
+	"l":         "LTR",
+	"liter":     "LTR",
+	"kwh":       "KWH",
+	"mwh":       "MWH",
+	"psch":      "LS",
+	"psch.":     "LS",
+	"pauschal":  "LS",
+	"pauschale": "LS",
+	"lump sum":  "LS",
+	"set":       "SET",
+	"paar":      "PR",
+	"pair":      "PR",
+	"%":         "P1",
+}
+
+var unitCodeRegexp = regexp.MustCompile(`^[A-Z0-9]{2,3}$`)
+
+// UnitCode returns the UN/ECE Recommendation 20 code for a unit name
+// like "Stk" or "kWh", or the unit itself if it already is a code.
+// Returns DefaultUnitCode and false if the unit is unknown.
+func UnitCode(unit string) (string, bool) {
+	unit = strings.TrimSpace(unit)
+	if code, ok := unitCodes[strings.ToLower(unit)]; ok {
+		return code, true
+	}
+	if unitCodeRegexp.MatchString(unit) {
+		return unit, true
+	}
+	return DefaultUnitCode, false
+}
@@ -0,0 +1,357 @@
+package einvoice
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+
+	"github.com/domonda/go-types/money"
+
+	"github.com/docvibe-ai/api/go/invoicing"
+)
+
+// Severity of a Finding as used by the EN 16931 validation artefacts
+type Severity string
+
+const (
+	// SeverityFatal findings make the e-invoice invalid
+	SeverityFatal Severity = "FATAL"
+	// SeverityWarning findings don't make the e-invoice invalid
+	// but indicate that the exported data differs from the invoice
+	SeverityWarning Severity = "WARNING"
+)
+
+// Finding is a business rule of EN 16931
+// that is violated by an invoice
+type Finding struct {
+	// Rule is the ID of the business rule like "BR-16" or "BR-CO-15"
+	Rule string `json:"rule"`
+	// Severity of the violation
+	Severity Severity `json:"severity"`
+	// Path is the JSON path of the offending invoice field
+	// like "issuer_address.country" or "items[2].description"
+	Path string `json:"path"`
+	// Message describes the violation
+	Message string `json:"message"`
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s %s at %s: %s", f.Severity, f.Rule, f.Path, f.Message)
+}
+
+// HasFatal returns if any of the findings has SeverityFatal
+func HasFatal(findings []Finding) bool {
+	for _, f := range findings {
+		if f.Severity == SeverityFatal {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the invoice against the business rules of EN 16931
+// (BR-*, BR-CO-* and the VAT category rules like BR-S-*, BR-AE-* and BR-IC-*)
+// as the invoice would be exported by the cii and ubl packages.
+// An invoice without findings of SeverityFatal is accepted
+// as a valid EN 16931 e-invoice.
+//
+// Rules that are always fulfilled by the export,
+// like the specification identifier (BR-01) or the line IDs (BR-21),
+// and rules for elements that are not part of the invoice,
+// like the payee (BR-17) or tax representative (BR-18…BR-20),
+// are not checked.
+// A rule violated for several VAT rates or items at the same path
+// is reported only once.
+// A nil invoice has no findings because there is no rule of EN 16931
+// for it, callers have to check for nil before exporting an invoice.
+// Validate does not normalize the invoice.
+func Validate(inv *invoicing.Invoice) []Finding {
+	if inv == nil {
+		return nil
+	}
+	v := &validator{inv: inv}
+	v.header()
+	v.parties()
+	v.items()
+	v.taxBreakdown()
+	v.totals()
+	v.payment()
+	return v.findings
+}
+
+type validator struct {
+	inv      *invoicing.Invoice
+	findings []Finding
+}
+
+func (v *validator) fatalIf(violated bool, rule, path, format string, args ...any) {
+	if violated && !v.has(rule, path) {
+		v.findings = append(v.findings, Finding{Rule: rule, Severity: SeverityFatal, Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+}
+
+func (v *validator) warningIf(violated bool, rule, path, format string, args ...any) {
+	if violated && !v.has(rule, path) {
+		v.findings = append(v.findings, Finding{Rule: rule, Severity: SeverityWarning, Path: path, Message: fmt.Sprintf(format, args...)})
+	}
+}
+
+// has returns if a finding of the rule at the path was already reported,
+// an empty rule matches any rule
+func (v *validator) has(rule, path string) bool {
+	return slices.ContainsFunc(v.findings, func(f Finding) bool {
+		return (rule == "" || f.Rule == rule) && f.Path == path
+	})
+}
+
+func (v *validator) header() {
+	inv := v.inv
+	v.fatalIf(inv.InvoiceID.IsNull(), "BR-02", "invoice_id", "invoice number is missing")
+	v.fatalIf(inv.IssueDate.IsNull(), "BR-03", "issue_date", "invoice issue date is missing")
+	v.fatalIf(inv.Currency.IsNull(), "BR-05", "currency", "invoice currency code is missing")
+	v.fatalIf(len(inv.Items) == 0, "BR-16", "items", "invoice has no invoice lines")
+	if inv.PeriodStart.IsNotNull() && inv.PeriodEnd.IsNotNull() {
+		v.fatalIf(inv.PeriodEnd.Get() < inv.PeriodStart.Get(), "BR-29", "period_end", "invoicing period end date %s is before start date %s", inv.PeriodEnd.Get(), inv.PeriodStart.Get())
+	}
+}
+
+// vatIDPrefixRegexp matches the ISO 3166-1 alpha-2 country code prefix
+// of a VAT ID, Greece uses the prefix EL
+var vatIDPrefixRegexp = regexp.MustCompile(`^[A-Z]{2}`)
+
+func (v *validator) parties() {
+	inv := v.inv
+	v.fatalIf(inv.Issuer.IsNull(), "BR-06", "issuer", "seller name is missing")
+	v.fatalIf(inv.Customer.IsNull(), "BR-07", "customer", "buyer name is missing")
+	v.fatalIf(inv.IssuerAddress == nil || *inv.IssuerAddress == (invoicing.Address{}), "BR-08", "issuer_address", "seller postal address is missing")
+	v.fatalIf(inv.IssuerAddress == nil || inv.IssuerAddress.Country.IsNull(), "BR-09", "issuer_address.country", "seller country code is missing")
+	v.fatalIf(inv.CustomerBillingAddress == nil || *inv.CustomerBillingAddress == (invoicing.Address{}), "BR-10", "customer_billing_address", "buyer postal address is missing")
+	v.fatalIf(inv.CustomerBillingAddress == nil || inv.CustomerBillingAddress.Country.IsNull(), "BR-11", "customer_billing_address.country", "buyer country code is missing")
+	if inv.CustomerShippingAddress != nil && *inv.CustomerShippingAddress != (invoicing.Address{}) {
+		v.fatalIf(inv.CustomerShippingAddress.Country.IsNull(), "BR-57", "customer_shipping_address.country", "deliver to country code is missing")
+	}
+	if inv.IssuerVATID.IsNotNull() {
+		v.fatalIf(!vatIDPrefixRegexp.MatchString(inv.IssuerVATID.String()), "BR-CO-09", "issuer_vat_id", "seller VAT identifier %q has no country code prefix", inv.IssuerVATID.String())
+	}
+	if inv.CustomerVATID.IsNotNull() {
+		v.fatalIf(!vatIDPrefixRegexp.MatchString(inv.CustomerVATID.String()), "BR-CO-09", "customer_vat_id", "buyer VAT identifier %q has no country code prefix", inv.CustomerVATID.String())
+	}
+}
+
+// lineAmount returns the line net amount of an item as exported
+// and false if the item has no subtotal and no unit price
+func lineAmount(item *invoicing.InvoiceItem) (money.Amount, bool) {
+	switch {
+	case item.Subtotal.IsNotNull():
+		return item.Subtotal.Get(), true
+	case item.UnitPrice.IsNotNull():
+		quantity := 1.0
+		if item.Quantity.IsNotNull() {
+			quantity = item.Quantity.Get()
+		}
+		return money.Amount(quantity) * item.UnitPrice.Get(), true
+	}
+	return 0, false
+}
+
+func (v *validator) items() {
+	inv := v.inv
+	for i, item := range inv.Items {
+		if item == nil {
+			continue
+		}
+		path := "items[" + strconv.Itoa(i) + "]"
+		v.warningIf(item.Quantity.IsNull(), "BR-22", path+".quantity", "invoiced quantity is missing and exported as 1")
+		_, hasAmount := lineAmount(item)
+		v.fatalIf(!hasAmount, "BR-24", path+".subtotal", "invoice line net amount is missing")
+		v.fatalIf(item.Description.IsNull(), "BR-25", path+".description", "item name is missing")
+		// The export derives the net price from the subtotal
+		// only for lines without discount
+		hasDiscount := item.DiscountPercent.IsNotNull() || item.DiscountAmount.IsNotNull()
+		hasPrice := item.UnitPrice.IsNotNull() ||
+			(item.Subtotal.IsNotNull() && !hasDiscount && (item.Quantity.IsNull() || item.Quantity.Get() != 0))
+		v.fatalIf(!hasPrice, "BR-26", path+".unit_price", "item net price is missing")
+		v.fatalIf(item.UnitPrice.IsNotNull() && item.UnitPrice.Get() < 0, "BR-27", path+".unit_price", "item net price %f is negative", item.UnitPrice.Get())
+		v.fatalIf(item.DiscountPercent.IsNotNull() && item.DiscountAmount.IsNull() && item.UnitPrice.IsNull(), "BR-41", path+".discount_amount", "invoice line allowance amount can't be calculated without unit price")
+		v.itemTaxPercent(path, item)
+	}
+}
+
+// itemTaxPercent checks that the VAT rate of an item can be determined,
+// matches its VAT category and is part of the VAT breakdown
+func (v *validator) itemTaxPercent(path string, item *invoicing.InvoiceItem) {
+	inv := v.inv
+	taxPercent, ok := ItemTaxPercent(inv, item)
+	v.fatalIf(!ok, "BR-CO-04", path+".tax_percent", "invoiced item VAT rate is missing and the invoice has more than one VAT rate")
+	if !ok {
+		return
+	}
+	category := TaxCategoryOf(inv, taxPercent, ExemptionReason(inv, taxPercent))
+	switch category {
+	case TaxCategoryStandard:
+		v.fatalIf(taxPercent <= 0, categoryRule(category, "05"), path+".tax_percent", "invoiced item VAT rate %v%% must be greater than zero for VAT category %s", taxPercent, category)
+	default:
+		v.fatalIf(taxPercent != 0, categoryRule(category, "05"), path+".tax_percent", "invoiced item VAT rate %v%% must be zero for VAT category %s", taxPercent, category)
+	}
+	subtotals := TaxSubtotals(inv)
+	if len(subtotals) > 0 {
+		inBreakdown := slices.ContainsFunc(subtotals, func(t invoicing.TaxSubtotal) bool { return t.TaxPercent.Get() == taxPercent })
+		v.fatalIf(!inBreakdown, categoryRule(category, "08"), path+".tax_percent", "invoiced item VAT rate %v%% is missing in the VAT breakdown", taxPercent)
+	}
+}
+
+// categoryRule returns the ID of a business rule of a VAT category
+// like "BR-S-08" or "BR-IC-11"
+func categoryRule(category TaxCategory, number string) string {
+	code := string(category)
+	if category == TaxCategoryIntraCommunity {
+		code = "IC"
+	}
+	return "BR-" + code + "-" + number
+}
+
+func (v *validator) taxBreakdown() {
+	inv := v.inv
+	for i, t := range inv.TaxBreakdown {
+		v.fatalIf(t.TaxPercent.IsNull(), "BR-48", "tax_breakdown["+strconv.Itoa(i)+"].tax_percent", "VAT category rate is missing, the VAT breakdown is calculated from the items")
+	}
+	subtotals := TaxSubtotals(inv)
+	v.fatalIf(len(subtotals) == 0, "BR-CO-18", "tax_breakdown", "invoice has no VAT breakdown and it can't be calculated from the items or the subtotal and tax")
+	for i, t := range subtotals {
+		category := TaxCategoryOf(inv, t.TaxPercent.Get(), t.ExemptionReason.String())
+		// The seller tax registration identifier is sufficient
+		// for the VAT categories where the buyer is not liable for the VAT
+		hasSellerTaxID := inv.IssuerVATID.IsNotNull() || (inv.IssuerTaxNumber.IsNotNull() && !category.IsReverseCharge())
+		v.fatalIf(category != TaxCategoryOutOfScope && !hasSellerTaxID, categoryRule(category, "02"), "issuer_vat_id", "seller VAT identifier is required for VAT category %s", category)
+		switch category {
+		case TaxCategoryStandard:
+			expected := (t.TaxableAmount * money.Amount(t.TaxPercent.Get()) / 100).RoundToCents()
+			v.fatalIf(!t.TaxAmount.WithinOneCent(expected), "BR-CO-17", v.breakdownPath(i, "tax_amount"), "VAT category tax amount %f is not taxable amount %f multiplied by rate %v%%", t.TaxAmount, t.TaxableAmount, t.TaxPercent.Get())
+			v.fatalIf(t.ExemptionReason.IsNotNull(), "BR-S-10", v.breakdownPath(i, "exemption_reason"), "VAT exemption reason %q is not allowed for standard rated VAT", t.ExemptionReason.String())
+		default:
+			v.fatalIf(t.TaxAmount != 0, categoryRule(category, "09"), v.breakdownPath(i, "tax_amount"), "VAT category tax amount %f must be zero for VAT category %s", t.TaxAmount, category)
+		}
+		if inv.ReverseCharge {
+			v.warningIf(t.TaxPercent.Get() != 0, "BR-AE-05", v.breakdownPath(i, "tax_percent"), "reverse charge invoice has a VAT rate of %v%% that is exported as standard rated", t.TaxPercent.Get())
+		}
+		switch category {
+		case TaxCategoryReverseCharge:
+			v.fatalIf(inv.CustomerVATID.IsNull(), "BR-AE-02", "customer_vat_id", "buyer VAT identifier is required for reverse charge")
+		case TaxCategoryIntraCommunity:
+			v.fatalIf(inv.PeriodStart.IsNull() && inv.PeriodEnd.IsNull(), "BR-IC-11", "period_start", "actual delivery date or invoicing period is required for intra-community supplies")
+			v.fatalIf(inv.CustomerShippingAddress == nil || inv.CustomerShippingAddress.Country.IsNull(), "BR-IC-12", "customer_shipping_address.country", "deliver to country code is required for intra-community supplies")
+		}
+	}
+	if len(subtotals) > 0 {
+		v.categorySums(subtotals)
+	}
+	// Reported only if no VAT category requires the seller VAT identifier
+	// to avoid two findings for the same missing field
+	v.fatalIf(inv.IssuerVATID.IsNull() && !v.has("", "issuer_vat_id"), "BR-CO-26", "issuer_vat_id", "seller VAT identifier is missing, the export has no other seller identifier")
+}
+
+// breakdownPath returns the JSON path of a field of the VAT breakdown
+// or of the invoice fields the VAT breakdown was calculated from
+func (v *validator) breakdownPath(index int, field string) string {
+	if len(v.inv.TaxBreakdown) > 0 {
+		return "tax_breakdown[" + strconv.Itoa(index) + "]." + field
+	}
+	switch field {
+	case "taxable_amount":
+		return "subtotal"
+	case "tax_amount":
+		return "tax"
+	}
+	return "tax_breakdown"
+}
+
+// categorySums checks the taxable amounts of the VAT breakdown
+// against the line net amounts with the same VAT rate.
+// The export represents an already deducted discount
+// as document level allowances per VAT rate,
+// but has no document level charges.
+func (v *validator) categorySums(subtotals []invoicing.TaxSubtotal) {
+	inv := v.inv
+	var lineTotal money.Amount
+	for _, item := range inv.Items {
+		if item == nil {
+			continue
+		}
+		amount, ok := lineAmount(item)
+		if _, hasTaxPercent := ItemTaxPercent(inv, item); !ok || !hasTaxPercent {
+			// Already reported by BR-24 or BR-CO-04
+			return
+		}
+		lineTotal += ItemSign(inv, item) * amount
+	}
+	if len(inv.Items) == 0 {
+		return
+	}
+	var taxBasis money.Amount
+	for _, t := range subtotals {
+		taxBasis += t.TaxableAmount
+	}
+	hasAllowances := (lineTotal - taxBasis).RoundToCents() > 0
+	for i, t := range subtotals {
+		var itemsSum money.Amount
+		for _, item := range inv.Items {
+			if item == nil {
+				continue
+			}
+			if taxPercent, _ := ItemTaxPercent(inv, item); taxPercent == t.TaxPercent.Get() {
+				amount, _ := lineAmount(item)
+				itemsSum += ItemSign(inv, item) * amount
+			}
+		}
+		diff := (itemsSum - t.TaxableAmount).RoundToCents()
+		category := TaxCategoryOf(inv, t.TaxPercent.Get(), t.ExemptionReason.String())
+		v.fatalIf(diff < 0 || (diff > 0 && !hasAllowances), categoryRule(category, "08"), v.breakdownPath(i, "taxable_amount"),
+			"VAT category taxable amount %f does not match the sum %f of the invoice lines with VAT rate %v%%", t.TaxableAmount, itemsSum, t.TaxPercent.Get())
+	}
+	if inv.Subtotal.IsNotNull() {
+		taxBasis = inv.Subtotal.Get()
+	}
+	v.fatalIf(taxBasis > lineTotal && !taxBasis.WithinOneCent(lineTotal), "BR-CO-13", "subtotal",
+		"invoice total amount without VAT %f is greater than the sum of invoice line net amounts %f", taxBasis, lineTotal.RoundToCents())
+}
+
+func (v *validator) totals() {
+	inv := v.inv
+	subtotals := TaxSubtotals(inv)
+	var taxBasis, taxTotal money.Amount
+	for _, t := range subtotals {
+		taxBasis += t.TaxableAmount
+		taxTotal += t.TaxAmount
+	}
+	if len(subtotals) > 0 {
+		v.fatalIf(inv.Subtotal.IsNotNull() && !inv.Subtotal.Get().WithinOneCent(taxBasis), "BR-CO-13", "subtotal",
+			"invoice total amount without VAT %f does not match the sum %f of the VAT breakdown taxable amounts", inv.Subtotal.Get(), taxBasis.RoundToCents())
+		v.fatalIf(inv.Tax.IsNotNull() && !inv.Tax.Get().WithinOneCent(taxTotal), "BR-CO-14", "tax",
+			"invoice total VAT amount %f does not match the sum %f of the VAT breakdown tax amounts", inv.Tax.Get(), taxTotal.RoundToCents())
+	}
+	if inv.Subtotal.IsNotNull() {
+		taxBasis = inv.Subtotal.Get()
+	}
+	if inv.Tax.IsNotNull() {
+		taxTotal = inv.Tax.Get()
+	}
+	v.fatalIf(inv.Subtotal.IsNull() && len(subtotals) == 0, "BR-13", "subtotal", "invoice total amount without VAT is missing")
+	v.fatalIf(inv.Total.IsNull() && len(subtotals) == 0, "BR-14", "total", "invoice total amount with VAT is missing")
+	if inv.Total.IsNotNull() && len(subtotals) > 0 {
+		v.fatalIf(!inv.Total.Get().WithinOneCent(taxBasis+taxTotal), "BR-CO-15", "total",
+			"invoice total amount with VAT %f does not equal total without VAT %f plus VAT %f", inv.Total.Get(), taxBasis, taxTotal)
+	}
+}
+
+func (v *validator) payment() {
+	inv := v.inv
+	total := inv.Total.Get()
+	if inv.Total.IsNull() {
+		total = inv.Subtotal.Get() + inv.Tax.Get()
+	}
+	_, hasDiscountTerms := DiscountTermsOf(inv)
+	hasTerms := inv.DueDate.IsNotNull() || inv.PaymentTerms.IsNotNull() || hasDiscountTerms
+	v.fatalIf(!inv.PaymentStatus.IsPaid() && total > 0 && !hasTerms, "BR-CO-25", "due_date", "payment due date or payment terms are required for a positive amount due")
+	v.fatalIf(PaymentMeansCode(inv) == PaymentMeansSEPATransfer && inv.PaymentIBAN.IsNull(), "BR-61", "payment_iban", "payment account identifier is required for credit transfer")
+}
@@ -0,0 +1,223 @@
+package einvoice
+
+import (
+	"testing"
+
+	"github.com/domonda/go-types/country"
+	"github.com/domonda/go-types/date"
+	"github.com/domonda/go-types/money"
+	"github.com/domonda/go-types/vat"
+
+	"github.com/docvibe-ai/api/go/einvoice/einvoicetest"
+	"github.com/docvibe-ai/api/go/invoicing"
+)
+
+// validInvoice returns an invoice without findings
+func validInvoice() *invoicing.Invoice {
+	return einvoicetest.Invoice()
+}
+
+// reverseCharge changes the invoice to a reverse charge invoice
+// to the customer VAT ID
+func reverseCharge(inv *invoicing.Invoice, customerVATID vat.NullableID) {
+	inv.ReverseCharge = true
+	inv.CustomerVATID = customerVATID
+	inv.Tax = einvoicetest.Amount(0)
+	inv.Total = einvoicetest.Amount(150)
+	inv.TaxBreakdown = []invoicing.TaxSubtotal{{TaxPercent: einvoicetest.Rate(0), TaxableAmount: 150}}
+	for _, item := range inv.Items {
+		item.TaxPercent = einvoicetest.Rate(0)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		rule         string
+		name         string
+		modify       func(inv *invoicing.Invoice)
+		wantPath     string
+		wantSeverity Severity
+	}{
+		{rule: "BR-02", modify: func(inv *invoicing.Invoice) { inv.InvoiceID = "" }, wantPath: "invoice_id"},
+		{rule: "BR-03", modify: func(inv *invoicing.Invoice) { inv.IssueDate = "" }, wantPath: "issue_date"},
+		{rule: "BR-05", modify: func(inv *invoicing.Invoice) { inv.Currency = "" }, wantPath: "currency"},
+		{rule: "BR-06", modify: func(inv *invoicing.Invoice) { inv.Issuer = "" }, wantPath: "issuer"},
+		{rule: "BR-07", modify: func(inv *invoicing.Invoice) { inv.Customer = "" }, wantPath: "customer"},
+		{rule: "BR-08", modify: func(inv *invoicing.Invoice) { inv.IssuerAddress = nil }, wantPath: "issuer_address"},
+		{rule: "BR-09", modify: func(inv *invoicing.Invoice) { inv.IssuerAddress.Country = "" }, wantPath: "issuer_address.country"},
+		{rule: "BR-10", modify: func(inv *invoicing.Invoice) { inv.CustomerBillingAddress = &invoicing.Address{} }, wantPath: "customer_billing_address"},
+		{rule: "BR-11", modify: func(inv *invoicing.Invoice) { inv.CustomerBillingAddress.Country = "" }, wantPath: "customer_billing_address.country"},
+		{rule: "BR-13", modify: func(inv *invoicing.Invoice) {
+			inv.Subtotal = money.NullableAmount{}
+			inv.TaxBreakdown = nil
+			inv.Items[0].TaxPercent = money.NullableRate{}
+		}, wantPath: "subtotal"},
+		{rule: "BR-14", modify: func(inv *invoicing.Invoice) {
+			inv.Total = money.NullableAmount{}
+			inv.TaxBreakdown = nil
+			inv.Items[0].TaxPercent = money.NullableRate{}
+		}, wantPath: "total"},
+		{rule: "BR-16", modify: func(inv *invoicing.Invoice) { inv.Items = nil }, wantPath: "items"},
+		{rule: "BR-22", modify: func(inv *invoicing.Invoice) { inv.Items[0].Quantity.SetNull() }, wantPath: "items[0].quantity", wantSeverity: SeverityWarning},
+		{rule: "BR-24", modify: func(inv *invoicing.Invoice) {
+			inv.Items[1].Subtotal = money.NullableAmount{}
+			inv.Items[1].UnitPrice = money.NullableAmount{}
+		}, wantPath: "items[1].subtotal"},
+		{rule: "BR-25", modify: func(inv *invoicing.Invoice) { inv.Items[1].Description = "" }, wantPath: "items[1].description"},
+		{rule: "BR-26", modify: func(inv *invoicing.Invoice) {
+			inv.Items[0].UnitPrice = money.NullableAmount{}
+			inv.Items[0].DiscountAmount = einvoicetest.Amount(10)
+		}, wantPath: "items[0].unit_price"},
+		{rule: "BR-27", modify: func(inv *invoicing.Invoice) { inv.Items[0].UnitPrice = einvoicetest.Amount(-100) }, wantPath: "items[0].unit_price"},
+		{rule: "BR-29", modify: func(inv *invoicing.Invoice) {
+			inv.PeriodStart = date.NullableDate("2024-02-29")
+			inv.PeriodEnd = date.NullableDate("2024-02-01")
+		}, wantPath: "period_end"},
+		{rule: "BR-41", modify: func(inv *invoicing.Invoice) {
+			inv.Items[0].UnitPrice = money.NullableAmount{}
+			inv.Items[0].DiscountPercent = einvoicetest.Rate(10)
+		}, wantPath: "items[0].discount_amount"},
+		{rule: "BR-48", modify: func(inv *invoicing.Invoice) { inv.TaxBreakdown[1].TaxPercent = money.NullableRate{} }, wantPath: "tax_breakdown[1].tax_percent"},
+		{rule: "BR-57", modify: func(inv *invoicing.Invoice) {
+			inv.CustomerShippingAddress = &invoicing.Address{City: "Hamburg"}
+		}, wantPath: "customer_shipping_address.country"},
+		{rule: "BR-61", modify: func(inv *invoicing.Invoice) {
+			inv.PaymentIBAN = ""
+			inv.PaymentStatus = invoicing.PaymentStatusPaidWithBankTransfer
+		}, wantPath: "payment_iban"},
+		{rule: "BR-CO-04", modify: func(inv *invoicing.Invoice) { inv.Items[1].TaxPercent = money.NullableRate{} }, wantPath: "items[1].tax_percent"},
+		{rule: "BR-CO-09", modify: func(inv *invoicing.Invoice) { inv.IssuerVATID = "123456789" }, wantPath: "issuer_vat_id"},
+		{rule: "BR-CO-13", modify: func(inv *invoicing.Invoice) { inv.Subtotal = einvoicetest.Amount(160) }, wantPath: "subtotal"},
+		{rule: "BR-CO-14", modify: func(inv *invoicing.Invoice) { inv.Tax = einvoicetest.Amount(20) }, wantPath: "tax"},
+		{rule: "BR-CO-15", modify: func(inv *invoicing.Invoice) { inv.Total = einvoicetest.Amount(170) }, wantPath: "total"},
+		{rule: "BR-CO-17", modify: func(inv *invoicing.Invoice) { inv.TaxBreakdown[0].TaxAmount = 20 }, wantPath: "tax_breakdown[0].tax_amount"},
+		{rule: "BR-CO-18", modify: func(inv *invoicing.Invoice) {
+			inv.TaxBreakdown = nil
+			inv.Items[0].TaxPercent = money.NullableRate{}
+			inv.Tax = einvoicetest.Amount(20)
+		}, wantPath: "tax_breakdown"},
+		{rule: "BR-CO-25", modify: func(inv *invoicing.Invoice) { inv.DueDate = "" }, wantPath: "due_date"},
+		{rule: "BR-CO-26", name: "tax number is no seller identifier", modify: func(inv *invoicing.Invoice) {
+			inv.IssuerVATID = ""
+			inv.IssuerTaxNumber = "12/345/67890"
+		}, wantPath: "issuer_vat_id"},
+		{rule: "BR-S-02", modify: func(inv *invoicing.Invoice) { inv.IssuerVATID = "" }, wantPath: "issuer_vat_id"},
+		{rule: "BR-S-05", modify: func(inv *invoicing.Invoice) { inv.Items[0].TaxPercent = einvoicetest.Rate(-19) }, wantPath: "items[0].tax_percent"},
+		{rule: "BR-S-08", name: "taxable amount", modify: func(inv *invoicing.Invoice) {
+			inv.TaxBreakdown[0].TaxableAmount = 110
+			inv.TaxBreakdown[0].TaxAmount = 20.9
+		}, wantPath: "tax_breakdown[0].taxable_amount"},
+		{rule: "BR-S-08", name: "item rate missing in breakdown", modify: func(inv *invoicing.Invoice) { inv.Items[1].TaxPercent = einvoicetest.Rate(10) }, wantPath: "items[1].tax_percent"},
+		{rule: "BR-S-10", modify: func(inv *invoicing.Invoice) { inv.TaxBreakdown[0].ExemptionReason = "§ 4 UStG" }, wantPath: "tax_breakdown[0].exemption_reason"},
+		{rule: "BR-Z-09", modify: func(inv *invoicing.Invoice) {
+			inv.Items[1].TaxPercent = einvoicetest.Rate(0)
+			inv.TaxBreakdown[1].TaxPercent = einvoicetest.Rate(0)
+		}, wantPath: "tax_breakdown[1].tax_amount"},
+		{rule: "BR-E-08", modify: func(inv *invoicing.Invoice) {
+			inv.Items[1].TaxPercent = einvoicetest.Rate(0)
+			inv.TaxBreakdown[1] = invoicing.TaxSubtotal{TaxPercent: einvoicetest.Rate(0), TaxableAmount: 60, ExemptionReason: "§ 4 UStG"}
+			inv.Tax = einvoicetest.Amount(19)
+			inv.Total = einvoicetest.Amount(179)
+			inv.Subtotal = einvoicetest.Amount(160)
+		}, wantPath: "tax_breakdown[1].taxable_amount"},
+		{rule: "BR-AE-02", modify: func(inv *invoicing.Invoice) { reverseCharge(inv, "") }, wantPath: "customer_vat_id"},
+		{rule: "BR-AE-05", modify: func(inv *invoicing.Invoice) { inv.ReverseCharge = true }, wantPath: "tax_breakdown[0].tax_percent", wantSeverity: SeverityWarning},
+		{rule: "BR-AE-02", name: "tax number is no seller VAT identifier", modify: func(inv *invoicing.Invoice) {
+			reverseCharge(inv, "DE987654321")
+			inv.IssuerVATID = ""
+			inv.IssuerTaxNumber = "12/345/67890"
+		}, wantPath: "issuer_vat_id"},
+		{rule: "BR-IC-11", modify: func(inv *invoicing.Invoice) {
+			reverseCharge(inv, "ATU12345678")
+			inv.PeriodStart = ""
+			inv.PeriodEnd = ""
+		}, wantPath: "period_start"},
+		{rule: "BR-IC-12", modify: func(inv *invoicing.Invoice) { reverseCharge(inv, "ATU12345678") }, wantPath: "customer_shipping_address.country"},
+	}
+	for _, tt := range tests {
+		name := tt.rule
+		if tt.name != "" {
+			name += " " + tt.name
+		}
+		t.Run(name, func(t *testing.T) {
+			inv := validInvoice()
+			tt.modify(inv)
+			wantSeverity := tt.wantSeverity
+			if wantSeverity == "" {
+				wantSeverity = SeverityFatal
+			}
+			findings := Validate(inv)
+			for _, f := range findings {
+				if f.Rule == tt.rule && f.Path == tt.wantPath && f.Severity == wantSeverity {
+					return
+				}
+			}
+			t.Errorf("Validate() = %v, want %s %s at %s", findings, wantSeverity, tt.rule, tt.wantPath)
+		})
+	}
+}
+
+func TestValidate_findings(t *testing.T) {
+	tests := []struct {
+		name   string
+		inv    *invoicing.Invoice
+		modify func(inv *invoicing.Invoice)
+		want   []Finding
+	}{
+		{
+			name:   "valid invoice",
+			inv:    validInvoice(),
+			modify: func(inv *invoicing.Invoice) {},
+		},
+		{
+			name: "valid intra-community supply",
+			inv:  validInvoice(),
+			modify: func(inv *invoicing.Invoice) {
+				reverseCharge(inv, "ATU12345678")
+				inv.PeriodStart = inv.IssueDate
+				inv.PeriodEnd = inv.IssueDate
+				inv.CustomerShippingAddress = &invoicing.Address{City: "Wien", Country: country.NullableCode("AT")}
+			},
+		},
+		{
+			name: "item tax percent from single rate",
+			inv:  validInvoice(),
+			modify: func(inv *invoicing.Invoice) {
+				inv.Items = inv.Items[:1]
+				inv.Items[0].TaxPercent = money.NullableRate{}
+				inv.TaxBreakdown = inv.TaxBreakdown[:1]
+				inv.Subtotal = einvoicetest.Amount(100)
+				inv.Tax = einvoicetest.Amount(19)
+				inv.Total = einvoicetest.Amount(119)
+			},
+		},
+		{
+			name: "missing seller VAT ID reported once",
+			inv:  validInvoice(),
+			modify: func(inv *invoicing.Invoice) {
+				inv.IssuerVATID = ""
+			},
+			want: []Finding{{Rule: "BR-S-02", Severity: SeverityFatal, Path: "issuer_vat_id", Message: "seller VAT identifier is required for VAT category S"}},
+		},
+		{
+			name:   "nil invoice",
+			modify: func(inv *invoicing.Invoice) {},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.inv != nil {
+				tt.modify(tt.inv)
+			}
+			got := Validate(tt.inv)
+			if len(got) != len(tt.want) {
+				t.Fatalf("Validate() = %v, want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Errorf("Validate()[%d] = %v, want %v", i, got[i], tt.want[i])
+				}
+			}
+		})
+	}
+}
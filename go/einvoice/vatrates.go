@@ -0,0 +1,84 @@
+package einvoice
+
+import (
+	"math"
+
+	"github.com/domonda/go-types/money"
+
+	"github.com/docvibe-ai/api/go/invoicing"
+)
+
+// vatRates are the standard, reduced and zero VAT rates
+// of the EU member states and Switzerland by country code,
+// including recently changed rates still found on invoices
+var vatRates = map[string][]money.Rate{
+	"AT": {20, 13, 10, 0},
+	"BE": {21, 12, 6, 0},
+	"BG": {20, 9, 0},
+	"CH": {8.1, 7.7, 3.8, 3.7, 2.6, 2.5, 0},
+	"CY": {19, 9, 5, 3, 0},
+	"CZ": {21, 15, 12, 10, 0},
+	"DE": {19, 16, 7, 5, 0},
+	"DK": {25, 0},
+	"EE": {24, 22, 20, 13, 9, 5, 0},
+	"ES": {21, 10, 5, 4, 0},
+	"FI": {25.5, 24, 14, 13.5, 10, 0},
+	"FR": {20, 10, 5.5, 2.1, 0},
+	"GR": {24, 13, 6, 0},
+	"HR": {25, 13, 5, 0},
+	"HU": {27, 18, 5, 0},
+	"IE": {23, 13.5, 9, 4.8, 0},
+	"IT": {22, 10, 5, 4, 0},
+	"LT": {21, 12, 9, 5, 0},
+	"LU": {17, 16, 14, 8, 7, 3, 0},
+	"LV": {21, 12, 5, 0},
+	"MT": {18, 7, 5, 0},
+	"NL": {21, 9, 0},
+	"PL": {23, 8, 5, 0},
+	"PT": {23, 13, 6, 0},
+	"RO": {21, 19, 11, 9, 5, 0},
+	"SE": {25, 12, 6, 0},
+	"SI": {22, 9.5, 5, 0},
+	"SK": {23, 20, 19, 10, 5, 0},
+}
+
+// sellerVATRates returns the VAT rates of the country
+// of the issuer address or else of the issuer VAT ID.
+// Returns nil if the country is unknown.
+func sellerVATRates(inv *invoicing.Invoice) []money.Rate {
+	var countryCode string
+	switch {
+	case inv.IssuerAddress != nil && inv.IssuerAddress.Country.IsNotNull():
+		countryCode = inv.IssuerAddress.Country.String()
+	case inv.IssuerVATID.IsNotNull():
+		countryCode = vatIDPrefix(inv.IssuerVATID.String())
+		if countryCode == "EL" {
+			countryCode = "GR"
+		}
+	}
+	return vatRates[countryCode]
+}
+
+// blendedVATRate returns the VAT rate of the seller's country
+// that results in the tax amount for the taxable amount,
+// allowing for one cent rounding difference per item.
+// If several rates are within the tolerance the closest is returned.
+// Returns false if no rate of the seller's country matches.
+func blendedVATRate(inv *invoicing.Invoice, taxable, tax money.Amount) (money.Rate, bool) {
+	if taxable == 0 {
+		return 0, false
+	}
+	var (
+		tolerance = 0.01*float64(max(1, len(inv.Items))) + 1e-6
+		result    money.Rate
+		found     bool
+		minDiff   = math.Inf(1)
+	)
+	for _, rate := range sellerVATRates(inv) {
+		diff := math.Abs(float64(taxable*money.Amount(rate)/100 - tax))
+		if diff <= tolerance && diff < minDiff {
+			result, found, minDiff = rate, true, diff
+		}
+	}
+	return result, found
+}
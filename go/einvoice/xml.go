@@ -0,0 +1,69 @@
+package einvoice
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+)
+
+// AddNamespacePrefixes adds namespace prefixes to the element names
+// of XML data marshalled without namespaces and adds the namespaceAttrs
+// like xmlns:cac="..." to the root element.
+// The prefix function returns the prefix for an element
+// with the passed local name and the local names of its parent elements.
+// An empty prefix leaves the element name unchanged.
+// The result is indented with two spaces.
+func AddNamespacePrefixes(data []byte, namespaceAttrs []xml.Attr, prefix func(parents []string, local string) string) ([]byte, error) {
+	var (
+		buf     bytes.Buffer
+		decoder = xml.NewDecoder(bytes.NewReader(data))
+		encoder = xml.NewEncoder(&buf)
+		parents []string
+	)
+	prefixed := func(parents []string, local string) xml.Name {
+		if p := prefix(parents, local); p != "" {
+			return xml.Name{Local: p + ":" + local}
+		}
+		return xml.Name{Local: local}
+	}
+	encoder.Indent("", "  ")
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			name := prefixed(parents, t.Name.Local)
+			parents = append(parents, t.Name.Local)
+			t.Name = name
+			if len(parents) == 1 {
+				t.Attr = append(namespaceAttrs, t.Attr...)
+			}
+			token = t
+		case xml.EndElement:
+			parents = parents[:len(parents)-1]
+			t.Name = prefixed(parents, t.Name.Local)
+			token = t
+		}
+		if err = encoder.EncodeToken(token); err != nil {
+			return nil, err
+		}
+	}
+	if err := encoder.Flush(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// NamespaceAttr returns the attribute declaring a namespace prefix,
+// or the default namespace if prefix is empty
+func NamespaceAttr(prefix, namespace string) xml.Attr {
+	if prefix == "" {
+		return xml.Attr{Name: xml.Name{Local: "xmlns"}, Value: namespace}
+	}
+	return xml.Attr{Name: xml.Name{Local: "xmlns:" + prefix}, Value: namespace}
+}
@@ -0,0 +1,131 @@
+package invoicing
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/domonda/go-types/money"
+)
+
+// PartnerEntryType returns the expected type of the accounting entry
+// on the partner account (vendor or client account) of the invoice.
+// An incoming invoice is credited to the vendor account
+// and an outgoing invoice is debited to the client account,
+// for a credit note it is the other way around.
+// Returns false if the invoice type is unknown.
+func (inv *Invoice) PartnerEntryType() (AccountingEntryType, bool) {
+	var t AccountingEntryType
+	switch inv.Type {
+	case InvoiceTypeIncoming:
+		t = AccountingEntryTypeCredit
+	case InvoiceTypeOutgoing:
+		t = AccountingEntryTypeDebit
+	default:
+		return "", false
+	}
+	if inv.CreditNote {
+		t = t.Opposite()
+	}
+	return t, true
+}
+
+// Opposite returns DEBIT for CREDIT and CREDIT for DEBIT,
+// or the unchanged value for an invalid type.
+func (t AccountingEntryType) Opposite() AccountingEntryType {
+	switch t {
+	case AccountingEntryTypeCredit:
+		return AccountingEntryTypeDebit
+	case AccountingEntryTypeDebit:
+		return AccountingEntryTypeCredit
+	}
+	return t
+}
+
+// GrossAmount returns the Amount plus the TaxAmount of the entry
+func (a *AccountingEntry) GrossAmount() money.Amount {
+	return a.Amount + a.TaxAmount.Get()
+}
+
+// CheckAccountingEntries checks that the debit and credit entries balance
+// and that the partner account entries match the invoice total
+// and are booked in the direction expected for the invoice type.
+//
+// Entries balance if the sums of the gross amounts (Amount plus TaxAmount)
+// per side are equal. Separate tax entries without TaxAmount
+// are part of the gross sum with their Amount.
+//
+// Partner entries are the entries booked to the PartnerAccountNumber.
+// Without a partner account number, a single entry on the side
+// expected for the partner account is used as partner entry.
+func (inv *AccountingInvoice) CheckAccountingEntries() error {
+	if inv == nil || len(inv.AccountingEntries) == 0 {
+		return nil
+	}
+	var (
+		result                  error
+		debitGross, creditGross money.Amount
+	)
+	for _, entry := range inv.AccountingEntries {
+		if entry == nil {
+			continue
+		}
+		switch entry.Type {
+		case AccountingEntryTypeDebit:
+			debitGross += entry.GrossAmount()
+		case AccountingEntryTypeCredit:
+			creditGross += entry.GrossAmount()
+		default:
+			// Can't check balance with entries of unknown type
+			return nil
+		}
+	}
+	tolerance := money.Amount(len(inv.AccountingEntries)) * 0.01
+	if !withinTolerance(debitGross, creditGross, tolerance) {
+		result = errors.Join(result, fmt.Errorf("accounting entries do not balance: debit %f, credit %f", debitGross, creditGross))
+	}
+	return errors.Join(result, inv.checkPartnerEntries())
+}
+
+func (inv *AccountingInvoice) checkPartnerEntries() (result error) {
+	expectedType, typeKnown := inv.PartnerEntryType()
+
+	var partnerEntries []*AccountingEntry
+	if inv.PartnerAccountNumber.IsNotNull() {
+		for _, entry := range inv.AccountingEntries {
+			if entry != nil && entry.GeneralLedgerAccountNumber.String() == inv.PartnerAccountNumber.String() {
+				partnerEntries = append(partnerEntries, entry)
+			}
+		}
+		if len(partnerEntries) == 0 {
+			return fmt.Errorf("no accounting entry for partner account %s", inv.PartnerAccountNumber.String())
+		}
+	} else if typeKnown {
+		for _, entry := range inv.AccountingEntries {
+			if entry != nil && entry.Type == expectedType {
+				partnerEntries = append(partnerEntries, entry)
+			}
+		}
+		if len(partnerEntries) != 1 {
+			// Partner entry can't be identified
+			return nil
+		}
+	} else {
+		return nil
+	}
+
+	var partnerSum money.Amount
+	for _, entry := range partnerEntries {
+		if typeKnown && entry.Type != expectedType {
+			result = errors.Join(result, fmt.Errorf("partner account %s is booked as %s but expected %s for %s with credit note %t", entry.GeneralLedgerAccountNumber, entry.Type, expectedType, inv.Type, inv.CreditNote))
+		}
+		if entry.Type == expectedType || !typeKnown {
+			partnerSum += entry.GrossAmount()
+		} else {
+			partnerSum -= entry.GrossAmount()
+		}
+	}
+	if inv.Total.IsNotNull() && !partnerSum.Abs().WithinOneCent(inv.Total.Get()) {
+		result = errors.Join(result, fmt.Errorf("partner account entries sum %f does not match invoice total %f", partnerSum.Abs(), inv.Total.Get()))
+	}
+	return result
+}
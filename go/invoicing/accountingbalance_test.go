@@ -0,0 +1,105 @@
+package invoicing
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/domonda/go-types/money"
+	"github.com/domonda/go-types/notnull"
+)
+
+func TestAccountingInvoice_CheckAccountingEntries(t *testing.T) {
+	entry := func(entryType AccountingEntryType, account string, net, taxAmount float64) *AccountingEntry {
+		e := &AccountingEntry{Type: entryType, GeneralLedgerAccountNumber: notnull.TrimmedString(account), Amount: money.Amount(net)}
+		if taxAmount != 0 {
+			e.TaxAmount = amount(taxAmount)
+		}
+		return e
+	}
+	incoming := func(total float64, entries ...*AccountingEntry) *AccountingInvoice {
+		return &AccountingInvoice{
+			Invoice:           Invoice{Type: InvoiceTypeIncoming, Total: amount(total)},
+			AccountingEntries: entries,
+		}
+	}
+	tests := []struct {
+		name    string
+		inv     *AccountingInvoice
+		wantErr []string
+	}{
+		{name: "nil", inv: nil},
+		{name: "no entries", inv: incoming(120)},
+		{
+			name: "gross balanced",
+			inv: incoming(120,
+				entry(AccountingEntryTypeDebit, "5000", 100, 20),
+				entry(AccountingEntryTypeCredit, "33000", 120, 0),
+			),
+		},
+		{
+			name: "separate tax entry",
+			inv: incoming(120,
+				entry(AccountingEntryTypeDebit, "5000", 100, 0),
+				entry(AccountingEntryTypeDebit, "2500", 20, 0),
+				entry(AccountingEntryTypeCredit, "33000", 120, 0),
+			),
+		},
+		{
+			name: "only net amounts balance",
+			inv: incoming(100,
+				entry(AccountingEntryTypeDebit, "5000", 100, 20),
+				entry(AccountingEntryTypeCredit, "33000", 100, 0),
+			),
+			wantErr: []string{"accounting entries do not balance: debit 120.000000, credit 100.000000"},
+		},
+		{
+			name: "partner total mismatch",
+			inv: incoming(150,
+				entry(AccountingEntryTypeDebit, "5000", 100, 20),
+				entry(AccountingEntryTypeCredit, "33000", 120, 0),
+			),
+			wantErr: []string{"partner account entries sum 120.000000 does not match invoice total 150"},
+		},
+		{
+			name: "partner booked on wrong side",
+			inv: &AccountingInvoice{
+				Invoice:              Invoice{Type: InvoiceTypeIncoming, Total: amount(120)},
+				PartnerAccountNumber: "33000",
+				AccountingEntries: []*AccountingEntry{
+					entry(AccountingEntryTypeCredit, "5000", 100, 20),
+					entry(AccountingEntryTypeDebit, "33000", 120, 0),
+				},
+			},
+			wantErr: []string{"partner account 33000 is booked as DEBIT but expected CREDIT"},
+		},
+		{
+			name: "credit note",
+			inv: &AccountingInvoice{
+				Invoice: Invoice{Type: InvoiceTypeIncoming, CreditNote: true, Total: amount(120)},
+				AccountingEntries: []*AccountingEntry{
+					entry(AccountingEntryTypeCredit, "5000", 100, 20),
+					entry(AccountingEntryTypeDebit, "33000", 120, 0),
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.inv.CheckAccountingEntries()
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("AccountingInvoice.CheckAccountingEntries() error = %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("AccountingInvoice.CheckAccountingEntries() error = nil, want %q", tt.wantErr)
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("AccountingInvoice.CheckAccountingEntries() error = %q, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
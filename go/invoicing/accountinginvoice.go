@@ -5,6 +5,9 @@ package invoicing
 import (
 	"errors"
 	"fmt"
+	"slices"
+	"strings"
+	"unicode"
 
 	"github.com/domonda/go-types/money"
 	"github.com/domonda/go-types/notnull"
@@ -20,9 +23,56 @@ type AccountingInvoice struct {
 	// Partner account name (vendor or client name depending on the invoice type)
 	PartnerAccountName nullable.TrimmedString `json:"partner_account_name,omitempty"`
 
+	// Accounting entries of the invoice
 	AccountingEntries []*AccountingEntry `json:"accounting_entries,omitempty"`
 }
 
+// Normalize validates and normalizes all fields of the AccountingInvoice
+// including the embedded Invoice.
+// It returns an aggregated error of all validation issues found.
+//
+// The AccountingEntries of the AccountingInvoice are authoritative,
+// the embedded Invoice.AccountingEntries are shadowed by them in JSON.
+// If only the embedded Invoice.AccountingEntries are set,
+// then they are moved to AccountingInvoice.AccountingEntries,
+// after normalization the embedded Invoice.AccountingEntries are always nil.
+func (inv *AccountingInvoice) Normalize() error {
+	if inv == nil {
+		return nil
+	}
+	var err, result error
+	if len(inv.AccountingEntries) == 0 {
+		inv.AccountingEntries = inv.Invoice.AccountingEntries
+	} else if len(inv.Invoice.AccountingEntries) > 0 {
+		result = errors.Join(result, errors.New("embedded invoice accounting entries are ignored because accounting invoice has its own accounting entries"))
+	}
+	inv.Invoice.AccountingEntries = nil
+	if err = inv.Invoice.Normalize(); err != nil {
+		result = errors.Join(result, err)
+	}
+
+	if inv.PartnerAccountName.IsNotNull() && inv.PartnerAccountNumber.IsNull() {
+		result = errors.Join(result, fmt.Errorf("partner account name %q has no partner account number", inv.PartnerAccountName.String()))
+	}
+	if inv.PartnerAccountNumber.IsNotNull() && strings.ContainsFunc(inv.PartnerAccountNumber.String(), unicode.IsSpace) {
+		result = errors.Join(result, fmt.Errorf("partner account number %q contains whitespace", inv.PartnerAccountNumber.String()))
+		inv.PartnerAccountNumber = nullable.TrimmedString(strings.Join(strings.Fields(inv.PartnerAccountNumber.String()), ""))
+	}
+
+	inv.AccountingEntries = slices.DeleteFunc(inv.AccountingEntries, func(entry *AccountingEntry) bool {
+		return entry == nil || *entry == AccountingEntry{}
+	})
+	for i, entry := range inv.AccountingEntries {
+		if err = entry.Normalize(); err != nil {
+			result = errors.Join(result, fmt.Errorf("invalid accounting entry %d: %w", i, err))
+		}
+	}
+	if err = inv.CheckAccountingEntries(); err != nil {
+		result = errors.Join(result, fmt.Errorf("invalid accounting entries: %w", err))
+	}
+	return result
+}
+
 type AccountingEntry struct {
 	// Type of the accounting entry
 	Type AccountingEntryType `json:"type"`
@@ -38,6 +38,9 @@ type Invoice struct {
 	ContractID nullable.TrimmedString `json:"contract_id,omitempty"`
 	// Unique customer identifier
 	CustomerID nullable.TrimmedString `json:"customer_id,omitempty"`
+	// Reference assigned by the buyer to route the invoice,
+	// like the Leitweg-ID of German public authorities
+	BuyerReference nullable.TrimmedString `json:"buyer_reference,omitempty"`
 
 	// IDs of the delivery notes that are related to the invoice
 	DeliveryNoteIDs []notnull.TrimmedString `json:"delivery_note_ids,omitempty"`
@@ -48,6 +51,8 @@ type Invoice struct {
 	IssuerVATID vat.NullableID `json:"issuer_vat_id,omitempty"`
 	// Issuer's tax number other than VAT ID
 	IssuerTaxNumber nullable.TrimmedString `json:"issuer_tax_number,omitempty"`
+	// Issuer's email
+	IssuerEmail email.NullableAddress `json:"issuer_email,omitempty"`
 	// Issuer's address
 	IssuerAddress *Address `json:"issuer_address,omitempty"`
 
@@ -72,6 +77,8 @@ type Invoice struct {
 	Total money.NullableAmount `json:"total,omitempty,omitzero"`
 	// Currency of the invoice
 	Currency money.NullableCurrency `json:"currency,omitempty"`
+	// Taxable amount and tax amount per tax rate
+	TaxBreakdown []TaxSubtotal `json:"tax_breakdown,omitempty"`
 
 	// European Union reverse charge for intra-community supply or acquisition
 	ReverseCharge bool `json:"reverse_charge"`
@@ -168,6 +175,10 @@ func (inv *Invoice) Normalize() error {
 		result = errors.Join(result, fmt.Errorf("invalid issuer VAT ID: %w", err))
 		inv.IssuerVATID.SetNull()
 	}
+	if inv.IssuerEmail, err = inv.IssuerEmail.Normalized(); err != nil {
+		result = errors.Join(result, fmt.Errorf("invalid issuer email: %w", err))
+		inv.IssuerEmail.SetNull()
+	}
 	if err = inv.IssuerAddress.Normalize(); err != nil {
 		result = errors.Join(result, fmt.Errorf("invalid issuer address: %w", err))
 	}
@@ -252,14 +263,33 @@ func (inv *Invoice) Normalize() error {
 	inv.Notes = slices.DeleteFunc(inv.Notes, func(note nullable.TrimmedString) bool {
 		return note.IsNull()
 	})
-	inv.Items = slices.DeleteFunc(inv.Items, func(item *InvoiceItem) bool {
-		return item == nil || *item == InvoiceItem{}
-	})
+	// Empty items are removed after the items are checked
+	// so that the reported item lines match the document
 	for i, item := range inv.Items {
+		if item == nil || *item == (InvoiceItem{}) {
+			continue
+		}
 		if err = item.Normalize(); err != nil {
 			result = errors.Join(result, fmt.Errorf("invalid item %d: %w", i, err))
 		}
 	}
+	if err = inv.CheckItemTotals(); err != nil {
+		result = errors.Join(result, fmt.Errorf("items do not match invoice amounts: %w", err))
+	}
+	inv.Items = slices.DeleteFunc(inv.Items, func(item *InvoiceItem) bool {
+		return item == nil || *item == InvoiceItem{}
+	})
+	inv.TaxBreakdown = slices.DeleteFunc(inv.TaxBreakdown, func(t TaxSubtotal) bool {
+		return t == TaxSubtotal{}
+	})
+	for i := range inv.TaxBreakdown {
+		if err = inv.TaxBreakdown[i].Normalize(); err != nil {
+			result = errors.Join(result, fmt.Errorf("invalid tax breakdown %d: %w", i, err))
+		}
+	}
+	if err = inv.CheckTaxBreakdown(); err != nil {
+		result = errors.Join(result, fmt.Errorf("tax breakdown does not match invoice: %w", err))
+	}
 	inv.AccountingEntries = slices.DeleteFunc(inv.AccountingEntries, func(entry *AccountingEntry) bool {
 		return entry == nil || *entry == AccountingEntry{}
 	})
@@ -73,5 +73,108 @@ func (item *InvoiceItem) Normalize() error {
 			item.DiscountPercent.SetNull()
 		}
 	}
+	if item.DiscountAmount.IsNotNull() && item.DiscountAmount.Get() < 0 {
+		result = errors.Join(result, fmt.Errorf("discount amount %f is negative", item.DiscountAmount.Get()))
+		item.DiscountAmount.Set(item.DiscountAmount.Get().Abs())
+	}
+	result = errors.Join(result, item.completeLineAmounts())
+	result = errors.Join(result, item.completeTaxAmounts())
 	return result
 }
+
+// completeLineAmounts checks that Quantity × UnitPrice − discount equals Subtotal
+// or derives the one of the three values if it is missing.
+// The discount is the DiscountAmount or the DiscountPercent of Quantity × UnitPrice.
+func (item *InvoiceItem) completeLineAmounts() error {
+	var (
+		hasQuantity  = item.Quantity.IsNotNull()
+		hasUnitPrice = item.UnitPrice.IsNotNull()
+		hasSubtotal  = item.Subtotal.IsNotNull()
+		quantity     = item.Quantity.Get()
+		unitPrice    = float64(item.UnitPrice.Get())
+		subtotal     = float64(item.Subtotal.Get())
+		// factor of the price left after a discount percentage
+		factor = 1.0
+		// absolute discount amount
+		discount = float64(item.DiscountAmount.Get())
+	)
+	if item.DiscountAmount.IsNull() && item.DiscountPercent.IsNotNull() {
+		factor = 1 - float64(item.DiscountPercent.Get())/100
+	}
+	switch {
+	case hasQuantity && hasUnitPrice && hasSubtotal:
+		expected := quantity*unitPrice*factor - discount
+		// Allow half a cent rounding difference per unit
+		// with unit prices rounded to cents
+		tolerance := max(0.01, math.Abs(quantity)*0.005)
+		if math.Abs(expected-subtotal) > tolerance+amountEpsilon {
+			return fmt.Errorf("quantity %f × unit price %f minus discount does not match subtotal %f, expected %f", quantity, unitPrice, subtotal, expected)
+		}
+
+	case hasQuantity && hasUnitPrice:
+		subtotal := money.Amount(quantity*unitPrice*factor - discount).RoundToCents()
+		item.Subtotal.Set(subtotal)
+		return fmt.Errorf("derived missing subtotal %f from quantity %f × unit price %f minus discount", subtotal, quantity, unitPrice)
+
+	case hasQuantity && hasSubtotal:
+		if quantity == 0 || factor == 0 {
+			return nil
+		}
+		unitPrice := money.Amount(roundTo((subtotal+discount)/factor/quantity, 4))
+		item.UnitPrice.Set(unitPrice)
+		return fmt.Errorf("derived missing unit price %f from subtotal %f plus discount ÷ quantity %f", unitPrice, subtotal, quantity)
+
+	case hasUnitPrice && hasSubtotal:
+		if unitPrice == 0 || factor == 0 {
+			return nil
+		}
+		quantity := roundTo((subtotal+discount)/factor/unitPrice, 3)
+		item.Quantity.Set(quantity)
+		return fmt.Errorf("derived missing quantity %f from subtotal %f plus discount ÷ unit price %f", quantity, subtotal, unitPrice)
+	}
+	return nil
+}
+
+// completeTaxAmounts checks that Subtotal × TaxPercent equals TaxAmount
+// or derives TaxAmount if it is missing.
+// A missing Subtotal is not derived from TaxAmount ÷ TaxPercent
+// because the tax amount is rounded to cents and the division
+// multiplies that rounding error, for example by 100 at 1% tax.
+func (item *InvoiceItem) completeTaxAmounts() error {
+	var (
+		hasSubtotal   = item.Subtotal.IsNotNull()
+		hasTaxPercent = item.TaxPercent.IsNotNull()
+		hasTaxAmount  = item.TaxAmount.IsNotNull()
+		subtotal      = float64(item.Subtotal.Get())
+		taxPercent    = float64(item.TaxPercent.Get())
+		taxAmount     = float64(item.TaxAmount.Get())
+	)
+	switch {
+	case hasSubtotal && hasTaxPercent && hasTaxAmount:
+		expected := subtotal * taxPercent / 100
+		if math.Abs(expected-taxAmount) > 0.01+amountEpsilon {
+			return fmt.Errorf("subtotal %f × tax percent %f%% does not match tax amount %f, expected %f", subtotal, taxPercent, taxAmount, expected)
+		}
+
+	case hasSubtotal && hasTaxPercent:
+		taxAmount := money.Amount(subtotal * taxPercent / 100).RoundToCents()
+		item.TaxAmount.Set(taxAmount)
+		return fmt.Errorf("derived missing tax amount %f from subtotal %f × tax percent %f%%", taxAmount, subtotal, taxPercent)
+
+	case hasSubtotal && hasTaxAmount:
+		// TaxPercent is not derived from TaxAmount ÷ Subtotal
+		// because the ratio of amounts rounded to cents is often
+		// no VAT rate, for example 19.05% for 0.04 ÷ 0.21
+		return fmt.Errorf("missing tax percent for tax amount %f and subtotal %f", taxAmount, subtotal)
+	}
+	return nil
+}
+
+// amountEpsilon compensates floating point errors
+// when comparing amounts with a tolerance
+const amountEpsilon = 1e-6
+
+func roundTo(f float64, decimals int) float64 {
+	pow := math.Pow10(decimals)
+	return math.Round(f*pow) / pow
+}
@@ -0,0 +1,74 @@
+package invoicing
+
+import (
+	"testing"
+
+	"github.com/domonda/go-types/nullable"
+)
+
+func TestInvoiceItem_Normalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		item    InvoiceItem
+		want    InvoiceItem
+		wantErr bool
+	}{
+		{
+			name: "complete",
+			item: InvoiceItem{Subtotal: amount(100), TaxPercent: rate(20), TaxAmount: amount(20)},
+			want: InvoiceItem{Subtotal: amount(100), TaxPercent: rate(20), TaxAmount: amount(20)},
+		},
+		{
+			name:    "derive tax amount",
+			item:    InvoiceItem{Subtotal: amount(100), TaxPercent: rate(20)},
+			want:    InvoiceItem{Subtotal: amount(100), TaxPercent: rate(20), TaxAmount: amount(20)},
+			wantErr: true,
+		},
+		{
+			name:    "no tax percent from tax amount and subtotal",
+			item:    InvoiceItem{Subtotal: amount(0.21), TaxAmount: amount(0.04)},
+			want:    InvoiceItem{Subtotal: amount(0.21), TaxAmount: amount(0.04)},
+			wantErr: true,
+		},
+		{
+			name: "no subtotal from tax amount and percent",
+			item: InvoiceItem{TaxPercent: rate(1), TaxAmount: amount(0.33)},
+			want: InvoiceItem{TaxPercent: rate(1), TaxAmount: amount(0.33)},
+		},
+		{
+			name:    "derive subtotal from quantity and unit price",
+			item:    InvoiceItem{Quantity: quantity(3), UnitPrice: amount(10), DiscountPercent: rate(10), TaxPercent: rate(20)},
+			want:    InvoiceItem{Quantity: quantity(3), UnitPrice: amount(10), DiscountPercent: rate(10), Subtotal: amount(27), TaxPercent: rate(20), TaxAmount: amount(5.4)},
+			wantErr: true,
+		},
+		{
+			name:    "tax amount mismatch",
+			item:    InvoiceItem{Subtotal: amount(100), TaxPercent: rate(20), TaxAmount: amount(19)},
+			want:    InvoiceItem{Subtotal: amount(100), TaxPercent: rate(20), TaxAmount: amount(19)},
+			wantErr: true,
+		},
+		{
+			name:    "tax percent above 100",
+			item:    InvoiceItem{Subtotal: amount(100), TaxPercent: rate(120)},
+			want:    InvoiceItem{Subtotal: amount(100)},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			item := tt.item
+			err := item.Normalize()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("InvoiceItem.Normalize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if item != tt.want {
+				t.Errorf("InvoiceItem.Normalize() = %+v, want %+v", item, tt.want)
+			}
+		})
+	}
+}
+
+func quantity(v float64) (q nullable.Type[float64]) {
+	q.Set(v)
+	return q
+}
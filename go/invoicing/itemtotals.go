@@ -0,0 +1,214 @@
+package invoicing
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/domonda/go-types/money"
+)
+
+// CheckItemTotals compares the sums of the item subtotals and tax amounts
+// per currency with the Subtotal, Tax and Total of the invoice.
+// Items without currency are counted in the invoice currency.
+// A sum matches if it is within one cent per summed item
+// of the invoice amount. If the invoice subtotal matches the
+// item subtotals only with the invoice discount (DiscountAmount
+// or DiscountPercent) subtracted, then the discount is treated as
+// already deducted and the tax and total must match the item sums
+// with the tax share of the discount subtracted.
+// The returned error names the lines of the items involved
+// in every mismatch, where line is the 1-based index
+// of the item followed by its position number if available.
+func (inv *Invoice) CheckItemTotals() error {
+	if inv == nil || len(inv.Items) == 0 {
+		return nil
+	}
+	var result error
+	for _, sums := range inv.itemSumsPerCurrency() {
+		if sums.currency != "" && inv.Currency.IsNotNull() {
+			result = errors.Join(result, fmt.Errorf("%s in currency %s can't be compared with invoice currency %s", itemLines(sums.lines), sums.currency, inv.Currency.Get()))
+			continue
+		}
+		result = errors.Join(result, inv.checkItemSums(sums))
+	}
+	return result
+}
+
+type itemSums struct {
+	currency money.Currency
+	// 1-based item lines with position numbers
+	lines []string
+	items []*InvoiceItem
+
+	subtotal money.Amount
+	// missingSubtotal lists the lines without subtotal
+	missingSubtotal []string
+	tax             money.Amount
+	// missingTax lists the lines without tax amount
+	missingTax []string
+}
+
+func (inv *Invoice) itemSumsPerCurrency() []*itemSums {
+	var result []*itemSums
+	for i, item := range inv.Items {
+		if item == nil || *item == (InvoiceItem{}) {
+			continue
+		}
+		currency := item.Currency.Get()
+		if currency == inv.Currency.Get() {
+			// Items without currency and with the invoice
+			// currency are summed up together
+			currency = ""
+		}
+		index := slices.IndexFunc(result, func(s *itemSums) bool { return s.currency == currency })
+		if index == -1 {
+			index = len(result)
+			result = append(result, &itemSums{currency: currency})
+		}
+		sums := result[index]
+		line := itemLine(i, item)
+		sums.lines = append(sums.lines, line)
+		sums.items = append(sums.items, item)
+		if item.Subtotal.IsNotNull() {
+			sums.subtotal += inv.itemAmountSign(item, item.Subtotal.Get())
+		} else {
+			sums.missingSubtotal = append(sums.missingSubtotal, line)
+		}
+		if item.TaxAmount.IsNotNull() {
+			sums.tax += inv.itemAmountSign(item, item.TaxAmount.Get())
+		} else {
+			sums.missingTax = append(sums.missingTax, line)
+		}
+	}
+	return result
+}
+
+// itemAmountSign returns the amount as negative value
+// for a credit note item within an invoice that is not a credit note.
+func (inv *Invoice) itemAmountSign(item *InvoiceItem, amount money.Amount) money.Amount {
+	if item.CreditNote && !inv.CreditNote && amount > 0 {
+		return -amount
+	}
+	return amount
+}
+
+func (inv *Invoice) checkItemSums(sums *itemSums) (result error) {
+	tolerance := money.Amount(len(sums.items)) * 0.01
+
+	subtotalComparable := len(sums.missingSubtotal) == 0
+	taxComparable := len(sums.missingTax) == 0
+	if !subtotalComparable && inv.Subtotal.IsNotNull() {
+		// Can't compare the subtotal if an item has no subtotal
+		// but report that only if the other item amounts are complete
+		if len(sums.missingSubtotal) < len(sums.items) {
+			result = errors.Join(result, fmt.Errorf("%s without subtotal, can't compare item subtotals with invoice subtotal %f", itemLines(sums.missingSubtotal), inv.Subtotal.Get()))
+		}
+	}
+
+	// The discount is usually an early payment discount
+	// that is not deducted from the invoice amounts,
+	// but if the invoice subtotal matches only with the discount
+	// deducted, then the tax and total must have the tax share
+	// of the discount deducted as well.
+	// The tax share is the discount multiplied with
+	// the average tax rate of the items, which is the sum
+	// of the tax shares of the item discounts per tax rate.
+	discount := inv.discountForItemsSubtotal(sums.subtotal)
+	var taxDiscount money.Amount
+	if taxComparable && sums.subtotal != 0 {
+		taxDiscount = (discount * sums.tax / sums.subtotal).RoundToCents()
+	}
+	deducted := []bool{false}
+	if discount != 0 {
+		deducted = append(deducted, true)
+	}
+	matches := func(itemsSum, itemsDiscount, invoiceAmount money.Amount) bool {
+		return slices.ContainsFunc(deducted, func(d bool) bool {
+			if d {
+				return withinTolerance(itemsSum-itemsDiscount, invoiceAmount, tolerance)
+			}
+			return withinTolerance(itemsSum, invoiceAmount, tolerance)
+		})
+	}
+
+	if subtotalComparable && inv.Subtotal.IsNotNull() {
+		if !matches(sums.subtotal, discount, inv.Subtotal.Get()) {
+			result = errors.Join(result, inv.itemSumMismatch("subtotal", "subtotals", sums, sums.subtotal, inv.Subtotal.Get(), discount, func(item *InvoiceItem) money.Amount { return item.Subtotal.Get() }))
+		} else if discount != 0 {
+			deducted = []bool{!withinTolerance(sums.subtotal, inv.Subtotal.Get(), tolerance)}
+		}
+	}
+	if taxComparable && inv.Tax.IsNotNull() {
+		if !matches(sums.tax, taxDiscount, inv.Tax.Get()) {
+			result = errors.Join(result, inv.itemSumMismatch("tax", "tax amounts", sums, sums.tax, inv.Tax.Get(), taxDiscount, func(item *InvoiceItem) money.Amount { return item.TaxAmount.Get() }))
+		}
+	}
+	if subtotalComparable && taxComparable && inv.Total.IsNotNull() && (inv.Subtotal.IsNull() || inv.Tax.IsNull()) {
+		// If both header subtotal and tax are present, then
+		// the total was already checked against them by Normalize
+		total := sums.subtotal + sums.tax
+		totalDiscount := discount + taxDiscount
+		if !matches(total, totalDiscount, inv.Total.Get()) {
+			result = errors.Join(result, inv.itemSumMismatch("total", "totals", sums, total, inv.Total.Get(), totalDiscount, func(item *InvoiceItem) money.Amount { return item.Subtotal.Get() + item.TaxAmount.Get() }))
+		}
+	}
+	return result
+}
+
+// discountForItemsSubtotal returns the invoice discount
+// that applies to the passed sum of item amounts
+func (inv *Invoice) discountForItemsSubtotal(sum money.Amount) money.Amount {
+	switch {
+	case inv.DiscountAmount.IsNotNull():
+		return inv.DiscountAmount.Get()
+	case inv.DiscountPercent.IsNotNull():
+		return (sum * money.Amount(inv.DiscountPercent.Get()) / 100).RoundToCents()
+	}
+	return 0
+}
+
+// itemSumMismatch returns an error describing the mismatch
+// of the summed item amounts with the invoice amount.
+// If the difference equals the amount of a single item,
+// then that item is named as probably missing or duplicated.
+func (inv *Invoice) itemSumMismatch(field, itemField string, sums *itemSums, itemsSum, invoiceAmount, discount money.Amount, itemAmount func(*InvoiceItem) money.Amount) error {
+	msg := fmt.Sprintf("sum %f of item %s of %s does not match invoice %s %f", itemsSum, itemField, itemLines(sums.lines), field, invoiceAmount)
+	if discount != 0 {
+		msg += fmt.Sprintf(" with or without discount %f", discount)
+	}
+	diff := itemsSum - invoiceAmount
+	var suspects []string
+	for i, item := range sums.items {
+		if inv.itemAmountSign(item, itemAmount(item)).WithinOneCent(diff) {
+			suspects = append(suspects, sums.lines[i])
+		}
+	}
+	switch {
+	case len(suspects) > 0:
+		msg += fmt.Sprintf(", the difference %f equals the amount of %s which may be duplicated or not part of the invoice", diff, itemLines(suspects))
+	case diff < 0:
+		msg += fmt.Sprintf(", items with a sum of %f may be missing", -diff)
+	}
+	return errors.New(msg)
+}
+
+func itemLine(index int, item *InvoiceItem) string {
+	if item.PositionNumber.IsNotNull() {
+		return fmt.Sprintf("%d (position %s)", index+1, item.PositionNumber.String())
+	}
+	return fmt.Sprintf("%d", index+1)
+}
+
+func itemLines(lines []string) string {
+	if len(lines) == 1 {
+		return "line " + lines[0]
+	}
+	return "lines " + strings.Join(lines, ", ")
+}
+
+func withinTolerance(a, b, tolerance money.Amount) bool {
+	return math.Abs(float64(a-b)) <= float64(tolerance)+amountEpsilon
+}
@@ -0,0 +1,171 @@
+package invoicing
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/domonda/go-types/money"
+)
+
+func amount(v float64) (a money.NullableAmount) {
+	a.Set(money.Amount(v))
+	return a
+}
+
+func rate(v float64) (r money.NullableRate) {
+	r.Set(money.Rate(v))
+	return r
+}
+
+func item(subtotal, taxPercent, taxAmount float64) *InvoiceItem {
+	return &InvoiceItem{
+		Subtotal:   amount(subtotal),
+		TaxPercent: rate(taxPercent),
+		TaxAmount:  amount(taxAmount),
+	}
+}
+
+func TestInvoice_CheckItemTotals(t *testing.T) {
+	withDiscountPercent := func(inv *Invoice, percent float64) *Invoice {
+		inv.DiscountPercent = rate(percent)
+		return inv
+	}
+	withDiscountAmount := func(inv *Invoice, discount float64) *Invoice {
+		inv.DiscountAmount = amount(discount)
+		return inv
+	}
+	invoice := func(subtotal, tax, total float64, items ...*InvoiceItem) *Invoice {
+		return &Invoice{Subtotal: amount(subtotal), Tax: amount(tax), Total: amount(total), Items: items}
+	}
+	tests := []struct {
+		name    string
+		inv     *Invoice
+		wantErr []string
+	}{
+		{name: "nil", inv: nil},
+		{name: "no items", inv: invoice(100, 20, 120)},
+		{
+			name: "matching",
+			inv:  invoice(150, 25, 175, item(100, 20, 20), item(50, 10, 5)),
+		},
+		{
+			name: "rounding within one cent per item",
+			inv:  invoice(150.01, 25.02, 175.03, item(100, 20, 20.01), item(50, 10, 5)),
+		},
+		{
+			name: "Skonto not deducted",
+			inv:  withDiscountPercent(invoice(100, 20, 120, item(100, 20, 20)), 2),
+		},
+		{
+			name: "discount percent deducted",
+			inv:  withDiscountPercent(invoice(98, 19.6, 117.6, item(100, 20, 20)), 2),
+		},
+		{
+			name: "discount amount deducted with tax share per rate",
+			inv:  withDiscountAmount(invoice(190, 28.5, 218.5, item(100, 20, 20), item(100, 10, 10)), 10),
+		},
+		{
+			name:    "tax does not match full discount",
+			inv:     withDiscountAmount(invoice(100, 18, 118, item(100, 20, 20)), 2),
+			wantErr: []string{"item tax amounts of line 1 does not match invoice tax 18"},
+		},
+		{
+			name:    "discount deducted from subtotal but not from tax",
+			inv:     withDiscountPercent(invoice(98, 20, 118, item(100, 20, 20)), 2),
+			wantErr: []string{"item tax amounts of line 1 does not match invoice tax 20"},
+		},
+		{
+			name: "total without subtotal and tax",
+			inv: &Invoice{
+				Total: amount(175),
+				Items: []*InvoiceItem{item(100, 20, 20), item(50, 10, 5)},
+			},
+		},
+		{
+			name:    "duplicated item",
+			inv:     invoice(100, 20, 120, item(100, 20, 20), item(100, 20, 20)),
+			wantErr: []string{"item subtotals of lines 1, 2 does not match invoice subtotal 100", "equals the amount of lines 1, 2"},
+		},
+		{
+			name:    "missing items",
+			inv:     invoice(300, 60, 360, item(100, 20, 20)),
+			wantErr: []string{"items with a sum of 200"},
+		},
+		{
+			name: "missing item subtotal",
+			inv: invoice(150, 25, 175, item(100, 20, 20), &InvoiceItem{
+				TaxPercent: rate(10),
+				TaxAmount:  amount(5),
+			}),
+			wantErr: []string{"line 2 without subtotal"},
+		},
+		{
+			name: "credit note item",
+			inv: invoice(50, 10, 60, item(100, 20, 20), &InvoiceItem{
+				CreditNote: true,
+				Subtotal:   amount(50),
+				TaxPercent: rate(20),
+				TaxAmount:  amount(10),
+			}),
+		},
+		{
+			name: "position numbers and empty items",
+			inv: invoice(100, 20, 120,
+				&InvoiceItem{},
+				&InvoiceItem{PositionNumber: "A", Subtotal: amount(60), TaxPercent: rate(20), TaxAmount: amount(12)},
+				nil,
+				&InvoiceItem{PositionNumber: "B", Subtotal: amount(50), TaxPercent: rate(20), TaxAmount: amount(10)},
+			),
+			wantErr: []string{"lines 2 (position A), 4 (position B)"},
+		},
+		{
+			name: "foreign currency items",
+			inv: &Invoice{
+				Currency: "EUR",
+				Subtotal: amount(100),
+				Items:    []*InvoiceItem{{Subtotal: amount(100), Currency: "USD"}},
+			},
+			wantErr: []string{"line 1 in currency USD can't be compared with invoice currency EUR"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.inv.CheckItemTotals()
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("Invoice.CheckItemTotals() error = %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Invoice.CheckItemTotals() error = nil, want %q", tt.wantErr)
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Invoice.CheckItemTotals() error = %q, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
+
+func TestInvoice_Normalize_itemLines(t *testing.T) {
+	inv := &Invoice{
+		Subtotal: amount(100),
+		Tax:      amount(20),
+		Total:    amount(120),
+		Items: []*InvoiceItem{
+			{},
+			item(100, 20, 20),
+			{},
+			item(50, 20, 10),
+		},
+	}
+	err := inv.Normalize()
+	if err == nil || !strings.Contains(err.Error(), "lines 2, 4") {
+		t.Errorf("Invoice.Normalize() error = %v, want item lines 2, 4 of the document", err)
+	}
+	if len(inv.Items) != 2 {
+		t.Errorf("Invoice.Normalize() left %d items, want the 2 non-empty items", len(inv.Items))
+	}
+}
@@ -31,6 +31,14 @@ func (p PaymentStatus) IsPaid() bool {
 	return strings.HasPrefix(string(p), "PAID")
 }
 
+// IsCashless indicates if the invoice was paid to the bank account
+// of the payee by bank transfer or direct debit.
+// Payments by credit card or payment services are not included
+// because they are not accepted as cashless by the tax office.
+func (p PaymentStatus) IsCashless() bool {
+	return p == PaymentStatusPaidWithBankTransfer || p == PaymentStatusPaidWithDirectDebit
+}
+
 // Valid indicates if p is any of the valid values for PaymentStatus
 func (p PaymentStatus) Valid() bool {
 	switch p {
@@ -0,0 +1,149 @@
+package invoicing
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/domonda/go-types/money"
+	"github.com/domonda/go-types/nullable"
+)
+
+// TaxSubtotal is the taxable amount and tax amount
+// of an invoice for a single tax rate
+type TaxSubtotal struct {
+	// Tax percentage of the tax rate (valid range: 0-100)
+	TaxPercent money.NullableRate `json:"tax_percent,omitempty,omitzero"`
+	// Net amount taxed with the tax percentage
+	TaxableAmount money.Amount `json:"taxable_amount"`
+	// Tax amount of the taxable amount
+	TaxAmount money.Amount `json:"tax_amount"`
+	// Reason for a tax exemption if the tax percentage is zero
+	ExemptionReason nullable.TrimmedString `json:"exemption_reason,omitempty"`
+}
+
+// Normalize validates and normalizes all fields of the TaxSubtotal.
+// It returns an aggregated error of all validation issues found.
+// Invalid fields are either corrected (e.g., amounts become absolute and rounded)
+// or set to null/zero values. The tax subtotal remains usable after normalization,
+// with the returned error describing what was corrected.
+func (t *TaxSubtotal) Normalize() error {
+	if t == nil {
+		return nil
+	}
+	var result error
+	if t.TaxPercent.IsNotNull() && t.TaxPercent.Get() < 0 {
+		result = errors.Join(result, fmt.Errorf("tax percent %f is negative", t.TaxPercent.Get()))
+		t.TaxPercent.Set(t.TaxPercent.Get().Abs())
+	}
+	if t.TaxPercent.IsNotNull() && t.TaxPercent.Get() > 100 {
+		result = errors.Join(result, fmt.Errorf("tax percent %f is greater than 100%%", t.TaxPercent.Get()))
+		t.TaxPercent.SetNull()
+	}
+	t.TaxableAmount = t.TaxableAmount.Abs().RoundToCents()
+	t.TaxAmount = t.TaxAmount.Abs().RoundToCents()
+	if t.TaxPercent.IsNotNull() {
+		if expected := (t.TaxableAmount * money.Amount(t.TaxPercent.Get()) / 100).RoundToCents(); !expected.WithinOneCent(t.TaxAmount) {
+			result = errors.Join(result, fmt.Errorf("taxable amount %f × tax percent %f%% does not match tax amount %f, expected %f", t.TaxableAmount, t.TaxPercent.Get(), t.TaxAmount, expected))
+		}
+	}
+	return result
+}
+
+// CheckTaxBreakdown checks the TaxBreakdown against the Subtotal and Tax
+// of the invoice and against the Items summed up per tax percentage.
+// Items are only compared if all of them have a tax percentage
+// and a subtotal in the invoice currency.
+// An invoice discount is distributed proportionally to the tax rates
+// and a sum matches with or without the discount applied.
+func (inv *Invoice) CheckTaxBreakdown() error {
+	if inv == nil || len(inv.TaxBreakdown) == 0 {
+		return nil
+	}
+	var result error
+	var (
+		taxableSum money.Amount
+		taxSum     money.Amount
+		tolerance  = money.Amount(len(inv.TaxBreakdown)) * 0.01
+	)
+	for i, t := range inv.TaxBreakdown {
+		if t.TaxPercent.IsNull() {
+			result = errors.Join(result, fmt.Errorf("tax breakdown %d has no tax percent", i))
+		} else if slices.ContainsFunc(inv.TaxBreakdown[:i], func(other TaxSubtotal) bool { return other.TaxPercent == t.TaxPercent }) {
+			result = errors.Join(result, fmt.Errorf("tax breakdown contains tax percent %f%% more than once", t.TaxPercent.Get()))
+		}
+		if t.TaxPercent.IsNotNull() && t.TaxPercent.Get() == 0 && t.ExemptionReason.IsNull() && !inv.ReverseCharge {
+			result = errors.Join(result, fmt.Errorf("tax breakdown with zero tax percent has no exemption reason"))
+		}
+		taxableSum += t.TaxableAmount
+		taxSum += t.TaxAmount
+	}
+	if inv.Subtotal.IsNotNull() && !withinTolerance(taxableSum, inv.Subtotal.Get(), tolerance) {
+		result = errors.Join(result, fmt.Errorf("sum %f of tax breakdown taxable amounts does not match subtotal %f", taxableSum, inv.Subtotal.Get()))
+	}
+	if inv.Tax.IsNotNull() && !withinTolerance(taxSum, inv.Tax.Get(), tolerance) {
+		result = errors.Join(result, fmt.Errorf("sum %f of tax breakdown tax amounts does not match tax %f", taxSum, inv.Tax.Get()))
+	}
+	return errors.Join(result, inv.checkTaxBreakdownItems())
+}
+
+func (inv *Invoice) checkTaxBreakdownItems() (result error) {
+	type rateSums struct {
+		taxPercent money.Rate
+		lines      []string
+		subtotal   money.Amount
+	}
+	var (
+		perRate       []*rateSums
+		itemsSubtotal money.Amount
+	)
+	for i, item := range inv.Items {
+		if item == nil {
+			continue
+		}
+		if item.TaxPercent.IsNull() || item.Subtotal.IsNull() {
+			return nil
+		}
+		if item.Currency.IsNotNull() && inv.Currency.IsNotNull() && item.Currency.Get() != inv.Currency.Get() {
+			return nil
+		}
+		index := slices.IndexFunc(perRate, func(s *rateSums) bool { return s.taxPercent == item.TaxPercent.Get() })
+		if index == -1 {
+			index = len(perRate)
+			perRate = append(perRate, &rateSums{taxPercent: item.TaxPercent.Get()})
+		}
+		subtotal := inv.itemAmountSign(item, item.Subtotal.Get())
+		perRate[index].lines = append(perRate[index].lines, itemLine(i, item))
+		perRate[index].subtotal += subtotal
+		itemsSubtotal += subtotal
+	}
+	if len(perRate) == 0 {
+		return nil
+	}
+
+	// Fraction of the item subtotals that is deducted by the invoice discount
+	var discountFactor float64
+	if itemsSubtotal != 0 {
+		discountFactor = float64(inv.discountForItemsSubtotal(itemsSubtotal) / itemsSubtotal)
+	}
+	for _, sums := range perRate {
+		index := slices.IndexFunc(inv.TaxBreakdown, func(t TaxSubtotal) bool { return t.TaxPercent.IsNotNull() && t.TaxPercent.Get() == sums.taxPercent })
+		if index == -1 {
+			result = errors.Join(result, fmt.Errorf("tax percent %f%% of item %s is missing in tax breakdown", sums.taxPercent, itemLines(sums.lines)))
+			continue
+		}
+		taxable := inv.TaxBreakdown[index].TaxableAmount
+		tolerance := money.Amount(len(sums.lines)) * 0.01
+		discounted := money.Amount(math.Round(float64(sums.subtotal)*(1-discountFactor)*100) / 100)
+		if !withinTolerance(sums.subtotal, taxable, tolerance) && !withinTolerance(discounted, taxable, tolerance) {
+			result = errors.Join(result, fmt.Errorf("sum %f of item subtotals of %s does not match tax breakdown taxable amount %f for tax percent %f%%", sums.subtotal, itemLines(sums.lines), taxable, sums.taxPercent))
+		}
+	}
+	for _, t := range inv.TaxBreakdown {
+		if t.TaxPercent.IsNotNull() && !slices.ContainsFunc(perRate, func(s *rateSums) bool { return s.taxPercent == t.TaxPercent.Get() }) {
+			result = errors.Join(result, fmt.Errorf("tax breakdown tax percent %f%% is not used by any item", t.TaxPercent.Get()))
+		}
+	}
+	return result
+}
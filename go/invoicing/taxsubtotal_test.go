@@ -0,0 +1,129 @@
+package invoicing
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTaxSubtotal_Normalize(t *testing.T) {
+	tests := []struct {
+		name    string
+		t       TaxSubtotal
+		want    TaxSubtotal
+		wantErr string
+	}{
+		{
+			name: "valid",
+			t:    TaxSubtotal{TaxPercent: rate(20), TaxableAmount: 100, TaxAmount: 20},
+			want: TaxSubtotal{TaxPercent: rate(20), TaxableAmount: 100, TaxAmount: 20},
+		},
+		{
+			name: "negative values",
+			t:    TaxSubtotal{TaxPercent: rate(-20), TaxableAmount: -100, TaxAmount: -20},
+			want: TaxSubtotal{TaxPercent: rate(20), TaxableAmount: 100, TaxAmount: 20},
+			// Only the negative tax percent is reported
+			wantErr: "tax percent -20",
+		},
+		{
+			name:    "tax percent above 100",
+			t:       TaxSubtotal{TaxPercent: rate(200), TaxableAmount: 100, TaxAmount: 20},
+			want:    TaxSubtotal{TaxableAmount: 100, TaxAmount: 20},
+			wantErr: "tax percent 200.000000 is greater than 100%",
+		},
+		{
+			name:    "tax amount mismatch",
+			t:       TaxSubtotal{TaxPercent: rate(10), TaxableAmount: 100, TaxAmount: 20},
+			want:    TaxSubtotal{TaxPercent: rate(10), TaxableAmount: 100, TaxAmount: 20},
+			wantErr: "does not match tax amount 20",
+		},
+		{
+			name: "null tax percent",
+			t:    TaxSubtotal{TaxableAmount: 100, TaxAmount: 20},
+			want: TaxSubtotal{TaxableAmount: 100, TaxAmount: 20},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sub := tt.t
+			err := sub.Normalize()
+			switch {
+			case tt.wantErr == "" && err != nil:
+				t.Errorf("TaxSubtotal.Normalize() error = %v", err)
+			case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+				t.Errorf("TaxSubtotal.Normalize() error = %v, want %q", err, tt.wantErr)
+			}
+			if sub != tt.want {
+				t.Errorf("TaxSubtotal.Normalize() = %+v, want %+v", sub, tt.want)
+			}
+		})
+	}
+}
+
+func TestInvoice_CheckTaxBreakdown(t *testing.T) {
+	tests := []struct {
+		name    string
+		inv     *Invoice
+		wantErr []string
+	}{
+		{
+			name: "matching",
+			inv: &Invoice{
+				Subtotal:     amount(150),
+				Tax:          amount(25),
+				TaxBreakdown: []TaxSubtotal{{TaxPercent: rate(20), TaxableAmount: 100, TaxAmount: 20}, {TaxPercent: rate(10), TaxableAmount: 50, TaxAmount: 5}},
+				Items:        []*InvoiceItem{item(100, 20, 20), item(50, 10, 5)},
+			},
+		},
+		{
+			name: "null tax percent",
+			inv: &Invoice{
+				TaxBreakdown: []TaxSubtotal{{TaxableAmount: 100, TaxAmount: 20}},
+				Items:        []*InvoiceItem{item(100, 20, 20)},
+			},
+			wantErr: []string{"tax breakdown 0 has no tax percent", "tax percent 20.000000% of item line 1 is missing in tax breakdown"},
+		},
+		{
+			name: "duplicate tax percent",
+			inv: &Invoice{
+				TaxBreakdown: []TaxSubtotal{{TaxPercent: rate(20), TaxableAmount: 50, TaxAmount: 10}, {TaxPercent: rate(20), TaxableAmount: 50, TaxAmount: 10}},
+			},
+			wantErr: []string{"more than once"},
+		},
+		{
+			name: "zero tax percent without exemption reason",
+			inv: &Invoice{
+				TaxBreakdown: []TaxSubtotal{{TaxPercent: rate(0), TaxableAmount: 100}},
+			},
+			wantErr: []string{"no exemption reason"},
+		},
+		{
+			name: "sums mismatch",
+			inv: &Invoice{
+				Subtotal:     amount(150),
+				Tax:          amount(25),
+				TaxBreakdown: []TaxSubtotal{{TaxPercent: rate(20), TaxableAmount: 100, TaxAmount: 20}},
+				Items:        []*InvoiceItem{item(100, 20, 20), item(50, 10, 5)},
+			},
+			wantErr: []string{"does not match subtotal 150", "does not match tax 25", "tax percent 10.000000% of item line 2 is missing"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.inv.CheckTaxBreakdown()
+			if len(tt.wantErr) == 0 {
+				if err != nil {
+					t.Fatalf("Invoice.CheckTaxBreakdown() error = %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("Invoice.CheckTaxBreakdown() error = nil, want %q", tt.wantErr)
+			}
+			for _, want := range tt.wantErr {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("Invoice.CheckTaxBreakdown() error = %q, want it to contain %q", err, want)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package realestate
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/domonda/go-types/date"
+	"github.com/domonda/go-types/money"
+)
+
+// co2TenantTiers is the tier table of the CO2KostAufG (Anlage zu §§ 5 bis 7)
+// for residential buildings.
+// A tier applies to emissions per square meter living area and year
+// lower than its limit.
+var co2TenantTiers = []struct {
+	limit         float64
+	tenantPercent float64
+}{
+	{12, 100},
+	{17, 90},
+	{22, 80},
+	{27, 70},
+	{32, 60},
+	{37, 50},
+	{42, 40},
+	{47, 30},
+	{52, 20},
+	{math.Inf(1), 5},
+}
+
+// CO2NonResidentialTenantPercent is the tenant share of the CO2 costs
+// of non-residential buildings according to § 8 CO2KostAufG
+const CO2NonResidentialTenantPercent = 50
+
+// CO2CostSplit is the split of the CO2 costs of a heating invoice
+// between landlord and tenants according to the
+// Kohlendioxidkostenaufteilungsgesetz (CO2KostAufG)
+type CO2CostSplit struct {
+	// CO2 emissions in kilograms
+	CO2Emissions float64 `json:"co2_emissions"`
+	// CO2 costs to split
+	CO2Cost money.Amount `json:"co2_cost"`
+	// Living area of the building in square meters
+	LivingArea float64 `json:"living_area"`
+	// CO2 emissions in kilograms per square meter living area
+	EmissionsPerSquareMeter float64 `json:"emissions_per_square_meter"`
+	// Building is a non-residential building split 50/50
+	NonResidential bool `json:"non_residential,omitempty"`
+
+	TenantPercent   float64      `json:"tenant_percent"`
+	LandlordPercent float64      `json:"landlord_percent"`
+	TenantAmount    money.Amount `json:"tenant_amount"`
+	LandlordAmount  money.Amount `json:"landlord_amount"`
+}
+
+// NewCO2CostSplit splits the CO2 costs of a heating invoice
+// between landlord and tenants according to the tier table
+// of the CO2KostAufG for residential buildings,
+// or 50/50 for non-residential buildings.
+//
+// The emissions and costs must be for a billing period of one year
+// because the tiers are defined per square meter and year.
+// The amounts are rounded to cents and sum up exactly to the CO2 costs.
+func NewCO2CostSplit(co2Emissions float64, co2Cost money.Amount, livingArea float64, nonResidential bool) (*CO2CostSplit, error) {
+	if co2Emissions < 0 || math.IsNaN(co2Emissions) || math.IsInf(co2Emissions, 0) {
+		return nil, fmt.Errorf("invalid CO2 emissions %f", co2Emissions)
+	}
+	if co2Cost < 0 {
+		return nil, fmt.Errorf("CO2 cost %f is negative", co2Cost)
+	}
+	if livingArea <= 0 || math.IsNaN(livingArea) || math.IsInf(livingArea, 0) {
+		return nil, fmt.Errorf("invalid living area %f", livingArea)
+	}
+	split := &CO2CostSplit{
+		CO2Emissions:            co2Emissions,
+		CO2Cost:                 co2Cost.RoundToCents(),
+		LivingArea:              livingArea,
+		EmissionsPerSquareMeter: co2Emissions / livingArea,
+		NonResidential:          nonResidential,
+	}
+	if nonResidential {
+		split.TenantPercent = CO2NonResidentialTenantPercent
+	} else {
+		for _, tier := range co2TenantTiers {
+			if split.EmissionsPerSquareMeter < tier.limit {
+				split.TenantPercent = tier.tenantPercent
+				break
+			}
+		}
+	}
+	split.LandlordPercent = 100 - split.TenantPercent
+	if split.CO2Cost == 0 {
+		return split, nil
+	}
+	amounts, err := splitAmount(split.CO2Cost, []float64{split.TenantPercent, split.LandlordPercent})
+	if err != nil {
+		return nil, err
+	}
+	split.TenantAmount, split.LandlordAmount = amounts[0], amounts[1]
+	return split, nil
+}
+
+// CO2 cost splits require a billing period of one year,
+// with some days tolerance for readings not taken on the exact day
+const (
+	co2MinPeriodDays = 350
+	co2MaxPeriodDays = 380
+)
+
+// CO2CostSplit returns the split of the CO2Cost of the invoice
+// by its CO2Emissions using NewCO2CostSplit.
+// The living area of the identified object can be taken
+// from ObjectTree.CostAllocationTarget.
+// Returns an error if the invoice has no CO2 emissions or costs,
+// or if its PeriodStart or PeriodEnd is missing or the period
+// is not about one year, because the tiers are defined per year.
+func (inv *Invoice) CO2CostSplit(livingArea float64, nonResidential bool) (*CO2CostSplit, error) {
+	if inv == nil || inv.AccountingInvoice == nil {
+		return nil, errors.New("invoice is nil")
+	}
+	if inv.PeriodStart.IsNull() || inv.PeriodEnd.IsNull() {
+		return nil, errors.New("invoice has no billing period for the CO2 cost split")
+	}
+	days, ok := periodDays(inv.PeriodStart.Get(), inv.PeriodEnd.Get())
+	if !ok {
+		return nil, fmt.Errorf("invalid billing period from %s to %s", inv.PeriodStart.Get(), inv.PeriodEnd.Get())
+	}
+	if days < co2MinPeriodDays || days > co2MaxPeriodDays {
+		return nil, fmt.Errorf("billing period from %s to %s has %d days, but the CO2 cost split requires a period of one year", inv.PeriodStart.Get(), inv.PeriodEnd.Get(), days)
+	}
+	if inv.CO2Emissions.IsNull() {
+		return nil, errors.New("invoice has no CO2 emissions")
+	}
+	if inv.CO2Cost.IsNull() {
+		return nil, errors.New("invoice has no CO2 cost")
+	}
+	return NewCO2CostSplit(inv.CO2Emissions.Get(), inv.CO2Cost.Get(), livingArea, nonResidential)
+}
+
+// periodDays returns the number of days of the period
+// including the start and end date
+func periodDays(start, end date.Date) (int, bool) {
+	startTime, err := time.Parse(time.DateOnly, start.String())
+	if err != nil {
+		return 0, false
+	}
+	endTime, err := time.Parse(time.DateOnly, end.String())
+	if err != nil {
+		return 0, false
+	}
+	return int(endTime.Sub(startTime).Hours()/24) + 1, true
+}
@@ -0,0 +1,106 @@
+package realestate
+
+import (
+	"math"
+	"testing"
+
+	"github.com/domonda/go-types/date"
+	"github.com/domonda/go-types/money"
+
+	"github.com/docvibe-ai/api/go/invoicing"
+)
+
+func TestNewCO2CostSplit(t *testing.T) {
+	tests := []struct {
+		name           string
+		co2Emissions   float64
+		co2Cost        money.Amount
+		livingArea     float64
+		nonResidential bool
+		wantTenant     float64
+		wantTenantAmt  money.Amount
+		wantLandlord   money.Amount
+		wantErr        bool
+	}{
+		{name: "lowest tier", co2Emissions: 1100, co2Cost: 100, livingArea: 100, wantTenant: 100, wantTenantAmt: 100, wantLandlord: 0},
+		{name: "tier limit belongs to next tier", co2Emissions: 1200, co2Cost: 100, livingArea: 100, wantTenant: 90, wantTenantAmt: 90, wantLandlord: 10},
+		{name: "middle tier", co2Emissions: 3000, co2Cost: 200, livingArea: 100, wantTenant: 60, wantTenantAmt: 120, wantLandlord: 80},
+		{name: "highest tier", co2Emissions: 6000, co2Cost: 100, livingArea: 100, wantTenant: 5, wantTenantAmt: 5, wantLandlord: 95},
+		{name: "non-residential", co2Emissions: 6000, co2Cost: 100, livingArea: 100, nonResidential: true, wantTenant: 50, wantTenantAmt: 50, wantLandlord: 50},
+		{name: "amounts sum up to cost", co2Emissions: 3000, co2Cost: 0.05, livingArea: 100, wantTenant: 60, wantTenantAmt: 0.03, wantLandlord: 0.02},
+		{name: "negative emissions", co2Emissions: -1, co2Cost: 100, livingArea: 100, wantErr: true},
+		{name: "NaN emissions", co2Emissions: math.NaN(), co2Cost: 100, livingArea: 100, wantErr: true},
+		{name: "negative cost", co2Emissions: 1000, co2Cost: -1, livingArea: 100, wantErr: true},
+		{name: "zero living area", co2Emissions: 1000, co2Cost: 100, livingArea: 0, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			split, err := NewCO2CostSplit(tt.co2Emissions, tt.co2Cost, tt.livingArea, tt.nonResidential)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewCO2CostSplit() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if split.TenantPercent != tt.wantTenant || split.LandlordPercent != 100-tt.wantTenant {
+				t.Errorf("NewCO2CostSplit() percent = %v/%v, want %v/%v", split.TenantPercent, split.LandlordPercent, tt.wantTenant, 100-tt.wantTenant)
+			}
+			if split.TenantAmount != tt.wantTenantAmt || split.LandlordAmount != tt.wantLandlord {
+				t.Errorf("NewCO2CostSplit() amounts = %v/%v, want %v/%v", split.TenantAmount, split.LandlordAmount, tt.wantTenantAmt, tt.wantLandlord)
+			}
+		})
+	}
+}
+
+func TestInvoice_CO2CostSplit(t *testing.T) {
+	heatingInvoice := func(periodStart, periodEnd string) *Invoice {
+		inv := &Invoice{
+			AccountingInvoice: &invoicing.AccountingInvoice{
+				Invoice: invoicing.Invoice{
+					PeriodStart: date.NullableDate(periodStart),
+					PeriodEnd:   date.NullableDate(periodEnd),
+				},
+			},
+		}
+		inv.CO2Emissions.Set(3000)
+		inv.CO2Cost.Set(200)
+		return inv
+	}
+	tests := []struct {
+		name       string
+		inv        *Invoice
+		wantTenant money.Amount
+		wantErr    bool
+	}{
+		{name: "calendar year", inv: heatingInvoice("2024-01-01", "2024-12-31"), wantTenant: 120},
+		{name: "readings a few days off", inv: heatingInvoice("2024-01-05", "2024-12-28"), wantTenant: 120},
+		{name: "half year", inv: heatingInvoice("2024-01-01", "2024-06-30"), wantErr: true},
+		{name: "two years", inv: heatingInvoice("2023-01-01", "2024-12-31"), wantErr: true},
+		{name: "end before start", inv: heatingInvoice("2024-12-31", "2024-01-01"), wantErr: true},
+		{name: "missing period start", inv: heatingInvoice("", "2024-12-31"), wantErr: true},
+		{name: "missing period end", inv: heatingInvoice("2024-01-01", ""), wantErr: true},
+		{name: "no CO2 emissions", inv: func() *Invoice {
+			inv := heatingInvoice("2024-01-01", "2024-12-31")
+			inv.CO2Emissions.SetNull()
+			return inv
+		}(), wantErr: true},
+		{name: "no CO2 cost", inv: func() *Invoice {
+			inv := heatingInvoice("2024-01-01", "2024-12-31")
+			inv.CO2Cost.SetNull()
+			return inv
+		}(), wantErr: true},
+		{name: "no accounting invoice", inv: &Invoice{}, wantErr: true},
+		{name: "nil invoice", inv: nil, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			split, err := tt.inv.CO2CostSplit(100, false)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Invoice.CO2CostSplit() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && split.TenantAmount != tt.wantTenant {
+				t.Errorf("Invoice.CO2CostSplit() tenant amount = %v, want %v", split.TenantAmount, tt.wantTenant)
+			}
+		})
+	}
+}
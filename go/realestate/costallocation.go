@@ -0,0 +1,293 @@
+package realestate
+
+//go:generate go tool go-enum $GOFILE
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/domonda/go-types/money"
+	"github.com/invopop/jsonschema"
+
+	"github.com/docvibe-ai/api/go/invoicing"
+)
+
+// CostAllocationKey is the distribution key (Umlageschlüssel)
+// used to split costs across objects and units
+type CostAllocationKey string //#enum,jsonschema
+
+const (
+	CostAllocationKeyArea         CostAllocationKey = "AREA"          // Split by living or usable area
+	CostAllocationKeyUnits        CostAllocationKey = "UNITS"         // Split by number of units
+	CostAllocationKeyPersons      CostAllocationKey = "PERSONS"       // Split by number of persons
+	CostAllocationKeyConsumption  CostAllocationKey = "CONSUMPTION"   // Split by measured consumption
+	CostAllocationKeyFixedPercent CostAllocationKey = "FIXED_PERCENT" // Split by fixed percentages summing up to 100
+)
+
+// Valid indicates if k is any of the valid values for CostAllocationKey
+func (k CostAllocationKey) Valid() bool {
+	switch k {
+	case
+		CostAllocationKeyArea,
+		CostAllocationKeyUnits,
+		CostAllocationKeyPersons,
+		CostAllocationKeyConsumption,
+		CostAllocationKeyFixedPercent:
+		return true
+	}
+	return false
+}
+
+// Validate returns an error if k is none of the valid values for CostAllocationKey
+func (k CostAllocationKey) Validate() error {
+	if !k.Valid() {
+		return fmt.Errorf("invalid value %#v for type realestate.CostAllocationKey", k)
+	}
+	return nil
+}
+
+// Enums returns all valid values for CostAllocationKey
+func (CostAllocationKey) Enums() []CostAllocationKey {
+	return []CostAllocationKey{
+		CostAllocationKeyArea,
+		CostAllocationKeyUnits,
+		CostAllocationKeyPersons,
+		CostAllocationKeyConsumption,
+		CostAllocationKeyFixedPercent,
+	}
+}
+
+// EnumStrings returns all valid values for CostAllocationKey as strings
+func (CostAllocationKey) EnumStrings() []string {
+	return []string{
+		"AREA",
+		"UNITS",
+		"PERSONS",
+		"CONSUMPTION",
+		"FIXED_PERCENT",
+	}
+}
+
+// String implements the fmt.Stringer interface for CostAllocationKey
+func (k CostAllocationKey) String() string {
+	return string(k)
+}
+
+// JSONSchema returns a github.com/invopop/jsonschema.Schema for CostAllocationKey
+func (CostAllocationKey) JSONSchema() *jsonschema.Schema {
+	return &jsonschema.Schema{
+		Type: "string",
+		Enum: []any{
+			"AREA",
+			"UNITS",
+			"PERSONS",
+			"CONSUMPTION",
+			"FIXED_PERCENT",
+		},
+	}
+}
+
+// CostAllocationTarget is an object or unit that costs are allocated to
+// with its quantities for every CostAllocationKey
+type CostAllocationTarget struct {
+	// ID of the object or unit
+	ObjectID string `json:"object_id"`
+	// Living or usable area in square meters
+	Area float64 `json:"area,omitempty"`
+	// Number of units
+	Units float64 `json:"units,omitempty"`
+	// Number of persons
+	Persons float64 `json:"persons,omitempty"`
+	// Measured consumption in the unit of the cost type
+	Consumption float64 `json:"consumption,omitempty"`
+	// Fixed percentage of the costs
+	Percent float64 `json:"percent,omitempty"`
+}
+
+// Weight returns the quantity of the target for the key
+func (t *CostAllocationTarget) Weight(key CostAllocationKey) float64 {
+	switch key {
+	case CostAllocationKeyArea:
+		return t.Area
+	case CostAllocationKeyUnits:
+		return t.Units
+	case CostAllocationKeyPersons:
+		return t.Persons
+	case CostAllocationKeyConsumption:
+		return t.Consumption
+	case CostAllocationKeyFixedPercent:
+		return t.Percent
+	}
+	return 0
+}
+
+// CostAllocationRules define which CostAllocationKey
+// is used for the accounting entries of a general ledger account
+type CostAllocationRules struct {
+	// Key used for accounts without an account specific key
+	DefaultKey CostAllocationKey `json:"default_key"`
+	// Keys by general ledger account number
+	AccountKeys map[string]CostAllocationKey `json:"account_keys,omitempty"`
+}
+
+// Key returns the CostAllocationKey for the general ledger account number
+func (r *CostAllocationRules) Key(accountNumber string) CostAllocationKey {
+	if key, ok := r.AccountKeys[accountNumber]; ok {
+		return key
+	}
+	return r.DefaultKey
+}
+
+// Validate returns an error if the rules contain invalid keys
+func (r *CostAllocationRules) Validate() error {
+	result := r.DefaultKey.Validate()
+	for account, key := range r.AccountKeys {
+		if err := key.Validate(); err != nil {
+			result = errors.Join(result, fmt.Errorf("account %s: %w", account, err))
+		}
+	}
+	return result
+}
+
+// CostPosting is the share of an accounting entry allocated to an object
+type CostPosting struct {
+	// ID of the object or unit
+	ObjectID string `json:"object_id"`
+	// Key that was used for the allocation
+	Key CostAllocationKey `json:"key"`
+	// Fraction of the accounting entry allocated to the object
+	Fraction float64 `json:"fraction"`
+
+	Type                            invoicing.AccountingEntryType `json:"type"`
+	GeneralLedgerAccountNumber      string                        `json:"general_ledger_account_number"`
+	GeneralLedgerAccountDescription string                        `json:"general_ledger_account_description,omitempty"`
+	Amount                          money.Amount                  `json:"amount"`
+	TaxAmount                       money.Amount                  `json:"tax_amount,omitempty"`
+	TaxPercent                      money.Rate                    `json:"tax_percent,omitempty"`
+	BookingText                     string                        `json:"booking_text"`
+}
+
+// AllocateAccountingEntry splits the Amount and TaxAmount of the entry
+// across the targets proportionally to their weights for the key.
+// The amounts of the returned postings are rounded to cents
+// and sum up exactly to the amounts of the entry.
+// For CostAllocationKeyFixedPercent the percentages of the targets
+// must sum up to 100.
+func AllocateAccountingEntry(entry *invoicing.AccountingEntry, targets []CostAllocationTarget, key CostAllocationKey) ([]*CostPosting, error) {
+	if entry == nil {
+		return nil, errors.New("accounting entry is nil")
+	}
+	weights, err := costAllocationWeights(targets, key)
+	if err != nil {
+		return nil, err
+	}
+	amounts, err := splitAmount(entry.Amount, weights)
+	if err != nil {
+		return nil, err
+	}
+	taxAmounts, err := splitAmount(entry.TaxAmount.Get(), weights)
+	if err != nil {
+		return nil, err
+	}
+	var weightSum float64
+	for _, w := range weights {
+		weightSum += w
+	}
+	postings := make([]*CostPosting, len(targets))
+	for i, target := range targets {
+		postings[i] = &CostPosting{
+			ObjectID:                        target.ObjectID,
+			Key:                             key,
+			Fraction:                        weights[i] / weightSum,
+			Type:                            entry.Type,
+			GeneralLedgerAccountNumber:      entry.GeneralLedgerAccountNumber.String(),
+			GeneralLedgerAccountDescription: entry.GeneralLedgerAccountDescription.String(),
+			Amount:                          amounts[i],
+			TaxAmount:                       taxAmounts[i],
+			TaxPercent:                      entry.TaxPercent.Get(),
+			BookingText:                     entry.BookingText.String(),
+		}
+	}
+	return postings, nil
+}
+
+// AllocateCosts splits the cost entries of the invoice
+// across the targets using the key of the rules
+// for the general ledger account of every entry.
+//
+// Cost entries are all accounting entries except the partner entries.
+// Partner entries are the entries booked to the PartnerAccountNumber,
+// or without a partner account number the entries
+// on the side expected for the partner account.
+func (inv *Invoice) AllocateCosts(targets []CostAllocationTarget, rules *CostAllocationRules) ([]*CostPosting, error) {
+	if inv == nil || inv.AccountingInvoice == nil {
+		return nil, errors.New("missing accounting invoice")
+	}
+	if rules == nil {
+		return nil, errors.New("missing cost allocation rules")
+	}
+	if err := rules.Validate(); err != nil {
+		return nil, err
+	}
+	partnerType, typeKnown := inv.PartnerEntryType()
+	if inv.PartnerAccountNumber.IsNull() && !typeKnown {
+		return nil, fmt.Errorf("can't identify partner entries of invoice %s without partner account number and invoice type", inv.InvoiceID.String())
+	}
+	var postings []*CostPosting
+	for i, entry := range inv.AccountingEntries {
+		if entry == nil {
+			continue
+		}
+		if inv.PartnerAccountNumber.IsNotNull() {
+			if entry.GeneralLedgerAccountNumber.String() == inv.PartnerAccountNumber.String() {
+				continue
+			}
+		} else if entry.Type == partnerType {
+			continue
+		}
+		entryPostings, err := AllocateAccountingEntry(entry, targets, rules.Key(entry.GeneralLedgerAccountNumber.String()))
+		if err != nil {
+			return nil, fmt.Errorf("can't allocate accounting entry %d: %w", i, err)
+		}
+		postings = append(postings, entryPostings...)
+	}
+	return postings, nil
+}
+
+func costAllocationWeights(targets []CostAllocationTarget, key CostAllocationKey) ([]float64, error) {
+	if err := key.Validate(); err != nil {
+		return nil, err
+	}
+	if len(targets) == 0 {
+		return nil, errors.New("no cost allocation targets")
+	}
+	weights := make([]float64, len(targets))
+	var weightSum float64
+	for i := range targets {
+		weights[i] = targets[i].Weight(key)
+		if weights[i] < 0 {
+			return nil, fmt.Errorf("cost allocation target %s has negative %s", targets[i].ObjectID, key)
+		}
+		weightSum += weights[i]
+	}
+	if weightSum <= 0 {
+		return nil, fmt.Errorf("cost allocation targets have no %s to allocate by", key)
+	}
+	if key == CostAllocationKeyFixedPercent && math.Abs(weightSum-100) > 0.0001 {
+		return nil, fmt.Errorf("fixed percentages of cost allocation targets sum up to %f instead of 100", weightSum)
+	}
+	return weights, nil
+}
+
+// CostAllocationTarget returns the target for the object with the passed ID
+// with the summed up Area of its allocatable units
+// and the number of allocatable units as Units.
+func (t *ObjectTree) CostAllocationTarget(id string) CostAllocationTarget {
+	target := CostAllocationTarget{ObjectID: id}
+	for _, unit := range t.AllocatableUnits(id) {
+		target.Area += unit.Area.Get()
+		target.Units++
+	}
+	return target
+}
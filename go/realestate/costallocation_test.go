@@ -0,0 +1,188 @@
+package realestate
+
+import (
+	"math"
+	"slices"
+	"testing"
+
+	"github.com/domonda/go-types/money"
+	"github.com/domonda/go-types/notnull"
+	"github.com/domonda/go-types/nullable"
+
+	"github.com/docvibe-ai/api/go/invoicing"
+)
+
+func TestSplitAmount(t *testing.T) {
+	tests := []struct {
+		name    string
+		amount  money.Amount
+		weights []float64
+		want    []money.Amount
+		wantErr bool
+	}{
+		{name: "even", amount: 100, weights: []float64{1, 1}, want: []money.Amount{50, 50}},
+		{name: "remaining cent to first of equal remainders", amount: 100, weights: []float64{1, 1, 1}, want: []money.Amount{33.34, 33.33, 33.33}},
+		{name: "remaining cents to largest remainders", amount: 0.05, weights: []float64{1, 2, 2}, want: []money.Amount{0.01, 0.02, 0.02}},
+		{name: "proportional", amount: 10, weights: []float64{75, 25}, want: []money.Amount{7.5, 2.5}},
+		{name: "negative amount", amount: -1, weights: []float64{1, 1, 1}, want: []money.Amount{-0.34, -0.33, -0.33}},
+		{name: "zero weight", amount: 1, weights: []float64{0, 1}, want: []money.Amount{0, 1}},
+		{name: "amount rounded to cents", amount: 1.006, weights: []float64{1}, want: []money.Amount{1.01}},
+		{name: "sum of weights zero", amount: 1, weights: []float64{0, 0}, wantErr: true},
+		{name: "no weights", amount: 1, wantErr: true},
+		{name: "negative weight", amount: 1, weights: []float64{2, -1}, wantErr: true},
+		{name: "NaN weight", amount: 1, weights: []float64{math.NaN()}, wantErr: true},
+		{name: "infinite weight", amount: 1, weights: []float64{math.Inf(1), 1}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := splitAmount(tt.amount, tt.weights)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("splitAmount() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("splitAmount() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAllocateAccountingEntry(t *testing.T) {
+	entry := func(net, tax float64) *invoicing.AccountingEntry {
+		return &invoicing.AccountingEntry{
+			Type:                       invoicing.AccountingEntryTypeDebit,
+			GeneralLedgerAccountNumber: "5000",
+			Amount:                     money.Amount(net),
+			TaxAmount:                  amount(tax),
+			TaxPercent:                 rate(19),
+			BookingText:                "Hausreinigung",
+		}
+	}
+	targets := []CostAllocationTarget{
+		{ObjectID: "top-1", Area: 50, Units: 1, Persons: 2, Percent: 70},
+		{ObjectID: "top-2", Area: 25, Units: 1, Persons: 1, Percent: 20},
+		{ObjectID: "top-3", Area: 25, Units: 1, Persons: 0, Percent: 10},
+	}
+	tests := []struct {
+		name           string
+		entry          *invoicing.AccountingEntry
+		targets        []CostAllocationTarget
+		key            CostAllocationKey
+		wantAmounts    []money.Amount
+		wantTaxAmounts []money.Amount
+		wantErr        bool
+	}{
+		{name: "area", entry: entry(100, 19), targets: targets, key: CostAllocationKeyArea, wantAmounts: []money.Amount{50, 25, 25}, wantTaxAmounts: []money.Amount{9.5, 4.75, 4.75}},
+		{name: "units sum up exactly", entry: entry(100, 19), targets: targets, key: CostAllocationKeyUnits, wantAmounts: []money.Amount{33.34, 33.33, 33.33}, wantTaxAmounts: []money.Amount{6.34, 6.33, 6.33}},
+		{name: "zero weight target", entry: entry(10, 0), targets: targets, key: CostAllocationKeyPersons, wantAmounts: []money.Amount{6.67, 3.33, 0}, wantTaxAmounts: []money.Amount{0, 0, 0}},
+		{name: "negative amounts", entry: entry(-100, -19), targets: targets, key: CostAllocationKeyUnits, wantAmounts: []money.Amount{-33.34, -33.33, -33.33}, wantTaxAmounts: []money.Amount{-6.34, -6.33, -6.33}},
+		{name: "fixed percent", entry: entry(100, 19), targets: targets, key: CostAllocationKeyFixedPercent, wantAmounts: []money.Amount{70, 20, 10}, wantTaxAmounts: []money.Amount{13.3, 3.8, 1.9}},
+		{name: "fixed percent not summing up to 100", entry: entry(100, 19), targets: targets[:2], key: CostAllocationKeyFixedPercent, wantErr: true},
+		{name: "zero weights", entry: entry(100, 19), targets: targets, key: CostAllocationKeyConsumption, wantErr: true},
+		{name: "negative weight", entry: entry(100, 19), targets: []CostAllocationTarget{{ObjectID: "top-1", Area: -1}, {ObjectID: "top-2", Area: 2}}, key: CostAllocationKeyArea, wantErr: true},
+		{name: "invalid key", entry: entry(100, 19), targets: targets, key: "INVALID", wantErr: true},
+		{name: "no targets", entry: entry(100, 19), key: CostAllocationKeyArea, wantErr: true},
+		{name: "nil entry", targets: targets, key: CostAllocationKeyArea, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			postings, err := AllocateAccountingEntry(tt.entry, tt.targets, tt.key)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("AllocateAccountingEntry() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			var amounts, taxAmounts []money.Amount
+			var fractionSum float64
+			for i, posting := range postings {
+				amounts = append(amounts, posting.Amount)
+				taxAmounts = append(taxAmounts, posting.TaxAmount)
+				fractionSum += posting.Fraction
+				if posting.ObjectID != tt.targets[i].ObjectID || posting.Key != tt.key || posting.GeneralLedgerAccountNumber != "5000" || posting.TaxPercent != 19 {
+					t.Errorf("AllocateAccountingEntry() posting %d = %+v", i, posting)
+				}
+			}
+			if !slices.Equal(amounts, tt.wantAmounts) || !slices.Equal(taxAmounts, tt.wantTaxAmounts) {
+				t.Errorf("AllocateAccountingEntry() amounts = %v, tax amounts = %v, want %v, %v", amounts, taxAmounts, tt.wantAmounts, tt.wantTaxAmounts)
+			}
+			if math.Abs(fractionSum-1) > 1e-9 {
+				t.Errorf("AllocateAccountingEntry() fractions sum up to %f, want 1", fractionSum)
+			}
+		})
+	}
+}
+
+func TestInvoice_AllocateCosts(t *testing.T) {
+	entry := func(entryType invoicing.AccountingEntryType, account string, amount float64) *invoicing.AccountingEntry {
+		return &invoicing.AccountingEntry{
+			Type:                       entryType,
+			GeneralLedgerAccountNumber: notnull.TrimmedString(account),
+			Amount:                     money.Amount(amount),
+			BookingText:                "Betriebskosten",
+		}
+	}
+	entries := []*invoicing.AccountingEntry{
+		entry(invoicing.AccountingEntryTypeCredit, "33000", 150),
+		entry(invoicing.AccountingEntryTypeDebit, "5000", 100),
+		nil,
+		entry(invoicing.AccountingEntryTypeDebit, "6000", 50),
+	}
+	invoice := func(invoiceType invoicing.InvoiceType, partnerAccount string) *Invoice {
+		return &Invoice{
+			AccountingInvoice: &invoicing.AccountingInvoice{
+				Invoice:              invoicing.Invoice{InvoiceID: "RE-1", Type: invoiceType},
+				PartnerAccountNumber: nullable.TrimmedString(partnerAccount),
+				AccountingEntries:    entries,
+			},
+		}
+	}
+	targets := []CostAllocationTarget{
+		{ObjectID: "top-1", Area: 75, Units: 1},
+		{ObjectID: "top-2", Area: 25, Units: 1},
+	}
+	rules := &CostAllocationRules{
+		DefaultKey:  CostAllocationKeyArea,
+		AccountKeys: map[string]CostAllocationKey{"6000": CostAllocationKeyUnits},
+	}
+	type posting struct {
+		objectID string
+		account  string
+		key      CostAllocationKey
+		amount   money.Amount
+	}
+	wantPostings := []posting{
+		{"top-1", "5000", CostAllocationKeyArea, 75},
+		{"top-2", "5000", CostAllocationKeyArea, 25},
+		{"top-1", "6000", CostAllocationKeyUnits, 25},
+		{"top-2", "6000", CostAllocationKeyUnits, 25},
+	}
+	tests := []struct {
+		name    string
+		invoice *Invoice
+		rules   *CostAllocationRules
+		want    []posting
+		wantErr bool
+	}{
+		{name: "partner account number", invoice: invoice("", "33000"), rules: rules, want: wantPostings},
+		{name: "partner entry type of incoming invoice", invoice: invoice(invoicing.InvoiceTypeIncoming, ""), rules: rules, want: wantPostings},
+		{name: "partner entries not identifiable", invoice: invoice("", ""), rules: rules, wantErr: true},
+		{name: "invalid rules", invoice: invoice("", "33000"), rules: &CostAllocationRules{DefaultKey: "INVALID"}, wantErr: true},
+		{name: "missing rules", invoice: invoice("", "33000"), wantErr: true},
+		{name: "missing accounting invoice", invoice: &Invoice{}, rules: rules, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			postings, err := tt.invoice.AllocateCosts(targets, tt.rules)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Invoice.AllocateCosts() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var got []posting
+			for _, p := range postings {
+				got = append(got, posting{p.ObjectID, p.GeneralLedgerAccountNumber, p.Key, p.Amount})
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("Invoice.AllocateCosts() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
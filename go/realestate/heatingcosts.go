@@ -0,0 +1,265 @@
+package realestate
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/domonda/go-types/money"
+	"github.com/domonda/go-types/nullable"
+)
+
+const (
+	// HeatingMinConsumptionPercent is the minimum share of the heating costs
+	// allocated by consumption according to § 7 Heizkostenverordnung
+	HeatingMinConsumptionPercent = 50
+	// HeatingMaxConsumptionPercent is the maximum share of the heating costs
+	// allocated by consumption according to § 7 Heizkostenverordnung
+	HeatingMaxConsumptionPercent = 70
+	// HeatingMaxEstimatedAreaPercent is the maximum share of the area
+	// with estimated consumption according to § 9a Heizkostenverordnung.
+	// If more area has estimated consumption, all costs are allocated by area.
+	HeatingMaxEstimatedAreaPercent = 25
+)
+
+// HeatingCostUnit is a unit that heating costs are allocated to
+type HeatingCostUnit struct {
+	// ID of the unit
+	UnitID string `json:"unit_id"`
+	// Heated living or usable area in square meters
+	Area float64 `json:"area"`
+	// Heat meters or heat cost allocators of the unit
+	Meters []*Meter `json:"meters,omitempty"`
+	// Estimated consumption used if no meter of the unit
+	// has a consumption, like for missing readings.
+	// Must be in kWh or in heat cost allocator units
+	// like the measured consumption of the other units.
+	EstimatedConsumption nullable.Type[float64] `json:"estimated_consumption,omitempty,omitzero"`
+}
+
+// Consumption returns the summed up consumption of the meters of the unit
+// in kWh, or in heat cost allocator units if the unit is ConsumptionUnitUnits.
+// Meter consumptions in other units are converted to kWh
+// using their ConsumptionKWh, ConversionFactor or the KWhFactor of their unit.
+// If no meter has a consumption or readings to calculate it from,
+// then the EstimatedConsumption is returned with an empty unit and true for estimated.
+// Returns an error if neither is available, if a meter consumption
+// can't be converted to kWh, or if the meters mix kWh
+// with heat cost allocator units.
+func (u *HeatingCostUnit) Consumption() (consumption float64, unit ConsumptionUnit, estimated bool, err error) {
+	for _, meter := range u.Meters {
+		if meter == nil {
+			continue
+		}
+		meterConsumption, meterUnit, ok, err := meter.heatingConsumption()
+		if err != nil {
+			return 0, "", false, err
+		}
+		if !ok {
+			continue
+		}
+		if unit != "" && meterUnit != unit {
+			return 0, "", false, fmt.Errorf("unit %s has meters with consumption in %s and %s", u.UnitID, unit, meterUnit)
+		}
+		unit = meterUnit
+		consumption += meterConsumption
+	}
+	if unit != "" {
+		return consumption, unit, false, nil
+	}
+	if u.EstimatedConsumption.IsNotNull() {
+		return u.EstimatedConsumption.Get(), "", true, nil
+	}
+	return 0, "", false, fmt.Errorf("unit %s has neither measured nor estimated consumption", u.UnitID)
+}
+
+// heatingConsumption returns the consumption of the meter in kWh,
+// or in heat cost allocator units for ConsumptionUnitUnits,
+// or false if the meter has no consumption or readings to calculate it from.
+func (m *Meter) heatingConsumption() (consumption float64, unit ConsumptionUnit, ok bool, err error) {
+	if m.Unit != ConsumptionUnitUnits && m.ConsumptionKWh.IsNotNull() {
+		return m.ConsumptionKWh.Get(), ConsumptionUnitKWh, true, nil
+	}
+	consumption, ok = m.Consumption.Get(), m.Consumption.IsNotNull()
+	if !ok {
+		consumption, ok = m.readingsConsumption()
+		if !ok {
+			return 0, "", false, nil
+		}
+		if consumption < 0 {
+			return 0, "", false, fmt.Errorf("last reading of meter %s is lower than first reading", m.ID)
+		}
+	}
+	if m.Unit == ConsumptionUnitUnits {
+		return consumption, ConsumptionUnitUnits, true, nil
+	}
+	factor, hasFactor := m.ConversionFactor.Get(), m.ConversionFactor.IsNotNull()
+	if !hasFactor {
+		factor, hasFactor = m.Unit.KWhFactor()
+	}
+	if !hasFactor {
+		return 0, "", false, fmt.Errorf("consumption of meter %s in %q can't be converted to kWh without conversion factor", m.ID, m.Unit)
+	}
+	return consumption * factor, ConsumptionUnitKWh, true, nil
+}
+
+// HeatingCostLine is the share of a unit of the heating costs
+type HeatingCostLine struct {
+	UnitID string  `json:"unit_id"`
+	Area   float64 `json:"area"`
+	// Measured or estimated consumption of the unit
+	// in the ConsumptionUnit of the allocation
+	Consumption float64 `json:"consumption"`
+	// Consumption is estimated
+	Estimated bool `json:"estimated,omitempty"`
+	// Costs allocated by area (Grundkosten)
+	AreaCost money.Amount `json:"area_cost"`
+	// Costs allocated by consumption (Verbrauchskosten)
+	ConsumptionCost money.Amount `json:"consumption_cost"`
+	// Sum of AreaCost and ConsumptionCost
+	Total money.Amount `json:"total"`
+}
+
+// HeatingCostAllocation is the allocation of heating costs
+// to units according to the Heizkostenverordnung
+type HeatingCostAllocation struct {
+	// Heating costs to allocate
+	TotalCost money.Amount `json:"total_cost"`
+	// Percentage of the costs allocated by consumption
+	ConsumptionPercent float64 `json:"consumption_percent"`
+	// All costs are allocated by area because the area
+	// with estimated consumption exceeds HeatingMaxEstimatedAreaPercent
+	AreaOnly bool `json:"area_only,omitempty"`
+	// Unit of the measured consumption of all units,
+	// ConsumptionUnitKWh or ConsumptionUnitUnits for heat cost allocators,
+	// empty if all consumption is estimated
+	ConsumptionUnit ConsumptionUnit `json:"consumption_unit,omitempty"`
+
+	TotalArea        float64      `json:"total_area"`
+	TotalConsumption float64      `json:"total_consumption"`
+	AreaCost         money.Amount `json:"area_cost"`
+	ConsumptionCost  money.Amount `json:"consumption_cost"`
+
+	Lines []HeatingCostLine `json:"lines"`
+}
+
+// NewHeatingCostAllocation allocates the heating costs to the units
+// according to the Heizkostenverordnung.
+// The consumptionPercent of the costs between HeatingMinConsumptionPercent
+// and HeatingMaxConsumptionPercent is allocated by the consumption
+// of the units, the rest by their area.
+//
+// The measured consumption of all units must either be in kWh
+// or in heat cost allocator units, see HeatingCostUnit.Consumption.
+// Units without measured consumption use their estimated consumption.
+// If the area of units with estimated consumption exceeds
+// HeatingMaxEstimatedAreaPercent of the total area,
+// then all costs are allocated by area.
+//
+// All amounts are rounded to cents and the lines sum up
+// exactly to the total costs.
+func NewHeatingCostAllocation(totalCost money.Amount, consumptionPercent float64, units []HeatingCostUnit) (*HeatingCostAllocation, error) {
+	if consumptionPercent < HeatingMinConsumptionPercent || consumptionPercent > HeatingMaxConsumptionPercent {
+		return nil, fmt.Errorf("consumption percent %f is not between %d and %d", consumptionPercent, HeatingMinConsumptionPercent, HeatingMaxConsumptionPercent)
+	}
+	if len(units) == 0 {
+		return nil, errors.New("no units to allocate heating costs to")
+	}
+	a := &HeatingCostAllocation{
+		TotalCost:          totalCost.RoundToCents(),
+		ConsumptionPercent: consumptionPercent,
+		Lines:              make([]HeatingCostLine, len(units)),
+	}
+	var (
+		err           error
+		estimatedArea float64
+		areas         = make([]float64, len(units))
+		consumptions  = make([]float64, len(units))
+	)
+	for i := range units {
+		unit := &units[i]
+		if unit.Area < 0 || math.IsNaN(unit.Area) || math.IsInf(unit.Area, 0) {
+			return nil, fmt.Errorf("unit %s has invalid area %f", unit.UnitID, unit.Area)
+		}
+		line := &a.Lines[i]
+		line.UnitID = unit.UnitID
+		line.Area = unit.Area
+		var consumptionUnit ConsumptionUnit
+		line.Consumption, consumptionUnit, line.Estimated, err = unit.Consumption()
+		if err != nil {
+			return nil, err
+		}
+		if consumptionUnit != "" {
+			if a.ConsumptionUnit != "" && consumptionUnit != a.ConsumptionUnit {
+				return nil, fmt.Errorf("unit %s has consumption in %s but other units in %s", unit.UnitID, consumptionUnit, a.ConsumptionUnit)
+			}
+			a.ConsumptionUnit = consumptionUnit
+		}
+		if line.Consumption < 0 {
+			return nil, fmt.Errorf("unit %s has negative consumption %f", unit.UnitID, line.Consumption)
+		}
+		if line.Estimated {
+			estimatedArea += unit.Area
+		}
+		areas[i] = unit.Area
+		consumptions[i] = line.Consumption
+		a.TotalArea += unit.Area
+		a.TotalConsumption += line.Consumption
+	}
+	if a.TotalArea <= 0 {
+		return nil, errors.New("units have no area to allocate by")
+	}
+	if estimatedArea > a.TotalArea*HeatingMaxEstimatedAreaPercent/100 {
+		a.AreaOnly = true
+	}
+	if a.TotalConsumption <= 0 && !a.AreaOnly {
+		return nil, errors.New("units have no consumption to allocate by")
+	}
+
+	a.AreaCost = a.TotalCost
+	if !a.AreaOnly {
+		parts, err := splitAmount(a.TotalCost, []float64{100 - consumptionPercent, consumptionPercent})
+		if err != nil {
+			return nil, err
+		}
+		a.AreaCost, a.ConsumptionCost = parts[0], parts[1]
+	}
+	areaCosts, err := splitAmount(a.AreaCost, areas)
+	if err != nil {
+		return nil, err
+	}
+	consumptionCosts := make([]money.Amount, len(units))
+	if !a.AreaOnly {
+		consumptionCosts, err = splitAmount(a.ConsumptionCost, consumptions)
+		if err != nil {
+			return nil, err
+		}
+	}
+	for i := range a.Lines {
+		line := &a.Lines[i]
+		line.AreaCost = areaCosts[i]
+		line.ConsumptionCost = consumptionCosts[i]
+		line.Total = line.AreaCost + line.ConsumptionCost
+	}
+	return a, nil
+}
+
+// HeatingCostUnits returns the allocatable units of the object
+// with the passed ID as HeatingCostUnits with their Area
+// and the meters assigned to them by Meter.ObjectID.
+func (t *ObjectTree) HeatingCostUnits(id string, meters []*Meter) []HeatingCostUnit {
+	var result []HeatingCostUnit
+	for _, unit := range t.AllocatableUnits(id) {
+		heatingUnit := HeatingCostUnit{
+			UnitID: unit.ID.String(),
+			Area:   unit.Area.Get(),
+		}
+		for _, meter := range meters {
+			if meter != nil && meter.ObjectID.String() == heatingUnit.UnitID {
+				heatingUnit.Meters = append(heatingUnit.Meters, meter)
+			}
+		}
+		result = append(result, heatingUnit)
+	}
+	return result
+}
@@ -0,0 +1,169 @@
+package realestate
+
+import (
+	"testing"
+
+	"github.com/domonda/go-types/date"
+	"github.com/domonda/go-types/money"
+	"github.com/domonda/go-types/notnull"
+)
+
+// heatMeter returns a meter of the unit with the passed consumption
+func heatMeter(id string, unit ConsumptionUnit, consumption float64) *Meter {
+	m := &Meter{ID: notnull.TrimmedString(id), Unit: unit}
+	m.Consumption.Set(consumption)
+	return m
+}
+
+func TestHeatingCostUnit_Consumption(t *testing.T) {
+	gasMeter := heatMeter("gas", ConsumptionUnitM3, 100)
+	gasMeter.ConversionFactor.Set(10)
+	readingsMeter := &Meter{
+		ID:   "readings",
+		Unit: ConsumptionUnitKWh,
+		Readings: []*MeterReading{
+			{Date: date.NullableDate("2024-01-01"), Value: 1000},
+			{Date: date.NullableDate("2024-12-31"), Value: 1600},
+		},
+	}
+	kwhMeter := &Meter{ID: "kwh", Unit: ConsumptionUnitLiter}
+	kwhMeter.ConsumptionKWh.Set(250)
+	decreasingMeter := &Meter{
+		ID:   "decreasing",
+		Unit: ConsumptionUnitKWh,
+		Readings: []*MeterReading{
+			{Date: date.NullableDate("2024-01-01"), Value: 1000},
+			{Date: date.NullableDate("2024-12-31"), Value: 900},
+		},
+	}
+	estimated := HeatingCostUnit{UnitID: "estimated", Meters: []*Meter{{ID: "empty", Unit: ConsumptionUnitKWh}}}
+	estimated.EstimatedConsumption.Set(42)
+
+	tests := []struct {
+		name          string
+		unit          HeatingCostUnit
+		wantValue     float64
+		wantUnit      ConsumptionUnit
+		wantEstimated bool
+		wantErr       bool
+	}{
+		{name: "kWh and MWh summed in kWh", unit: HeatingCostUnit{UnitID: "1", Meters: []*Meter{heatMeter("a", ConsumptionUnitKWh, 500), heatMeter("b", ConsumptionUnitMWh, 1.5)}}, wantValue: 2000, wantUnit: ConsumptionUnitKWh},
+		{name: "conversion factor", unit: HeatingCostUnit{UnitID: "1", Meters: []*Meter{gasMeter}}, wantValue: 1000, wantUnit: ConsumptionUnitKWh},
+		{name: "consumption in kWh", unit: HeatingCostUnit{UnitID: "1", Meters: []*Meter{kwhMeter}}, wantValue: 250, wantUnit: ConsumptionUnitKWh},
+		{name: "readings", unit: HeatingCostUnit{UnitID: "1", Meters: []*Meter{readingsMeter, nil}}, wantValue: 600, wantUnit: ConsumptionUnitKWh},
+		{name: "heat cost allocators", unit: HeatingCostUnit{UnitID: "1", Meters: []*Meter{heatMeter("a", ConsumptionUnitUnits, 120), heatMeter("b", ConsumptionUnitUnits, 80)}}, wantValue: 200, wantUnit: ConsumptionUnitUnits},
+		{name: "estimated", unit: estimated, wantValue: 42, wantEstimated: true},
+		{name: "mixed kWh and allocator units", unit: HeatingCostUnit{UnitID: "1", Meters: []*Meter{heatMeter("a", ConsumptionUnitKWh, 500), heatMeter("b", ConsumptionUnitUnits, 80)}}, wantErr: true},
+		{name: "m³ without conversion factor", unit: HeatingCostUnit{UnitID: "1", Meters: []*Meter{heatMeter("a", ConsumptionUnitM3, 100)}}, wantErr: true},
+		{name: "decreasing readings", unit: HeatingCostUnit{UnitID: "1", Meters: []*Meter{decreasingMeter}}, wantErr: true},
+		{name: "no consumption", unit: HeatingCostUnit{UnitID: "1"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, unit, estimated, err := tt.unit.Consumption()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("HeatingCostUnit.Consumption() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !approxEqual(value, tt.wantValue) || unit != tt.wantUnit || estimated != tt.wantEstimated {
+				t.Errorf("HeatingCostUnit.Consumption() = %v, %q, %v, want %v, %q, %v", value, unit, estimated, tt.wantValue, tt.wantUnit, tt.wantEstimated)
+			}
+		})
+	}
+}
+
+func TestNewHeatingCostAllocation(t *testing.T) {
+	estimatedUnit := func(id string, area, consumption float64) HeatingCostUnit {
+		u := HeatingCostUnit{UnitID: id, Area: area}
+		u.EstimatedConsumption.Set(consumption)
+		return u
+	}
+	tests := []struct {
+		name               string
+		consumptionPercent float64
+		units              []HeatingCostUnit
+		wantUnit           ConsumptionUnit
+		wantAreaOnly       bool
+		wantTotals         []money.Amount
+		wantErr            bool
+	}{
+		{
+			name:               "kWh meters",
+			consumptionPercent: 50,
+			units: []HeatingCostUnit{
+				{UnitID: "1", Area: 50, Meters: []*Meter{heatMeter("a", ConsumptionUnitKWh, 3000)}},
+				{UnitID: "2", Area: 50, Meters: []*Meter{heatMeter("b", ConsumptionUnitMWh, 1)}},
+			},
+			wantUnit:   ConsumptionUnitKWh,
+			wantTotals: []money.Amount{625, 375},
+		},
+		{
+			name:               "heat cost allocators with estimated unit",
+			consumptionPercent: 70,
+			units: []HeatingCostUnit{
+				{UnitID: "1", Area: 40, Meters: []*Meter{heatMeter("a", ConsumptionUnitUnits, 60)}},
+				{UnitID: "2", Area: 40, Meters: []*Meter{heatMeter("b", ConsumptionUnitUnits, 20)}},
+				estimatedUnit("3", 20, 20),
+			},
+			wantUnit:   ConsumptionUnitUnits,
+			wantTotals: []money.Amount{540, 260, 200},
+		},
+		{
+			name:               "area only for too much estimated area",
+			consumptionPercent: 50,
+			units: []HeatingCostUnit{
+				{UnitID: "1", Area: 50, Meters: []*Meter{heatMeter("a", ConsumptionUnitKWh, 3000)}},
+				estimatedUnit("2", 50, 1000),
+			},
+			wantUnit:     ConsumptionUnitKWh,
+			wantAreaOnly: true,
+			wantTotals:   []money.Amount{500, 500},
+		},
+		{
+			name:               "units mixing kWh and allocator units",
+			consumptionPercent: 50,
+			units: []HeatingCostUnit{
+				{UnitID: "1", Area: 50, Meters: []*Meter{heatMeter("a", ConsumptionUnitKWh, 3000)}},
+				{UnitID: "2", Area: 50, Meters: []*Meter{heatMeter("b", ConsumptionUnitUnits, 40)}},
+			},
+			wantErr: true,
+		},
+		{
+			name:               "consumption percent too low",
+			consumptionPercent: 40,
+			units:              []HeatingCostUnit{{UnitID: "1", Area: 50, Meters: []*Meter{heatMeter("a", ConsumptionUnitKWh, 3000)}}},
+			wantErr:            true,
+		},
+		{
+			name:               "no units",
+			consumptionPercent: 50,
+			wantErr:            true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a, err := NewHeatingCostAllocation(1000, tt.consumptionPercent, tt.units)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewHeatingCostAllocation() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if a.ConsumptionUnit != tt.wantUnit || a.AreaOnly != tt.wantAreaOnly {
+				t.Errorf("NewHeatingCostAllocation() unit = %q, area only = %v, want %q, %v", a.ConsumptionUnit, a.AreaOnly, tt.wantUnit, tt.wantAreaOnly)
+			}
+			var sum money.Amount
+			for i, line := range a.Lines {
+				sum += line.Total
+				if line.Total != tt.wantTotals[i] {
+					t.Errorf("NewHeatingCostAllocation() line %d total = %v, want %v", i, line.Total, tt.wantTotals[i])
+				}
+			}
+			if sum != a.TotalCost {
+				t.Errorf("NewHeatingCostAllocation() lines sum = %v, want %v", sum, a.TotalCost)
+			}
+		})
+	}
+}
@@ -1,10 +1,108 @@
 package realestate
 
-import "github.com/docvibe-ai/api/go/invoicing"
+import (
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/domonda/go-types/money"
+	"github.com/domonda/go-types/nullable"
+
+	"github.com/docvibe-ai/api/go/invoicing"
+)
 
 type Invoice struct {
 	*invoicing.AccountingInvoice
 
 	Section35aAmounts []*Section35aInvoiceAmount `json:"section35a_amounts,omitempty"`
 	IdentifiedObjects []*Object                  `json:"identified_objects,omitempty"`
+
+	// CO2 emissions in kilograms of the fuel or heat billed by a heating invoice
+	CO2Emissions nullable.Type[float64] `json:"co2_emissions,omitempty,omitzero"`
+	// CO2 costs included in a heating invoice
+	CO2Cost money.NullableAmount `json:"co2_cost,omitempty,omitzero"`
+}
+
+// Normalize validates and normalizes all fields of the Invoice
+// including the embedded AccountingInvoice, the Section35aAmounts
+// and the IdentifiedObjects.
+// It returns an aggregated error of all validation issues found.
+// Identified objects without ID are removed.
+func (inv *Invoice) Normalize() error {
+	if inv == nil {
+		return nil
+	}
+	var err, result error
+	if inv.AccountingInvoice == nil {
+		result = errors.Join(result, errors.New("missing accounting invoice"))
+	} else if err = inv.AccountingInvoice.Normalize(); err != nil {
+		result = errors.Join(result, err)
+	}
+
+	inv.Section35aAmounts = slices.DeleteFunc(inv.Section35aAmounts, func(amount *Section35aInvoiceAmount) bool {
+		return amount == nil || *amount == Section35aInvoiceAmount{}
+	})
+	for i, amount := range inv.Section35aAmounts {
+		if err = amount.Normalize(); err != nil {
+			result = errors.Join(result, fmt.Errorf("invalid §35a amount %d: %w", i, err))
+		}
+	}
+	if err = inv.checkSection35aTotals(); err != nil {
+		result = errors.Join(result, err)
+	}
+
+	inv.IdentifiedObjects = slices.DeleteFunc(inv.IdentifiedObjects, func(object *Object) bool {
+		return object == nil
+	})
+	for i, object := range inv.IdentifiedObjects {
+		if err = object.Normalize(); err != nil {
+			result = errors.Join(result, fmt.Errorf("invalid identified object %d: %w", i, err))
+		}
+	}
+	inv.IdentifiedObjects = slices.DeleteFunc(inv.IdentifiedObjects, func(object *Object) bool {
+		return object.ID.IsEmpty()
+	})
+
+	if inv.CO2Emissions.IsNotNull() && inv.CO2Emissions.Get() < 0 {
+		result = errors.Join(result, fmt.Errorf("CO2 emissions %f are negative", inv.CO2Emissions.Get()))
+		inv.CO2Emissions.Set(-inv.CO2Emissions.Get())
+	}
+	if inv.CO2Cost.IsNotNull() {
+		if inv.CO2Cost.Get() < 0 {
+			result = errors.Join(result, fmt.Errorf("CO2 cost %f is negative", inv.CO2Cost.Get()))
+		}
+		inv.CO2Cost.Set(inv.CO2Cost.Get().Abs().RoundToCents())
+		if inv.AccountingInvoice != nil && inv.Total.IsNotNull() && inv.CO2Cost.Get() > inv.Total.Get() && !inv.CO2Cost.Get().WithinOneCent(inv.Total.Get()) {
+			result = errors.Join(result, fmt.Errorf("CO2 cost %f exceeds invoice total %f", inv.CO2Cost.Get(), inv.Total.Get()))
+		}
+	}
+	return result
+}
+
+// checkSection35aTotals checks that the sum of the §35a gross amounts
+// does not exceed the invoice total and the sum of the net amounts
+// does not exceed the invoice subtotal.
+func (inv *Invoice) checkSection35aTotals() (result error) {
+	if inv.AccountingInvoice == nil || len(inv.Section35aAmounts) == 0 {
+		return nil
+	}
+	var netSum, grossSum money.Amount
+	for _, amount := range inv.Section35aAmounts {
+		netSum += amount.NetAmount.Get()
+		grossSum += amount.GrossAmount.Get()
+	}
+	if total := inv.Total; total.IsNotNull() {
+		if grossSum > total.Get() && !grossSum.WithinOneCent(total.Get()) {
+			result = errors.Join(result, fmt.Errorf("sum %f of §35a gross amounts exceeds invoice total %f", grossSum, total.Get()))
+		}
+		if netSum > total.Get() && !netSum.WithinOneCent(total.Get()) {
+			result = errors.Join(result, fmt.Errorf("sum %f of §35a net amounts exceeds invoice total %f", netSum, total.Get()))
+		}
+	}
+	if subtotal := inv.Subtotal; subtotal.IsNotNull() {
+		if netSum > subtotal.Get() && !netSum.WithinOneCent(subtotal.Get()) {
+			result = errors.Join(result, fmt.Errorf("sum %f of §35a net amounts exceeds invoice subtotal %f", netSum, subtotal.Get()))
+		}
+	}
+	return result
 }
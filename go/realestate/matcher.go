@@ -0,0 +1,345 @@
+package realestate
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/docvibe-ai/api/go/invoicing"
+)
+
+// Scores added for matching parts of an object address
+const (
+	matchScoreStructuredStreet = 0.5
+	matchScoreTextStreet       = 0.4
+	matchScoreHouseNumber      = 0.2
+	matchScoreWrongHouseNumber = -0.2
+	matchScorePostalCode       = 0.2
+	matchScorePostalCodeToken  = 0.1
+	matchScoreCity             = 0.1
+	matchScoreCityToken        = 0.05
+)
+
+// Match is an Object matched by a Matcher
+type Match struct {
+	Object *Object `json:"object"`
+	// Score between 0 and 1
+	Score float64 `json:"score"`
+	// Evidence describes what was matched
+	Evidence []string `json:"evidence"`
+}
+
+// Matcher matches the address and text fields of invoices
+// against the addresses of real estate objects.
+// It can be used as offline fallback for the object identification
+// of the API or to check its results.
+type Matcher struct {
+	objects []*matcherObject
+}
+
+type matcherObject struct {
+	object     *Object
+	streets    []matcherStreet
+	postalCode string
+	city       string
+}
+
+type matcherStreet struct {
+	// original street as used for evidence
+	original string
+	// normalized street name without house number
+	name string
+	// house numbers, empty if the street has none
+	numbers []houseNumber
+}
+
+// NewMatcher returns a Matcher for the candidate objects.
+// The Street and StreetVariations of every object are matched.
+func NewMatcher(objects []*Object) *Matcher {
+	m := &Matcher{}
+	for _, object := range objects {
+		if object == nil {
+			continue
+		}
+		mo := &matcherObject{
+			object:     object,
+			postalCode: strings.TrimSpace(object.PostalCode.String()),
+			city:       normalizeAddressText(object.City.String()),
+		}
+		streets := []string{object.Street.String()}
+		for _, variation := range object.StreetVariations {
+			streets = append(streets, variation.String())
+		}
+		for _, street := range streets {
+			name, numbers := parseStreet(street)
+			if name == "" || slices.ContainsFunc(mo.streets, func(s matcherStreet) bool { return s.name == name && slices.Equal(s.numbers, numbers) }) {
+				continue
+			}
+			mo.streets = append(mo.streets, matcherStreet{original: strings.TrimSpace(street), name: name, numbers: numbers})
+		}
+		m.objects = append(m.objects, mo)
+	}
+	return m
+}
+
+// MatchInvoice scores all objects of the Matcher against
+// the customer shipping address, the notes and the item descriptions
+// of the invoice and returns the objects with a positive score
+// sorted by descending score.
+func (m *Matcher) MatchInvoice(inv *invoicing.Invoice) []Match {
+	if inv == nil {
+		return nil
+	}
+	var texts []matcherText
+	for _, note := range inv.Notes {
+		texts = append(texts, matcherText{source: "notes", text: note.String()})
+	}
+	for i, item := range inv.Items {
+		if item != nil && item.Description.IsNotNull() {
+			texts = append(texts, matcherText{source: fmt.Sprintf("item %d description", i+1), text: item.Description.String()})
+		}
+	}
+	return m.match(inv.CustomerShippingAddress, texts)
+}
+
+// MatchText scores all objects of the Matcher against free texts
+// and returns the objects with a positive score
+// sorted by descending score.
+func (m *Matcher) MatchText(texts ...string) []Match {
+	matcherTexts := make([]matcherText, len(texts))
+	for i, text := range texts {
+		matcherTexts[i] = matcherText{source: fmt.Sprintf("text %d", i+1), text: text}
+	}
+	return m.match(nil, matcherTexts)
+}
+
+type matcherText struct {
+	source string
+	text   string
+}
+
+func (m *Matcher) match(address *invoicing.Address, texts []matcherText) []Match {
+	for i := range texts {
+		texts[i].text = normalizeAddressText(texts[i].text)
+	}
+	var matches []Match
+	for _, object := range m.objects {
+		match := object.match(address, texts)
+		if match.Score > 0 {
+			matches = append(matches, match)
+		}
+	}
+	slices.SortStableFunc(matches, func(a, b Match) int {
+		switch {
+		case a.Score > b.Score:
+			return -1
+		case a.Score < b.Score:
+			return 1
+		}
+		return 0
+	})
+	return matches
+}
+
+func (o *matcherObject) match(address *invoicing.Address, texts []matcherText) Match {
+	match := Match{Object: o.object}
+	add := func(score float64, format string, args ...any) {
+		match.Score += score
+		match.Evidence = append(match.Evidence, fmt.Sprintf(format, args...))
+	}
+
+	streetMatched := false
+	if address != nil && address.Street.IsNotNull() {
+		name, numbers := parseStreet(address.Street.String())
+		for _, street := range o.streets {
+			if name != street.name {
+				continue
+			}
+			add(matchScoreStructuredStreet, "street %q matches shipping address street %q", street.original, address.Street.String())
+			o.scoreHouseNumbers(street, numbers, "shipping address", add)
+			streetMatched = true
+			break
+		}
+	}
+	if !streetMatched {
+	textsLoop:
+		for _, text := range texts {
+			for _, street := range o.streets {
+				index := indexWord(text.text, street.name)
+				if index == -1 {
+					continue
+				}
+				add(matchScoreTextStreet, "street %q found in %s", street.original, text.source)
+				numbers := parseHouseNumbers(text.text[index+len(street.name):])
+				o.scoreHouseNumbers(street, numbers, text.source, add)
+				break textsLoop
+			}
+		}
+	}
+
+	switch {
+	case o.postalCode != "" && address != nil && strings.TrimSpace(address.PostalCode.String()) == o.postalCode:
+		add(matchScorePostalCode, "postal code %s matches shipping address", o.postalCode)
+	case o.postalCode != "":
+		for _, text := range texts {
+			if o.city != "" && indexWord(text.text, o.postalCode+" "+o.city) != -1 {
+				add(matchScorePostalCode, "postal code and city %q found in %s", o.postalCode+" "+o.object.City.String(), text.source)
+				break
+			}
+			// Postal codes have at least 4 digits like in Austria,
+			// shorter numbers are too likely to match other numbers
+			if len(o.postalCode) >= 4 && indexWord(text.text, o.postalCode) != -1 {
+				add(matchScorePostalCodeToken, "postal code %s found in %s", o.postalCode, text.source)
+				break
+			}
+		}
+	}
+
+	switch {
+	case o.city != "" && address != nil && normalizeAddressText(address.City.String()) == o.city:
+		add(matchScoreCity, "city %q matches shipping address", o.object.City.String())
+	case o.city != "":
+		for _, text := range texts {
+			if indexWord(text.text, o.city) != -1 {
+				add(matchScoreCityToken, "city %q found in %s", o.object.City.String(), text.source)
+				break
+			}
+		}
+	}
+
+	match.Score = min(max(math.Round(match.Score*100)/100, 0), 1)
+	return match
+}
+
+// scoreHouseNumbers adds a positive score if one of the found house numbers
+// belongs to the street and a negative score if house numbers were found
+// that all don't belong to the street.
+func (o *matcherObject) scoreHouseNumbers(street matcherStreet, found []houseNumber, source string, add func(float64, string, ...any)) {
+	if len(street.numbers) == 0 || len(found) == 0 {
+		return
+	}
+	for _, number := range found {
+		if slices.ContainsFunc(street.numbers, number.matches) {
+			add(matchScoreHouseNumber, "house number %s matches %s", number, source)
+			return
+		}
+	}
+	add(matchScoreWrongHouseNumber, "house number %s in %s does not match %q", found[0], source, street.original)
+}
+
+// houseNumber is a house number with an optional letter suffix like 12a
+type houseNumber struct {
+	number int
+	suffix string
+}
+
+func (h houseNumber) String() string {
+	return strconv.Itoa(h.number) + h.suffix
+}
+
+// matches returns true if the numbers are equal
+// and the suffixes are equal or one of them has no suffix
+func (h houseNumber) matches(other houseNumber) bool {
+	return h.number == other.number && (h.suffix == other.suffix || h.suffix == "" || other.suffix == "")
+}
+
+var (
+	// Ranges of house numbers like "12-14" or "12/14"
+	// are normalized to "12 bis 14" by normalizeAddressText.
+	// House numbers have at most 3 digits without leading zero
+	// so that dates like "01/2025" are not matched.
+	houseNumberRangeRegexp = regexp.MustCompile(`(^|\D)([1-9]\d{0,2}[a-z]?)\s*[-/–]\s*([1-9]\d{0,2}[a-z]?)\b`)
+	streetNumberRegexp     = regexp.MustCompile(`^(.*?)\s*(\d+(?:\s?[a-z]\b)?(?:\s(?:bis|und|u)\s\d+(?:\s?[a-z]\b)?)?)\b`)
+	houseNumberRegexp      = regexp.MustCompile(`^\s*(\d+)(?:\s?([a-z])\b)?(?:\s(?:bis|und|u)\s(\d+)(?:\s?([a-z])\b)?)?\b`)
+)
+
+// parseStreet returns the normalized street name
+// and the house numbers of a street address.
+// A range of house numbers like "12-14" returns all numbers
+// of the range, limited to ranges of at most 20 numbers.
+func parseStreet(street string) (name string, numbers []houseNumber) {
+	street = normalizeAddressText(street)
+	match := streetNumberRegexp.FindStringSubmatch(street)
+	if match == nil || strings.TrimSpace(match[1]) == "" {
+		return street, nil
+	}
+	return strings.TrimSpace(match[1]), parseHouseNumbers(match[2])
+}
+
+func parseHouseNumbers(s string) []houseNumber {
+	match := houseNumberRegexp.FindStringSubmatch(s)
+	if match == nil {
+		return nil
+	}
+	from, _ := strconv.Atoi(match[1])
+	if match[3] == "" {
+		return []houseNumber{{number: from, suffix: match[2]}}
+	}
+	to, _ := strconv.Atoi(match[3])
+	if to < from || to-from > 20 {
+		return []houseNumber{{number: from, suffix: match[2]}, {number: to, suffix: match[4]}}
+	}
+	numbers := make([]houseNumber, 0, to-from+1)
+	for n := from; n <= to; n++ {
+		numbers = append(numbers, houseNumber{number: n})
+	}
+	return numbers
+}
+
+var addressTextReplacer = strings.NewReplacer(
+	"ä", "ae",
+	"ö", "oe",
+	"ü", "ue",
+	"ß", "ss",
+)
+
+// normalizeAddressText lower cases s, replaces umlauts,
+// writes ranges of house numbers as "12 bis 14",
+// replaces all characters except letters and digits with spaces,
+// and abbreviates "strasse" with "str" joined to the preceding word
+// so that "Karl-Marx-Straße", "Karl Marx Str." and "Karl-Marx-Strasse"
+// are all normalized to "karl marxstr".
+func normalizeAddressText(s string) string {
+	s = addressTextReplacer.Replace(strings.ToLower(s))
+	s = houseNumberRangeRegexp.ReplaceAllString(s, "$1$2 bis $3")
+	fields := strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	result := make([]string, 0, len(fields))
+	for _, field := range fields {
+		if strings.HasSuffix(field, "strasse") {
+			field = strings.TrimSuffix(field, "strasse") + "str"
+		}
+		if field == "str" && len(result) > 0 {
+			result[len(result)-1] += field
+			continue
+		}
+		result = append(result, field)
+	}
+	return strings.Join(result, " ")
+}
+
+// indexWord returns the index of word in text
+// if it is found at word boundaries, else -1
+func indexWord(text, word string) int {
+	if word == "" {
+		return -1
+	}
+	for offset := 0; offset < len(text); {
+		index := strings.Index(text[offset:], word)
+		if index == -1 {
+			return -1
+		}
+		start := offset + index
+		end := start + len(word)
+		if (start == 0 || text[start-1] == ' ') && (end == len(text) || text[end] == ' ') {
+			return start
+		}
+		offset = start + 1
+	}
+	return -1
+}
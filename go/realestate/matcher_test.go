@@ -0,0 +1,115 @@
+package realestate
+
+import (
+	"testing"
+
+	"github.com/domonda/go-types/nullable"
+
+	"github.com/docvibe-ai/api/go/invoicing"
+)
+
+func TestNormalizeAddressText(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{s: "Karl-Marx-Straße 12", want: "karl marxstr 12"},
+		{s: "Karl Marx Str. 12", want: "karl marxstr 12"},
+		{s: "Hauptstraße 12-14", want: "hauptstr 12 bis 14"},
+		{s: "Hauptstraße 12 / 14", want: "hauptstr 12 bis 14"},
+		{s: "Hauptstraße 12a–14", want: "hauptstr 12a bis 14"},
+		{s: "Hauptstr.12-14", want: "hauptstr 12 bis 14"},
+		{s: "Abrechnung 01/2025", want: "abrechnung 01 2025"},
+		{s: "Leistung 12/2025", want: "leistung 12 2025"},
+		{s: "Tel. 0664-1234567", want: "tel 0664 1234567"},
+		{s: "Gößstraße 3", want: "goessstr 3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.s, func(t *testing.T) {
+			if got := normalizeAddressText(tt.s); got != tt.want {
+				t.Errorf("normalizeAddressText(%q) = %q, want %q", tt.s, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatcher(t *testing.T) {
+	objects := []*Object{
+		{ID: "vienna", Street: "Mariahilfer Straße 12-14", PostalCode: "1060", City: "Wien", Country: "AT"},
+		{ID: "berlin", Street: "Karl-Marx-Allee 5", PostalCode: "10243", City: "Berlin", Country: "DE"},
+	}
+	matcher := NewMatcher(objects)
+	tests := []struct {
+		name      string
+		texts     []string
+		address   *invoicing.Address
+		wantFirst string
+		wantScore float64
+	}{
+		{
+			name:      "street and house number in range",
+			texts:     []string{"Reparatur Mariahilfer Str. 13"},
+			wantFirst: "vienna",
+			wantScore: matchScoreTextStreet + matchScoreHouseNumber,
+		},
+		{
+			name:      "Austrian postal code token",
+			texts:     []string{"Mariahilfer Straße 12, PLZ 1060"},
+			wantFirst: "vienna",
+			wantScore: matchScoreTextStreet + matchScoreHouseNumber + matchScorePostalCodeToken,
+		},
+		{
+			name:      "postal code and city",
+			texts:     []string{"Objekt 10243 Berlin, Karl-Marx-Allee 5"},
+			wantFirst: "berlin",
+			wantScore: matchScoreTextStreet + matchScoreHouseNumber + matchScorePostalCode + matchScoreCityToken,
+		},
+		{
+			name:      "wrong house number",
+			texts:     []string{"Karl-Marx-Allee 7"},
+			wantFirst: "berlin",
+			wantScore: matchScoreTextStreet + matchScoreWrongHouseNumber,
+		},
+		{
+			name: "shipping address",
+			address: &invoicing.Address{
+				Street:     nullable.TrimmedString("Mariahilfer Strasse 14"),
+				PostalCode: nullable.TrimmedString("1060"),
+				City:       nullable.TrimmedString("Wien"),
+			},
+			wantFirst: "vienna",
+			wantScore: matchScoreStructuredStreet + matchScoreHouseNumber + matchScorePostalCode + matchScoreCity,
+		},
+		{
+			name:  "no match",
+			texts: []string{"Abrechnung 01/2025"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var matches []Match
+			if tt.address != nil {
+				matches = matcher.MatchInvoice(&invoicing.Invoice{CustomerShippingAddress: tt.address})
+			} else {
+				matches = matcher.MatchText(tt.texts...)
+			}
+			if tt.wantFirst == "" {
+				if len(matches) > 0 {
+					t.Errorf("matches = %+v, want none", matches)
+				}
+				return
+			}
+			if len(matches) == 0 {
+				t.Fatalf("no matches, want %s", tt.wantFirst)
+			}
+			first := matches[0]
+			if first.Object.ID.String() != tt.wantFirst || !approxEqual(first.Score, tt.wantScore) {
+				t.Errorf("first match = %s with score %f %v, want %s with score %f", first.Object.ID, first.Score, first.Evidence, tt.wantFirst, tt.wantScore)
+			}
+		})
+	}
+}
+
+func approxEqual(a, b float64) bool {
+	return a-b < 1e-9 && b-a < 1e-9
+}
@@ -1,17 +1,38 @@
 package realestate
 
+//go:generate go tool go-enum $GOFILE
+
 import (
+	"bytes"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
 	"github.com/domonda/go-types/country"
 	"github.com/domonda/go-types/notnull"
 	"github.com/domonda/go-types/nullable"
+	"github.com/invopop/jsonschema"
 )
 
 type Object struct {
-	ID    notnull.TrimmedString  `json:"id"`
-	Type  nullable.TrimmedString `json:"type,omitempty"`
-	Notes nullable.TrimmedString `json:"notes,omitempty"`
+	ID   notnull.TrimmedString `json:"id"`
+	Type ObjectType            `json:"type,omitempty"`
+	// Original type text of objects whose type was free text
+	// like "Wohnanlage" before the ObjectType values were introduced
+	TypeText nullable.TrimmedString `json:"type_text,omitempty"`
+	Notes    nullable.TrimmedString `json:"notes,omitempty"`
 	// Status nullable.TrimmedString `json:"status,omitempty"`
 
+	// ID of the parent object, like the building of a unit
+	ParentID nullable.TrimmedString `json:"parent_id,omitempty"`
+	// Living or usable area in square meters
+	Area nullable.Type[float64] `json:"area,omitempty,omitzero"`
+	// Ownership share (Miteigentumsanteil) of a unit, like 125 of 1000
+	OwnershipShare nullable.Type[float64] `json:"ownership_share,omitempty,omitzero"`
+
 	Street           nullable.TrimmedString  `json:"street,omitempty"`
 	StreetVariations []notnull.TrimmedString `json:"street_variations,omitempty"`
 	City             nullable.TrimmedString  `json:"city,omitempty"`
@@ -20,6 +41,213 @@ type Object struct {
 	Country          country.NullableCode    `json:"country,omitempty"`
 }
 
+// Normalize validates and normalizes all fields of the Object.
+// It returns an aggregated error of all validation issues found.
+// Invalid fields are either corrected or set to null values.
+// An empty ID can't be corrected and is returned as error.
+// A Type that is not an ObjectType value is moved to TypeText
+// and mapped to the ObjectType of known legacy type texts.
+func (o *Object) Normalize() error {
+	if o == nil {
+		return nil
+	}
+	var err, result error
+	if o.ID.IsEmpty() {
+		result = errors.Join(result, errors.New("object ID is empty"))
+	}
+	if !o.Type.Valid() {
+		if o.TypeText.IsNull() {
+			o.TypeText = nullable.TrimmedStringFrom(string(o.Type))
+		}
+		legacyType, ok := legacyObjectTypes[strings.ToLower(strings.TrimSpace(string(o.Type)))]
+		if !ok {
+			result = errors.Join(result, fmt.Errorf("unknown object type %q", o.Type))
+		}
+		o.Type = legacyType
+	}
+	if o.ParentID.IsNotNull() && o.ParentID.String() == o.ID.String() {
+		result = errors.Join(result, fmt.Errorf("object %s is its own parent", o.ID))
+		o.ParentID.SetNull()
+	}
+	if o.Area.IsNotNull() && o.Area.Get() < 0 {
+		result = errors.Join(result, fmt.Errorf("area %f is negative", o.Area.Get()))
+		o.Area.SetNull()
+	}
+	if o.OwnershipShare.IsNotNull() && o.OwnershipShare.Get() < 0 {
+		result = errors.Join(result, fmt.Errorf("ownership share %f is negative", o.OwnershipShare.Get()))
+		o.OwnershipShare.SetNull()
+	}
+	o.StreetVariations = slices.DeleteFunc(o.StreetVariations, func(street notnull.TrimmedString) bool {
+		return street.IsEmpty()
+	})
+	if o.Country, err = o.Country.Normalized(); err != nil {
+		result = errors.Join(result, fmt.Errorf("invalid country: %w", err))
+		o.Country.SetNull()
+	}
+	return result
+}
+
+// legacyObjectTypes maps lower case type texts
+// used before the ObjectType values were introduced
+var legacyObjectTypes = map[string]ObjectType{
+	"property":       ObjectTypeProperty,
+	"liegenschaft":   ObjectTypeProperty,
+	"wohnanlage":     ObjectTypeProperty,
+	"anlage":         ObjectTypeProperty,
+	"building":       ObjectTypeBuilding,
+	"gebäude":        ObjectTypeBuilding,
+	"gebaeude":       ObjectTypeBuilding,
+	"haus":           ObjectTypeBuilding,
+	"wohnhaus":       ObjectTypeBuilding,
+	"staircase":      ObjectTypeStaircase,
+	"stiege":         ObjectTypeStaircase,
+	"treppenhaus":    ObjectTypeStaircase,
+	"aufgang":        ObjectTypeStaircase,
+	"unit":           ObjectTypeUnit,
+	"einheit":        ObjectTypeUnit,
+	"wohnung":        ObjectTypeUnit,
+	"top":            ObjectTypeUnit,
+	"gewerbeeinheit": ObjectTypeUnit,
+	"geschäftslokal": ObjectTypeUnit,
+	"stellplatz":     ObjectTypeUnit,
+	"garage":         ObjectTypeUnit,
+	"apartment":      ObjectTypeUnit,
+}
+
+type ObjectType string //#enum,jsonschema
+
+const (
+	ObjectTypeNull      ObjectType = ""          //#null
+	ObjectTypeProperty  ObjectType = "PROPERTY"  // Liegenschaft, Wohnanlage
+	ObjectTypeBuilding  ObjectType = "BUILDING"  // Gebäude
+	ObjectTypeStaircase ObjectType = "STAIRCASE" // Stiege, Treppenhaus, Aufgang
+	ObjectTypeUnit      ObjectType = "UNIT"      // Wohnung, Gewerbeeinheit, Stellplatz
+)
+
+// Valid indicates if t is any of the valid values for ObjectType
+func (t ObjectType) Valid() bool {
+	switch t {
+	case
+		ObjectTypeNull,
+		ObjectTypeProperty,
+		ObjectTypeBuilding,
+		ObjectTypeStaircase,
+		ObjectTypeUnit:
+		return true
+	}
+	return false
+}
+
+// Validate returns an error if t is none of the valid values for ObjectType
+func (t ObjectType) Validate() error {
+	if !t.Valid() {
+		return fmt.Errorf("invalid value %#v for type realestate.ObjectType", t)
+	}
+	return nil
+}
+
+// Enums returns all valid values for ObjectType
+func (ObjectType) Enums() []ObjectType {
+	return []ObjectType{
+		ObjectTypeNull,
+		ObjectTypeProperty,
+		ObjectTypeBuilding,
+		ObjectTypeStaircase,
+		ObjectTypeUnit,
+	}
+}
+
+// EnumStrings returns all valid values for ObjectType as strings
+func (ObjectType) EnumStrings() []string {
+	return []string{
+		"",
+		"PROPERTY",
+		"BUILDING",
+		"STAIRCASE",
+		"UNIT",
+	}
+}
+
+// String implements the fmt.Stringer interface for ObjectType
+func (t ObjectType) String() string {
+	return string(t)
+}
+
+// IsNull returns true if t is the null value ObjectTypeNull
+func (t ObjectType) IsNull() bool {
+	return t == ObjectTypeNull
+}
+
+// IsNotNull returns true if t is not the null value ObjectTypeNull
+func (t ObjectType) IsNotNull() bool {
+	return t != ObjectTypeNull
+}
+
+// SetNull sets the null value ObjectTypeNull at t
+func (t *ObjectType) SetNull() {
+	*t = ObjectTypeNull
+}
+
+// MarshalJSON implements encoding/json.Marshaler for ObjectType
+// by returning the JSON null value for ObjectTypeNull.
+func (t ObjectType) MarshalJSON() ([]byte, error) {
+	if t == ObjectTypeNull {
+		return []byte("null"), nil
+	}
+	return json.Marshal(string(t))
+}
+
+// UnmarshalJSON implements encoding/json.Unmarshaler
+func (t *ObjectType) UnmarshalJSON(j []byte) error {
+	if bytes.Equal(j, []byte("null")) {
+		*t = ObjectTypeNull
+		return nil
+	}
+	return json.Unmarshal(j, (*string)(t))
+}
+
+// Scan implements the database/sql.Scanner interface for ObjectType
+func (t *ObjectType) Scan(value any) error {
+	switch value := value.(type) {
+	case string:
+		*t = ObjectType(value)
+	case []byte:
+		*t = ObjectType(value)
+	case nil:
+		*t = ObjectTypeNull
+	default:
+		return fmt.Errorf("can't scan SQL value of type %T as realestate.ObjectType", value)
+	}
+	return nil
+}
+
+// Value implements the driver database/sql/driver.Valuer interface for ObjectType
+func (t ObjectType) Value() (driver.Value, error) {
+	if t == ObjectTypeNull {
+		return nil, nil
+	}
+	return string(t), nil
+}
+
+// JSONSchema returns a github.com/invopop/jsonschema.Schema for ObjectType
+func (ObjectType) JSONSchema() *jsonschema.Schema {
+	return &jsonschema.Schema{
+		OneOf: []*jsonschema.Schema{
+			{
+				Type: "string",
+				Enum: []any{
+					"PROPERTY",
+					"BUILDING",
+					"STAIRCASE",
+					"UNIT",
+				},
+			},
+			{Type: "null"},
+		},
+		Default: ObjectTypeNull,
+	}
+}
+
 // type Address struct {
 // 	Street           nullable.TrimmedString  `json:"street,omitempty"`
 // 	StreetVariations []notnull.TrimmedString `json:"street_variations,omitempty"`
@@ -0,0 +1,40 @@
+package realestate
+
+import (
+	"testing"
+
+	"github.com/domonda/go-types/nullable"
+)
+
+func TestObject_Normalize_type(t *testing.T) {
+	tests := []struct {
+		name         string
+		objectType   ObjectType
+		typeText     nullable.TrimmedString
+		wantType     ObjectType
+		wantTypeText nullable.TrimmedString
+		wantErr      bool
+	}{
+		{name: "null", objectType: ObjectTypeNull, wantType: ObjectTypeNull},
+		{name: "valid", objectType: ObjectTypeUnit, wantType: ObjectTypeUnit},
+		{name: "legacy property", objectType: "Wohnanlage", wantType: ObjectTypeProperty, wantTypeText: "Wohnanlage"},
+		{name: "legacy building", objectType: " Gebäude ", wantType: ObjectTypeBuilding, wantTypeText: "Gebäude"},
+		{name: "legacy staircase", objectType: "Stiege", wantType: ObjectTypeStaircase, wantTypeText: "Stiege"},
+		{name: "legacy unit", objectType: "Wohnung", wantType: ObjectTypeUnit, wantTypeText: "Wohnung"},
+		{name: "lower case enum", objectType: "unit", wantType: ObjectTypeUnit, wantTypeText: "unit"},
+		{name: "unknown", objectType: "Schrebergarten", wantType: ObjectTypeNull, wantTypeText: "Schrebergarten", wantErr: true},
+		{name: "existing type text", objectType: "Top", typeText: "Top 4", wantType: ObjectTypeUnit, wantTypeText: "Top 4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			o := &Object{ID: "1", Type: tt.objectType, TypeText: tt.typeText}
+			err := o.Normalize()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Object.Normalize() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if o.Type != tt.wantType || o.TypeText != tt.wantTypeText {
+				t.Errorf("Object.Normalize() type = %q, type text = %q, want %q and %q", o.Type, o.TypeText, tt.wantType, tt.wantTypeText)
+			}
+		})
+	}
+}
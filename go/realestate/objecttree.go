@@ -0,0 +1,176 @@
+package realestate
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+)
+
+// ObjectTree is the hierarchy of real estate objects
+// like properties with buildings, staircases and units
+// built from the ParentID of the objects.
+type ObjectTree struct {
+	objects  map[string]*Object
+	children map[string][]*Object
+	roots    []*Object
+}
+
+// NewObjectTree returns the ObjectTree of the objects.
+// Returns an error for empty or duplicate object IDs,
+// parent IDs not found in objects, and cycles.
+func NewObjectTree(objects []*Object) (*ObjectTree, error) {
+	t := &ObjectTree{
+		objects:  make(map[string]*Object, len(objects)),
+		children: make(map[string][]*Object),
+	}
+	for _, object := range objects {
+		if object == nil {
+			continue
+		}
+		id := object.ID.String()
+		if id == "" {
+			return nil, errors.New("object ID is empty")
+		}
+		if _, ok := t.objects[id]; ok {
+			return nil, fmt.Errorf("duplicate object ID %s", id)
+		}
+		t.objects[id] = object
+	}
+	for _, object := range objects {
+		if object == nil {
+			continue
+		}
+		if object.ParentID.IsNull() {
+			t.roots = append(t.roots, object)
+			continue
+		}
+		parentID := object.ParentID.String()
+		if _, ok := t.objects[parentID]; !ok {
+			return nil, fmt.Errorf("parent %s of object %s not found", parentID, object.ID)
+		}
+		t.children[parentID] = append(t.children[parentID], object)
+	}
+	for id := range t.objects {
+		visited := map[string]bool{id: true}
+		for parent := t.Parent(id); parent != nil; parent = t.Parent(parent.ID.String()) {
+			if visited[parent.ID.String()] {
+				return nil, fmt.Errorf("object %s is part of a parent cycle", id)
+			}
+			visited[parent.ID.String()] = true
+		}
+	}
+	return t, nil
+}
+
+// Object returns the object with the passed ID or nil
+func (t *ObjectTree) Object(id string) *Object {
+	return t.objects[id]
+}
+
+// Roots returns the objects without parent
+func (t *ObjectTree) Roots() []*Object {
+	return t.roots
+}
+
+// Parent returns the parent of the object with the passed ID
+// or nil if it has no parent or does not exist
+func (t *ObjectTree) Parent(id string) *Object {
+	object := t.objects[id]
+	if object == nil || object.ParentID.IsNull() {
+		return nil
+	}
+	return t.objects[object.ParentID.String()]
+}
+
+// Ancestors returns the parent, grandparent and so on
+// of the object with the passed ID
+func (t *ObjectTree) Ancestors(id string) []*Object {
+	var ancestors []*Object
+	for parent := t.Parent(id); parent != nil; parent = t.Parent(parent.ID.String()) {
+		ancestors = append(ancestors, parent)
+	}
+	return ancestors
+}
+
+// Children returns the direct children of the object with the passed ID
+func (t *ObjectTree) Children(id string) []*Object {
+	return t.children[id]
+}
+
+// Descendants returns all children, grandchildren and so on
+// of the object with the passed ID in depth first order
+func (t *ObjectTree) Descendants(id string) []*Object {
+	var descendants []*Object
+	for _, child := range t.children[id] {
+		descendants = append(descendants, child)
+		descendants = append(descendants, t.Descendants(child.ID.String())...)
+	}
+	return descendants
+}
+
+// AllocatableUnits returns the objects of type ObjectTypeUnit
+// that costs assigned to the object with the passed ID can be allocated to.
+// These are the object itself if it is a unit, else the units
+// in the subtree of the object in depth first order.
+// Units within units, like a parking space of an apartment,
+// are part of the enclosing unit and not returned.
+// Returns nil if the object does not exist.
+func (t *ObjectTree) AllocatableUnits(id string) []*Object {
+	object := t.objects[id]
+	if object == nil {
+		return nil
+	}
+	if object.Type == ObjectTypeUnit {
+		return []*Object{object}
+	}
+	var units []*Object
+	for _, child := range t.children[id] {
+		units = append(units, t.AllocatableUnits(child.ID.String())...)
+	}
+	return units
+}
+
+// AllocatableUnitsOfInvoice returns the allocatable units
+// of all IdentifiedObjects of the invoice without duplicates.
+// Identified objects that are not part of the tree are returned as error.
+func (t *ObjectTree) AllocatableUnitsOfInvoice(inv *Invoice) ([]*Object, error) {
+	if inv == nil {
+		return nil, nil
+	}
+	var (
+		units []*Object
+		err   error
+	)
+	for _, identified := range inv.IdentifiedObjects {
+		if identified == nil {
+			continue
+		}
+		id := identified.ID.String()
+		if t.objects[id] == nil {
+			err = errors.Join(err, fmt.Errorf("identified object %s not found", id))
+			continue
+		}
+		for _, unit := range t.AllocatableUnits(id) {
+			if !slices.Contains(units, unit) {
+				units = append(units, unit)
+			}
+		}
+	}
+	return units, err
+}
+
+// Section35aUnits returns the allocatable units of the object
+// with the passed ID as Section35aUnits with the ID of the object
+// as ObjectID and the Area and OwnershipShare of the units.
+func (t *ObjectTree) Section35aUnits(id string) []Section35aUnit {
+	var result []Section35aUnit
+	for _, unit := range t.AllocatableUnits(id) {
+		result = append(result, Section35aUnit{
+			ObjectID: id,
+			UnitID:   unit.ID.String(),
+			Area:     unit.Area.Get(),
+			Share:    unit.OwnershipShare.Get(),
+		})
+	}
+	return result
+}
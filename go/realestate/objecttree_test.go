@@ -0,0 +1,72 @@
+package realestate
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/domonda/go-types/notnull"
+	"github.com/domonda/go-types/nullable"
+)
+
+func TestObjectTree_AllocatableUnits(t *testing.T) {
+	object := func(id, parentID string, objectType ObjectType) *Object {
+		return &Object{ID: notnull.TrimmedString(id), ParentID: nullable.TrimmedString(parentID), Type: objectType}
+	}
+	tree, err := NewObjectTree([]*Object{
+		object("property", "", ObjectTypeProperty),
+		object("building-1", "property", ObjectTypeBuilding),
+		object("staircase-1", "building-1", ObjectTypeStaircase),
+		object("top-1", "staircase-1", ObjectTypeUnit),
+		object("top-1-parking", "top-1", ObjectTypeUnit),
+		object("top-2", "staircase-1", ObjectTypeUnit),
+		object("building-2", "property", ObjectTypeBuilding),
+		object("shop", "building-2", ObjectTypeUnit),
+		object("empty-staircase", "building-2", ObjectTypeStaircase),
+		object("untyped", "building-2", ObjectTypeNull),
+	})
+	if err != nil {
+		t.Fatalf("NewObjectTree() error = %v", err)
+	}
+	tests := []struct {
+		id   string
+		want []string
+	}{
+		{id: "property", want: []string{"top-1", "top-2", "shop"}},
+		{id: "building-1", want: []string{"top-1", "top-2"}},
+		{id: "top-1", want: []string{"top-1"}},
+		{id: "top-1-parking", want: []string{"top-1-parking"}},
+		{id: "empty-staircase", want: nil},
+		{id: "untyped", want: nil},
+		{id: "unknown", want: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			var got []string
+			for _, unit := range tree.AllocatableUnits(tt.id) {
+				got = append(got, unit.ID.String())
+			}
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("ObjectTree.AllocatableUnits(%q) = %v, want %v", tt.id, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewObjectTree_errors(t *testing.T) {
+	tests := []struct {
+		name    string
+		objects []*Object
+	}{
+		{name: "empty ID", objects: []*Object{{ID: ""}}},
+		{name: "duplicate ID", objects: []*Object{{ID: "1"}, {ID: "1"}}},
+		{name: "parent not found", objects: []*Object{{ID: "1", ParentID: "2"}}},
+		{name: "cycle", objects: []*Object{{ID: "1", ParentID: "2"}, {ID: "2", ParentID: "1"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewObjectTree(tt.objects); err == nil {
+				t.Error("NewObjectTree() error = nil")
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package realestate
 //go:generate go tool go-enum $GOFILE
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/domonda/go-types/money"
@@ -15,6 +16,78 @@ type Section35aInvoiceAmount struct {
 	NetAmount   money.NullableAmount   `json:"net_amount"`
 	GrossAmount money.NullableAmount   `json:"gross_amount"`
 	Purpose     nullable.TrimmedString `json:"purpose"`
+
+	// Gross labor costs including machine costs, eligible for §35a
+	LaborAmount money.NullableAmount `json:"labor_amount,omitempty,omitzero"`
+	// Gross travel costs, eligible for §35a
+	TravelAmount money.NullableAmount `json:"travel_amount,omitempty,omitzero"`
+	// Gross material costs, not eligible for §35a
+	MaterialAmount money.NullableAmount `json:"material_amount,omitempty,omitzero"`
+	// Gross amount eligible for §35a (labor plus travel costs)
+	EligibleAmount money.NullableAmount `json:"eligible_amount,omitempty,omitzero"`
+}
+
+// Normalize validates and normalizes all fields of the Section35aInvoiceAmount.
+// It returns an aggregated error of all validation issues found.
+// Invalid fields are either corrected (e.g., negative amounts become absolute)
+// or set to null/zero values. The amount remains usable after normalization,
+// with the returned error describing what was corrected.
+func (a *Section35aInvoiceAmount) Normalize() error {
+	if a == nil {
+		return nil
+	}
+	var result error
+	if err := a.Type.Validate(); err != nil {
+		result = errors.Join(result, err)
+		a.Type = ""
+	}
+	if a.NetAmount.IsNotNull() && a.NetAmount.Get() < 0 {
+		result = errors.Join(result, fmt.Errorf("net amount %f is negative", a.NetAmount.Get()))
+		a.NetAmount.Set(a.NetAmount.Get().Abs())
+	}
+	if a.GrossAmount.IsNotNull() && a.GrossAmount.Get() < 0 {
+		result = errors.Join(result, fmt.Errorf("gross amount %f is negative", a.GrossAmount.Get()))
+		a.GrossAmount.Set(a.GrossAmount.Get().Abs())
+	}
+	if a.NetAmount.IsNotNull() && a.GrossAmount.IsNotNull() {
+		if a.NetAmount.Get() > a.GrossAmount.Get() {
+			result = errors.Join(result, fmt.Errorf("net amount %f is greater than gross amount %f", a.NetAmount.Get(), a.GrossAmount.Get()))
+			a.NetAmount.SetNull()
+		}
+	}
+	if a.Purpose.IsNull() {
+		result = errors.Join(result, errors.New("purpose is empty"))
+	}
+	for _, component := range []struct {
+		name   string
+		amount *money.NullableAmount
+	}{
+		{"labor amount", &a.LaborAmount},
+		{"travel amount", &a.TravelAmount},
+		{"material amount", &a.MaterialAmount},
+		{"eligible amount", &a.EligibleAmount},
+	} {
+		if component.amount.IsNotNull() && component.amount.Get() < 0 {
+			result = errors.Join(result, fmt.Errorf("%s %f is negative", component.name, component.amount.Get()))
+			component.amount.Set(component.amount.Get().Abs())
+		}
+	}
+	if a.LaborAmount.IsNotNull() && a.TravelAmount.IsNotNull() {
+		eligible := a.LaborAmount.Get() + a.TravelAmount.Get()
+		if a.EligibleAmount.IsNull() {
+			result = errors.Join(result, fmt.Errorf("derived missing eligible amount %f from labor and travel amounts", eligible))
+			a.EligibleAmount.Set(eligible)
+		} else if !a.EligibleAmount.Get().WithinOneCent(eligible) {
+			result = errors.Join(result, fmt.Errorf("eligible amount %f is not the sum %f of labor and travel amounts", a.EligibleAmount.Get(), eligible))
+			a.EligibleAmount.Set(eligible)
+		}
+	}
+	if a.EligibleAmount.IsNotNull() && a.GrossAmount.IsNotNull() {
+		if a.EligibleAmount.Get() > a.GrossAmount.Get() && !a.EligibleAmount.Get().WithinOneCent(a.GrossAmount.Get()) {
+			result = errors.Join(result, fmt.Errorf("eligible amount %f is greater than gross amount %f", a.EligibleAmount.Get(), a.GrossAmount.Get()))
+		}
+	}
+	return result
 }
 
 type Section35aType string //#enum,jsonschema
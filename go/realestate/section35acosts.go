@@ -0,0 +1,266 @@
+package realestate
+
+//go:generate go tool go-enum $GOFILE
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/domonda/go-types/money"
+	"github.com/invopop/jsonschema"
+
+	"github.com/docvibe-ai/api/go/invoicing"
+)
+
+// Section35aCostType classifies the costs of an invoice item
+// for the eligibility according to §35a EStG.
+// Only labor costs including machine costs and travel costs are eligible,
+// material costs are not.
+type Section35aCostType string //#enum,jsonschema
+
+const (
+	Section35aCostTypeLabor        Section35aCostType = "LABOR"        // Arbeitskosten inklusive Maschinenkosten
+	Section35aCostTypeTravel       Section35aCostType = "TRAVEL"       // Fahrtkosten
+	Section35aCostTypeMaterial     Section35aCostType = "MATERIAL"     // Materialkosten
+	Section35aCostTypeUnclassified Section35aCostType = "UNCLASSIFIED" // Nicht zuordenbar
+)
+
+// Eligible indicates if the cost type is eligible for §35a EStG
+func (s Section35aCostType) Eligible() bool {
+	return s == Section35aCostTypeLabor || s == Section35aCostTypeTravel
+}
+
+// Valid indicates if s is any of the valid values for Section35aCostType
+func (s Section35aCostType) Valid() bool {
+	switch s {
+	case
+		Section35aCostTypeLabor,
+		Section35aCostTypeTravel,
+		Section35aCostTypeMaterial,
+		Section35aCostTypeUnclassified:
+		return true
+	}
+	return false
+}
+
+// Validate returns an error if s is none of the valid values for Section35aCostType
+func (s Section35aCostType) Validate() error {
+	if !s.Valid() {
+		return fmt.Errorf("invalid value %#v for type realestate.Section35aCostType", s)
+	}
+	return nil
+}
+
+// Enums returns all valid values for Section35aCostType
+func (Section35aCostType) Enums() []Section35aCostType {
+	return []Section35aCostType{
+		Section35aCostTypeLabor,
+		Section35aCostTypeTravel,
+		Section35aCostTypeMaterial,
+		Section35aCostTypeUnclassified,
+	}
+}
+
+// EnumStrings returns all valid values for Section35aCostType as strings
+func (Section35aCostType) EnumStrings() []string {
+	return []string{
+		"LABOR",
+		"TRAVEL",
+		"MATERIAL",
+		"UNCLASSIFIED",
+	}
+}
+
+// String implements the fmt.Stringer interface for Section35aCostType
+func (s Section35aCostType) String() string {
+	return string(s)
+}
+
+// JSONSchema returns a github.com/invopop/jsonschema.Schema for Section35aCostType
+func (Section35aCostType) JSONSchema() *jsonschema.Schema {
+	return &jsonschema.Schema{
+		Type: "string",
+		Enum: []any{
+			"LABOR",
+			"TRAVEL",
+			"MATERIAL",
+			"UNCLASSIFIED",
+		},
+	}
+}
+
+var (
+	section35aTravelKeywords = []string{
+		"anfahrt", "abfahrt", "anreise", "fahrtkosten", "fahrtzeit", "fahrzeit", "wegezeit", "wegzeit", "wegegeld",
+		"kilometer", "km-pauschale", "kilometerpauschale", "travel",
+	}
+	section35aMaterialKeywords = []string{
+		"material", "ersatzteil", "kleinteil", "zubehör", "reinigungsmittel", "streugut", "streusalz", "saatgut",
+		"lieferung", "entsorgungsgebühr", "deponie", "spare part", "parts",
+	}
+	section35aLaborKeywords = []string{
+		"arbeit", "lohn", "monteur", "meister", "geselle", "helfer", "techniker", "montage", "demontage",
+		"reinigung", "pflege", "wartung", "reparatur", "instandsetzung", "winterdienst", "hausmeister",
+		"maschine", "gerät", "labor", "work", "service", "dienstleistung",
+	}
+	section35aLaborUnits    = []string{"h", "std", "std.", "stunde", "stunden", "min", "min.", "minuten", "hour", "hours", "hrs", "ah", "aw"}
+	section35aTravelUnits   = []string{"km"}
+	section35aMaterialUnits = []string{
+		"stk", "stk.", "stück", "st", "st.", "pcs", "kg", "g", "t", "l", "ltr", "m", "m2", "m²", "m3", "m³", "lfm",
+		"rolle", "sack", "eimer", "packung", "pck", "dose", "karton", "palette",
+	}
+)
+
+// ClassifySection35aCost classifies the costs of an invoice item
+// by keywords in its description and by its unit.
+// Labor keywords that are part of a material keyword are ignored,
+// so that for example "Reinigungsmittel" is classified as material
+// and not as cleaning labor.
+// Mixed positions with material and labor or travel keywords
+// like "Lieferung und Montage" are unclassified
+// because the eligible share can't be determined.
+func ClassifySection35aCost(item *invoicing.InvoiceItem) Section35aCostType {
+	if item == nil {
+		return Section35aCostTypeUnclassified
+	}
+	description := strings.ToLower(item.Description.String())
+	containsAny := func(description string, keywords []string) bool {
+		return slices.ContainsFunc(keywords, func(keyword string) bool {
+			return strings.Contains(description, keyword)
+		})
+	}
+	withoutMaterial := description
+	for _, keyword := range section35aMaterialKeywords {
+		withoutMaterial = strings.ReplaceAll(withoutMaterial, keyword, " ")
+	}
+	var (
+		travel   = containsAny(description, section35aTravelKeywords)
+		material = withoutMaterial != description
+		labor    = containsAny(withoutMaterial, section35aLaborKeywords)
+	)
+	switch {
+	case material && (labor || travel):
+		return Section35aCostTypeUnclassified
+	case travel:
+		return Section35aCostTypeTravel
+	case material:
+		return Section35aCostTypeMaterial
+	case labor:
+		return Section35aCostTypeLabor
+	}
+	unit := strings.ToLower(item.Unit.String())
+	switch {
+	case slices.Contains(section35aLaborUnits, unit):
+		return Section35aCostTypeLabor
+	case slices.Contains(section35aTravelUnits, unit):
+		return Section35aCostTypeTravel
+	case slices.Contains(section35aMaterialUnits, unit):
+		return Section35aCostTypeMaterial
+	}
+	return Section35aCostTypeUnclassified
+}
+
+// Section35aCosts are the gross costs of invoice items
+// summed up per Section35aCostType
+type Section35aCosts struct {
+	Labor        money.Amount `json:"labor"`
+	Travel       money.Amount `json:"travel"`
+	Material     money.Amount `json:"material"`
+	Unclassified money.Amount `json:"unclassified"`
+}
+
+// Section35aCostsOfItems classifies the items with ClassifySection35aCost
+// and sums up their gross amounts per cost type.
+// The gross amount of an item is its subtotal plus tax amount,
+// or the subtotal plus tax percentage if the tax amount is missing.
+// Credit note items are subtracted for invoices that are not credit notes.
+// Items without subtotal are returned as error.
+func Section35aCostsOfItems(inv *invoicing.Invoice) (costs Section35aCosts, err error) {
+	if inv == nil {
+		return costs, nil
+	}
+	for i, item := range inv.Items {
+		if item == nil {
+			continue
+		}
+		if item.Subtotal.IsNull() {
+			err = errors.Join(err, fmt.Errorf("item %d has no subtotal", i))
+			continue
+		}
+		gross := item.Subtotal.Get()
+		switch {
+		case item.TaxAmount.IsNotNull():
+			gross += item.TaxAmount.Get()
+		case item.TaxPercent.IsNotNull():
+			gross += (item.Subtotal.Get() * money.Amount(item.TaxPercent.Get()) / 100).RoundToCents()
+		}
+		if item.CreditNote && !inv.CreditNote && gross > 0 {
+			gross = -gross
+		}
+		switch ClassifySection35aCost(item) {
+		case Section35aCostTypeLabor:
+			costs.Labor += gross
+		case Section35aCostTypeTravel:
+			costs.Travel += gross
+		case Section35aCostTypeMaterial:
+			costs.Material += gross
+		default:
+			costs.Unclassified += gross
+		}
+	}
+	return costs, err
+}
+
+// Eligible returns the sum of the labor and travel costs
+// that are eligible for §35a EStG
+func (c Section35aCosts) Eligible() money.Amount {
+	return c.Labor + c.Travel
+}
+
+// Total returns the sum of all costs
+func (c Section35aCosts) Total() money.Amount {
+	return c.Labor + c.Travel + c.Material + c.Unclassified
+}
+
+// EligibleShare returns the eligible share of the total costs
+// between 0 and 1, or 0 if the total is zero.
+func (c Section35aCosts) EligibleShare() float64 {
+	total := c.Total()
+	if total <= 0 {
+		return 0
+	}
+	return min(max(float64(c.Eligible()/total), 0), 1)
+}
+
+// SetCosts sets the cost components and the eligible amount of the §35a amount
+func (a *Section35aInvoiceAmount) SetCosts(costs Section35aCosts) {
+	a.LaborAmount.Set(costs.Labor.RoundToCents())
+	a.TravelAmount.Set(costs.Travel.RoundToCents())
+	a.MaterialAmount.Set((costs.Material + costs.Unclassified).RoundToCents())
+	a.EligibleAmount.Set(costs.Eligible().RoundToCents())
+}
+
+// SplitSection35aCosts derives the labor, travel and material components
+// and the eligible amount of the §35a amount of the invoice from its items.
+// Items can only be assigned if the invoice has exactly one §35a amount.
+// Unclassified item costs are counted as material costs
+// because they can't be proven to be eligible.
+func (inv *Invoice) SplitSection35aCosts() error {
+	if inv == nil || inv.AccountingInvoice == nil || len(inv.Section35aAmounts) == 0 {
+		return nil
+	}
+	if len(inv.Section35aAmounts) > 1 {
+		return fmt.Errorf("can't assign items to %d §35a amounts", len(inv.Section35aAmounts))
+	}
+	if len(inv.Items) == 0 {
+		return errors.New("no items to split §35a costs")
+	}
+	costs, err := Section35aCostsOfItems(&inv.Invoice)
+	if err != nil {
+		return err
+	}
+	inv.Section35aAmounts[0].SetCosts(costs)
+	return nil
+}
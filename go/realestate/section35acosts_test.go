@@ -0,0 +1,93 @@
+package realestate
+
+import (
+	"testing"
+
+	"github.com/domonda/go-types/money"
+	"github.com/domonda/go-types/nullable"
+
+	"github.com/docvibe-ai/api/go/invoicing"
+)
+
+func amount(v float64) (a money.NullableAmount) {
+	a.Set(money.Amount(v))
+	return a
+}
+
+func rate(v float64) (r money.NullableRate) {
+	r.Set(money.Rate(v))
+	return r
+}
+
+func TestClassifySection35aCost(t *testing.T) {
+	tests := []struct {
+		description string
+		unit        string
+		want        Section35aCostType
+	}{
+		{description: "Arbeitszeit Monteur", want: Section35aCostTypeLabor},
+		{description: "Wartung Heizungsanlage", want: Section35aCostTypeLabor},
+		{description: "Winterdienst Jänner", want: Section35aCostTypeLabor},
+		{description: "Anfahrt", want: Section35aCostTypeTravel},
+		{description: "Kilometerpauschale", want: Section35aCostTypeTravel},
+		{description: "Anfahrt und Arbeitszeit", want: Section35aCostTypeTravel},
+		{description: "Reinigungsmittel", want: Section35aCostTypeMaterial},
+		{description: "Ersatzteil Thermostat", want: Section35aCostTypeMaterial},
+		{description: "Streusalz 25kg", want: Section35aCostTypeMaterial},
+		{description: "Lieferung und Montage Waschtisch", want: Section35aCostTypeUnclassified},
+		{description: "Reparatur inkl. Material", want: Section35aCostTypeUnclassified},
+		{description: "Anfahrt inkl. Kleinteile", want: Section35aCostTypeUnclassified},
+		{description: "Reinigungsmittel und Reinigung", want: Section35aCostTypeUnclassified},
+		{description: "Position 1", unit: "Std", want: Section35aCostTypeLabor},
+		{description: "Position 2", unit: "km", want: Section35aCostTypeTravel},
+		{description: "Position 3", unit: "Stk", want: Section35aCostTypeMaterial},
+		{description: "Position 4", want: Section35aCostTypeUnclassified},
+	}
+	for _, tt := range tests {
+		t.Run(tt.description, func(t *testing.T) {
+			item := &invoicing.InvoiceItem{
+				Description: nullable.TrimmedString(tt.description),
+				Unit:        nullable.TrimmedString(tt.unit),
+			}
+			if got := ClassifySection35aCost(item); got != tt.want {
+				t.Errorf("ClassifySection35aCost(%q, %q) = %s, want %s", tt.description, tt.unit, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSection35aCostsOfItems(t *testing.T) {
+	item := func(description string, subtotal, taxPercent float64) *invoicing.InvoiceItem {
+		return &invoicing.InvoiceItem{
+			Description: nullable.TrimmedString(description),
+			Subtotal:    amount(subtotal),
+			TaxPercent:  rate(taxPercent),
+		}
+	}
+	inv := &invoicing.Invoice{
+		Items: []*invoicing.InvoiceItem{
+			item("Arbeitszeit", 100, 19),
+			item("Anfahrt", 20, 19),
+			item("Ersatzteil", 50, 19),
+			item("Lieferung und Montage", 200, 19),
+			{Description: "Arbeitszeit Gutschrift", CreditNote: true, Subtotal: amount(10), TaxAmount: amount(1.9)},
+		},
+	}
+	costs, err := Section35aCostsOfItems(inv)
+	if err != nil {
+		t.Fatalf("Section35aCostsOfItems() error = %v", err)
+	}
+	want := Section35aCosts{Labor: 107.1, Travel: 23.8, Material: 59.5, Unclassified: 238}
+	if !costs.Labor.WithinOneCent(want.Labor) || !costs.Travel.WithinOneCent(want.Travel) ||
+		!costs.Material.WithinOneCent(want.Material) || !costs.Unclassified.WithinOneCent(want.Unclassified) {
+		t.Errorf("Section35aCostsOfItems() = %+v, want %+v", costs, want)
+	}
+	if !costs.Eligible().WithinOneCent(130.9) {
+		t.Errorf("Section35aCosts.Eligible() = %f, want 130.9", costs.Eligible())
+	}
+
+	inv.Items = append(inv.Items, &invoicing.InvoiceItem{Description: "Arbeitszeit"})
+	if _, err = Section35aCostsOfItems(inv); err == nil {
+		t.Error("Section35aCostsOfItems() with item without subtotal error = nil")
+	}
+}
@@ -0,0 +1,408 @@
+package realestate
+
+//go:generate go tool go-enum $GOFILE
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/domonda/go-types/money"
+	"github.com/invopop/jsonschema"
+)
+
+// Section35aAllocationKey defines how the §35a amounts of an object
+// are allocated to its units
+type Section35aAllocationKey string //#enum,jsonschema
+
+const (
+	Section35aAllocationKeyArea  Section35aAllocationKey = "AREA"  // Allocation by living area
+	Section35aAllocationKeyShare Section35aAllocationKey = "SHARE" // Allocation by share, like ownership shares (Miteigentumsanteile)
+)
+
+// Valid indicates if k is any of the valid values for Section35aAllocationKey
+func (k Section35aAllocationKey) Valid() bool {
+	switch k {
+	case
+		Section35aAllocationKeyArea,
+		Section35aAllocationKeyShare:
+		return true
+	}
+	return false
+}
+
+// Validate returns an error if k is none of the valid values for Section35aAllocationKey
+func (k Section35aAllocationKey) Validate() error {
+	if !k.Valid() {
+		return fmt.Errorf("invalid value %#v for type realestate.Section35aAllocationKey", k)
+	}
+	return nil
+}
+
+// Enums returns all valid values for Section35aAllocationKey
+func (Section35aAllocationKey) Enums() []Section35aAllocationKey {
+	return []Section35aAllocationKey{
+		Section35aAllocationKeyArea,
+		Section35aAllocationKeyShare,
+	}
+}
+
+// EnumStrings returns all valid values for Section35aAllocationKey as strings
+func (Section35aAllocationKey) EnumStrings() []string {
+	return []string{
+		"AREA",
+		"SHARE",
+	}
+}
+
+// String implements the fmt.Stringer interface for Section35aAllocationKey
+func (k Section35aAllocationKey) String() string {
+	return string(k)
+}
+
+// JSONSchema returns a github.com/invopop/jsonschema.Schema for Section35aAllocationKey
+func (Section35aAllocationKey) JSONSchema() *jsonschema.Schema {
+	return &jsonschema.Schema{
+		Type: "string",
+		Enum: []any{
+			"AREA",
+			"SHARE",
+		},
+	}
+}
+
+// Section35aUnit is a unit of an object like an apartment
+// that §35a amounts are allocated to
+type Section35aUnit struct {
+	// ID of the object the unit belongs to
+	ObjectID string `json:"object_id"`
+	// ID of the unit
+	UnitID string `json:"unit_id"`
+	// Tenant or owner of the unit receiving the statement
+	Tenant string `json:"tenant,omitempty"`
+	// Living area of the unit in square meters
+	Area float64 `json:"area,omitempty"`
+	// Share of the unit like ownership shares (Miteigentumsanteile)
+	Share float64 `json:"share,omitempty"`
+}
+
+// Section35aAmounts are summed up §35a amounts
+type Section35aAmounts struct {
+	Type        Section35aType `json:"type"`
+	NetAmount   money.Amount   `json:"net_amount"`
+	GrossAmount money.Amount   `json:"gross_amount"`
+	// Sum of the eligible costs of the invoice amounts,
+	// see Section35aInvoiceAmount.EligibleCosts
+	EligibleAmount money.Amount `json:"eligible_amount"`
+}
+
+// Section35aUnitStatement is the share of a unit
+// of the §35a amounts of an object
+type Section35aUnitStatement struct {
+	Section35aUnit
+	// Fraction of the object amounts allocated to the unit
+	Fraction float64             `json:"fraction"`
+	Amounts  []Section35aAmounts `json:"amounts"`
+}
+
+// Section35aStatement is the annual §35a statement of an object
+// with the amounts per Section35aType and their allocation to the units
+type Section35aStatement struct {
+	ObjectID      string                    `json:"object_id"`
+	Year          int                       `json:"year"`
+	AllocationKey Section35aAllocationKey   `json:"allocation_key"`
+	InvoiceIDs    []string                  `json:"invoice_ids,omitempty"`
+	Amounts       []Section35aAmounts       `json:"amounts"`
+	Units         []Section35aUnitStatement `json:"units,omitempty"`
+	// Invoices whose service amounts are excluded by CheckSection35aPayment
+	// and paid invoices without PaidDate
+	Excluded []Section35aExcludedInvoice `json:"excluded,omitempty"`
+	// Reason why the amounts could not be allocated to the units
+	AllocationError string `json:"allocation_error,omitempty"`
+}
+
+// NewSection35aStatements aggregates the §35a amounts of the invoices
+// by the IDs of their IdentifiedObjects for the passed calendar year
+// and allocates them to the units of every object using the allocation key.
+//
+// The year of an invoice is the year of its PaidDate
+// because expenses count in the year they are paid (§11 EStG),
+// invoices without PaidDate are not included.
+// Invoices with a paid PaymentStatus but without PaidDate
+// are listed as excluded in the statements of their objects
+// because they might belong to the year.
+// The service amounts of invoices that fail CheckSection35aPayment
+// are not included and the invoices are listed
+// with the reason in the statements of their objects.
+// The amounts of an invoice with multiple identified objects
+// are split evenly between the objects.
+// Objects without units are returned without unit statements.
+// If the amounts of an object can't be allocated to its units,
+// for example because the units have no area,
+// its statement is returned without unit statements
+// and with the reason as AllocationError.
+// The statements are sorted by object ID.
+func NewSection35aStatements(invoices []*Invoice, year int, units []Section35aUnit, key Section35aAllocationKey) ([]*Section35aStatement, error) {
+	if err := key.Validate(); err != nil {
+		return nil, err
+	}
+	statements := make(map[string]*Section35aStatement)
+	statementOf := func(object *Object) *Section35aStatement {
+		objectID := object.ID.String()
+		statement := statements[objectID]
+		if statement == nil {
+			statement = &Section35aStatement{ObjectID: objectID, Year: year, AllocationKey: key}
+			statements[objectID] = statement
+		}
+		return statement
+	}
+	for _, inv := range invoices {
+		if inv == nil || inv.AccountingInvoice == nil || len(inv.Section35aAmounts) == 0 || len(inv.IdentifiedObjects) == 0 {
+			continue
+		}
+		invoiceYear, paid := inv.section35aYear()
+		if !paid && inv.PaymentStatus.IsPaid() {
+			for _, object := range inv.IdentifiedObjects {
+				statement := statementOf(object)
+				statement.Excluded = append(statement.Excluded, Section35aExcludedInvoice{
+					InvoiceID:     inv.InvoiceID.String(),
+					PaymentStatus: inv.PaymentStatus,
+					Reason:        "invoice is paid but has no paid date to determine the year of the payment",
+				})
+			}
+			continue
+		}
+		if !paid || invoiceYear != year {
+			continue
+		}
+		paymentErr := inv.CheckSection35aPayment()
+		if paymentErr != nil {
+			for _, object := range inv.IdentifiedObjects {
+				statement := statementOf(object)
+				statement.Excluded = append(statement.Excluded, Section35aExcludedInvoice{
+					InvoiceID:     inv.InvoiceID.String(),
+					PaymentStatus: inv.PaymentStatus,
+					Reason:        paymentErr.Error(),
+				})
+			}
+		}
+
+		weights := make([]float64, len(inv.IdentifiedObjects))
+		for i := range weights {
+			weights[i] = 1
+		}
+		for _, amount := range inv.Section35aAmounts {
+			if amount == nil || !amount.Type.Valid() {
+				continue
+			}
+			if paymentErr != nil && amount.Type != Section35aTypeFormallyEmployedWorker {
+				continue
+			}
+			nets, err := splitAmount(amount.NetAmount.Get(), weights)
+			if err != nil {
+				return nil, err
+			}
+			grosses, err := splitAmount(amount.GrossAmount.Get(), weights)
+			if err != nil {
+				return nil, err
+			}
+			eligibles, err := splitAmount(amount.EligibleCosts(), weights)
+			if err != nil {
+				return nil, err
+			}
+			for i, object := range inv.IdentifiedObjects {
+				statement := statementOf(object)
+				if id := inv.InvoiceID.String(); id != "" && !slices.Contains(statement.InvoiceIDs, id) {
+					statement.InvoiceIDs = append(statement.InvoiceIDs, id)
+				}
+				sums := statement.amountsOfType(amount.Type)
+				sums.NetAmount += nets[i]
+				sums.GrossAmount += grosses[i]
+				sums.EligibleAmount += eligibles[i]
+			}
+		}
+	}
+
+	result := make([]*Section35aStatement, 0, len(statements))
+	for _, statement := range statements {
+		var objectUnits []Section35aUnit
+		for _, unit := range units {
+			if unit.ObjectID == statement.ObjectID {
+				objectUnits = append(objectUnits, unit)
+			}
+		}
+		if err := statement.allocate(objectUnits); err != nil {
+			statement.Units = nil
+			statement.AllocationError = err.Error()
+		}
+		result = append(result, statement)
+	}
+	slices.SortFunc(result, func(a, b *Section35aStatement) int {
+		switch {
+		case a.ObjectID < b.ObjectID:
+			return -1
+		case a.ObjectID > b.ObjectID:
+			return 1
+		}
+		return 0
+	})
+	return result, nil
+}
+
+// section35aYear returns the year of the PaidDate of the invoice
+// or false if the invoice has no PaidDate
+func (inv *Invoice) section35aYear() (year int, paid bool) {
+	if inv.PaidDate.IsNull() {
+		return 0, false
+	}
+	return inv.PaidDate.Get().Year(), true
+}
+
+func (s *Section35aStatement) amountsOfType(t Section35aType) *Section35aAmounts {
+	index := slices.IndexFunc(s.Amounts, func(a Section35aAmounts) bool { return a.Type == t })
+	if index == -1 {
+		s.Amounts = append(s.Amounts, Section35aAmounts{Type: t})
+		// Keep amounts in the order of the Section35aType enums
+		slices.SortFunc(s.Amounts, func(a, b Section35aAmounts) int {
+			enums := Section35aType("").Enums()
+			return slices.Index(enums, a.Type) - slices.Index(enums, b.Type)
+		})
+		index = slices.IndexFunc(s.Amounts, func(a Section35aAmounts) bool { return a.Type == t })
+	}
+	return &s.Amounts[index]
+}
+
+// allocate splits the amounts of the statement to the units
+// using the allocation key of the statement
+func (s *Section35aStatement) allocate(units []Section35aUnit) error {
+	if len(units) == 0 {
+		return nil
+	}
+	weights := make([]float64, len(units))
+	var weightSum float64
+	for i, unit := range units {
+		switch s.AllocationKey {
+		case Section35aAllocationKeyArea:
+			weights[i] = unit.Area
+		case Section35aAllocationKeyShare:
+			weights[i] = unit.Share
+		}
+		weightSum += weights[i]
+	}
+	if weightSum <= 0 {
+		return fmt.Errorf("units have no %s to allocate by", s.AllocationKey)
+	}
+	s.Units = make([]Section35aUnitStatement, len(units))
+	for i, unit := range units {
+		s.Units[i] = Section35aUnitStatement{
+			Section35aUnit: unit,
+			Fraction:       weights[i] / weightSum,
+			Amounts:        make([]Section35aAmounts, len(s.Amounts)),
+		}
+	}
+	for a, amounts := range s.Amounts {
+		nets, err := splitAmount(amounts.NetAmount, weights)
+		if err != nil {
+			return err
+		}
+		grosses, err := splitAmount(amounts.GrossAmount, weights)
+		if err != nil {
+			return err
+		}
+		eligibles, err := splitAmount(amounts.EligibleAmount, weights)
+		if err != nil {
+			return err
+		}
+		for u := range s.Units {
+			s.Units[u].Amounts[a] = Section35aAmounts{
+				Type:           amounts.Type,
+				NetAmount:      nets[u],
+				GrossAmount:    grosses[u],
+				EligibleAmount: eligibles[u],
+			}
+		}
+	}
+	return nil
+}
+
+// Section35aStatementsCSVHeader is the header row written by WriteSection35aStatementsCSV
+var Section35aStatementsCSVHeader = []string{
+	"object_id",
+	"year",
+	"unit_id",
+	"tenant",
+	"fraction",
+	"type",
+	"net_amount",
+	"gross_amount",
+	"eligible_amount",
+}
+
+// WriteSection35aStatementsCSV writes the statements as CSV
+// with a header row and one row per unit and Section35aType.
+// The amounts of objects without units are written
+// with empty unit_id, tenant and a fraction of 1.
+// Use ';' as comma for spreadsheet applications with German locale,
+// then numbers are written with ',' as decimal separator.
+func WriteSection35aStatementsCSV(w io.Writer, comma rune, statements []*Section35aStatement) error {
+	writer := csv.NewWriter(w)
+	writer.Comma = comma
+	err := writer.Write(Section35aStatementsCSVHeader)
+	if err != nil {
+		return err
+	}
+	formatFloat := func(f float64, prec int) string {
+		s := strconv.FormatFloat(f, 'f', prec, 64)
+		if comma == ';' {
+			s = strings.Replace(s, ".", ",", 1)
+		}
+		return s
+	}
+	formatAmount := func(a money.Amount) string {
+		return formatFloat(float64(a.RoundToCents()), 2)
+	}
+	for _, s := range statements {
+		if s == nil {
+			continue
+		}
+		year := strconv.Itoa(s.Year)
+		units := s.Units
+		if len(units) == 0 {
+			units = []Section35aUnitStatement{{Section35aUnit: Section35aUnit{ObjectID: s.ObjectID}, Fraction: 1, Amounts: s.Amounts}}
+		}
+		for _, unit := range units {
+			for _, amounts := range unit.Amounts {
+				err = writer.Write([]string{
+					s.ObjectID,
+					year,
+					unit.UnitID,
+					unit.Tenant,
+					formatFloat(unit.Fraction, -1),
+					string(amounts.Type),
+					formatAmount(amounts.NetAmount),
+					formatAmount(amounts.GrossAmount),
+					formatAmount(amounts.EligibleAmount),
+				})
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// WriteSection35aStatementsJSON writes the statements as indented JSON array
+func WriteSection35aStatementsJSON(w io.Writer, statements []*Section35aStatement) error {
+	if statements == nil {
+		statements = []*Section35aStatement{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(statements)
+}
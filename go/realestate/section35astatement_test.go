@@ -0,0 +1,168 @@
+package realestate
+
+import (
+	"bytes"
+	"slices"
+	"testing"
+
+	"github.com/domonda/go-types/date"
+	"github.com/domonda/go-types/money"
+	"github.com/domonda/go-types/notnull"
+	"github.com/domonda/go-types/nullable"
+
+	"github.com/docvibe-ai/api/go/invoicing"
+)
+
+// section35aInvoice returns an invoice for the object
+// with one §35a amount of the type and gross amount
+func section35aInvoice(id, objectID, paidDate string, status invoicing.PaymentStatus, amountType Section35aType, gross float64) *Invoice {
+	return &Invoice{
+		AccountingInvoice: &invoicing.AccountingInvoice{
+			Invoice: invoicing.Invoice{
+				InvoiceID:     nullable.TrimmedString(id),
+				IssueDate:     date.NullableDate("2024-12-01"),
+				PaidDate:      date.NullableDate(paidDate),
+				PaymentStatus: status,
+			},
+		},
+		Section35aAmounts: []*Section35aInvoiceAmount{
+			{Type: amountType, NetAmount: amount(gross / 1.19), GrossAmount: amount(gross)},
+		},
+		IdentifiedObjects: []*Object{{ID: notnull.TrimmedString(objectID)}},
+	}
+}
+
+func TestNewSection35aStatements(t *testing.T) {
+	invoices := []*Invoice{
+		section35aInvoice("paid-2025", "object-1", "2025-01-15", invoicing.PaymentStatusPaidWithBankTransfer, Section35aTypeCraftsmanServices, 1000),
+		section35aInvoice("paid-2024", "object-1", "2024-12-20", invoicing.PaymentStatusPaidWithBankTransfer, Section35aTypeCraftsmanServices, 500),
+		section35aInvoice("unpaid", "object-1", "", invoicing.PaymentStatusUnpaid, Section35aTypeCraftsmanServices, 300),
+		section35aInvoice("cash", "object-1", "2025-03-01", invoicing.PaymentStatusPaidWithCash, Section35aTypeHouseholdServices, 200),
+		section35aInvoice("worker", "object-1", "2025-03-01", invoicing.PaymentStatusPaidWithCash, Section35aTypeFormallyEmployedWorker, 100),
+	}
+	units := []Section35aUnit{
+		{ObjectID: "object-1", UnitID: "top-1", Tenant: "Anna", Area: 75},
+		{ObjectID: "object-1", UnitID: "top-2", Tenant: "Ben", Area: 25},
+	}
+	statements, err := NewSection35aStatements(invoices, 2025, units, Section35aAllocationKeyArea)
+	if err != nil {
+		t.Fatalf("NewSection35aStatements() error = %v", err)
+	}
+	if len(statements) != 1 {
+		t.Fatalf("NewSection35aStatements() returned %d statements, want 1", len(statements))
+	}
+	s := statements[0]
+	if want := []string{"paid-2025", "worker"}; !slices.Equal(s.InvoiceIDs, want) {
+		t.Errorf("InvoiceIDs = %v, want %v", s.InvoiceIDs, want)
+	}
+	// Wages of formally employed workers are not checked
+	if len(s.Excluded) != 1 || s.Excluded[0].InvoiceID != "cash" {
+		t.Errorf("Excluded = %+v, want the cash paid household services invoice", s.Excluded)
+	}
+	wantAmounts := map[Section35aType]money.Amount{
+		Section35aTypeFormallyEmployedWorker: 100,
+		Section35aTypeCraftsmanServices:      1000,
+	}
+	if len(s.Amounts) != len(wantAmounts) {
+		t.Fatalf("Amounts = %+v, want %v", s.Amounts, wantAmounts)
+	}
+	for _, a := range s.Amounts {
+		if !a.GrossAmount.WithinOneCent(wantAmounts[a.Type]) {
+			t.Errorf("gross amount of %s = %f, want %f", a.Type, a.GrossAmount, wantAmounts[a.Type])
+		}
+	}
+	if len(s.Units) != 2 {
+		t.Fatalf("Units = %+v, want 2", s.Units)
+	}
+	for _, a := range s.Units[0].Amounts {
+		if want := wantAmounts[a.Type] * 0.75; !a.GrossAmount.WithinOneCent(want) {
+			t.Errorf("unit top-1 gross amount of %s = %f, want %f", a.Type, a.GrossAmount, want)
+		}
+	}
+
+	if _, err = NewSection35aStatements(invoices, 2025, units, "INVALID"); err == nil {
+		t.Error("NewSection35aStatements() with invalid allocation key error = nil")
+	}
+}
+
+func TestNewSection35aStatements_paidWithoutPaidDate(t *testing.T) {
+	invoices := []*Invoice{
+		section35aInvoice("no-paid-date", "object-1", "", invoicing.PaymentStatusPaidWithBankTransfer, Section35aTypeCraftsmanServices, 1000),
+	}
+	statements, err := NewSection35aStatements(invoices, 2025, nil, Section35aAllocationKeyArea)
+	if err != nil {
+		t.Fatalf("NewSection35aStatements() error = %v", err)
+	}
+	if len(statements) != 1 {
+		t.Fatalf("NewSection35aStatements() returned %d statements, want 1", len(statements))
+	}
+	s := statements[0]
+	if len(s.Excluded) != 1 || s.Excluded[0].InvoiceID != "no-paid-date" {
+		t.Errorf("Excluded = %+v, want the paid invoice without paid date", s.Excluded)
+	}
+	if len(s.Amounts) != 0 || len(s.InvoiceIDs) != 0 {
+		t.Errorf("Amounts = %+v, InvoiceIDs = %v, want none", s.Amounts, s.InvoiceIDs)
+	}
+}
+
+func TestNewSection35aStatements_allocationError(t *testing.T) {
+	invoices := []*Invoice{
+		section35aInvoice("invoice-1", "object-1", "2025-01-15", invoicing.PaymentStatusPaidWithBankTransfer, Section35aTypeCraftsmanServices, 1000),
+		section35aInvoice("invoice-2", "object-2", "2025-01-15", invoicing.PaymentStatusPaidWithBankTransfer, Section35aTypeCraftsmanServices, 500),
+	}
+	units := []Section35aUnit{
+		{ObjectID: "object-1", UnitID: "top-1"},
+		{ObjectID: "object-2", UnitID: "top-1", Area: 50},
+	}
+	statements, err := NewSection35aStatements(invoices, 2025, units, Section35aAllocationKeyArea)
+	if err != nil {
+		t.Fatalf("NewSection35aStatements() error = %v", err)
+	}
+	if len(statements) != 2 {
+		t.Fatalf("NewSection35aStatements() returned %d statements, want 2", len(statements))
+	}
+	if s := statements[0]; s.AllocationError == "" || len(s.Units) != 0 || len(s.Amounts) != 1 {
+		t.Errorf("statement of units without area = %+v, want amounts without units and an allocation error", s)
+	}
+	if s := statements[1]; s.AllocationError != "" || len(s.Units) != 1 {
+		t.Errorf("statement of object-2 = %+v, want allocated units", s)
+	}
+}
+
+func TestWriteSection35aStatementsCSV(t *testing.T) {
+	statements := []*Section35aStatement{{
+		ObjectID:      "object-1",
+		Year:          2025,
+		AllocationKey: Section35aAllocationKeyShare,
+		Amounts:       []Section35aAmounts{{Type: Section35aTypeCraftsmanServices, NetAmount: 840.34, GrossAmount: 1000, EligibleAmount: 600}},
+	}}
+	tests := []struct {
+		name  string
+		comma rune
+		want  string
+	}{
+		{
+			name:  "comma",
+			comma: ',',
+			want: "object_id,year,unit_id,tenant,fraction,type,net_amount,gross_amount,eligible_amount\n" +
+				"object-1,2025,,,1,CRAFTSMAN_SERVICES,840.34,1000.00,600.00\n",
+		},
+		{
+			name:  "German locale",
+			comma: ';',
+			want: "object_id;year;unit_id;tenant;fraction;type;net_amount;gross_amount;eligible_amount\n" +
+				"object-1;2025;;;1;CRAFTSMAN_SERVICES;840,34;1000,00;600,00\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteSection35aStatementsCSV(&buf, tt.comma, statements); err != nil {
+				t.Fatalf("WriteSection35aStatementsCSV() error = %v", err)
+			}
+			if buf.String() != tt.want {
+				t.Errorf("WriteSection35aStatementsCSV() =\n%s\nwant\n%s", buf.String(), tt.want)
+			}
+		})
+	}
+}
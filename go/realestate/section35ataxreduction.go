@@ -0,0 +1,241 @@
+package realestate
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/domonda/go-types/money"
+
+	"github.com/docvibe-ai/api/go/invoicing"
+)
+
+const (
+	// Section35aTaxReductionPercent is the percentage of the eligible costs
+	// that reduces the income tax according to §35a EStG
+	Section35aTaxReductionPercent = 20
+
+	// Section35aMaxTaxReductionHousehold is the maximum yearly tax reduction
+	// for employment in a private household and household services (§35a Abs. 2 EStG)
+	Section35aMaxTaxReductionHousehold money.Amount = 4000
+
+	// Section35aMaxTaxReductionCraftsman is the maximum yearly tax reduction
+	// for craftsman services (§35a Abs. 3 EStG)
+	Section35aMaxTaxReductionCraftsman money.Amount = 1200
+)
+
+// CheckSection35aPayment returns an error if the invoice has §35a amounts
+// but was not paid by bank transfer or direct debit.
+// The tax office only accepts §35a costs for services
+// that were paid to the account of the service provider.
+// Wages of formally employed workers are not paid by invoice
+// and are not checked.
+func (inv *Invoice) CheckSection35aPayment() error {
+	if inv == nil || inv.AccountingInvoice == nil {
+		return nil
+	}
+	if !inv.hasSection35aServiceAmounts() {
+		return nil
+	}
+	switch status := inv.PaymentStatus; {
+	case status == invoicing.PaymentStatusPaidWithCash:
+		return errors.New("§35a amounts of invoice paid with cash are not tax deductible")
+	case !status.IsCashless():
+		return fmt.Errorf("§35a amounts are only tax deductible if paid by bank transfer or direct debit to the account of the service provider, but payment status is %q", status)
+	}
+	return nil
+}
+
+func (inv *Invoice) hasSection35aServiceAmounts() bool {
+	for _, amount := range inv.Section35aAmounts {
+		if amount != nil && amount.Type != Section35aTypeFormallyEmployedWorker {
+			return true
+		}
+	}
+	return false
+}
+
+// Section35aTaxReductionCategory is a group of Section35aTypes
+// that share a maximum yearly tax reduction
+type Section35aTaxReductionCategory struct {
+	Types []Section35aType `json:"types"`
+	// Sum of the eligible costs of the category
+	EligibleCosts money.Amount `json:"eligible_costs"`
+	// Maximum yearly tax reduction of the category
+	MaxTaxReduction money.Amount `json:"max_tax_reduction"`
+	// Tax reduction of 20% of the eligible costs capped to MaxTaxReduction
+	TaxReduction money.Amount `json:"tax_reduction"`
+}
+
+// Section35aExcludedInvoice is an invoice whose §35a amounts
+// were excluded from the tax reduction
+type Section35aExcludedInvoice struct {
+	InvoiceID     string                  `json:"invoice_id"`
+	PaymentStatus invoicing.PaymentStatus `json:"payment_status"`
+	Reason        string                  `json:"reason"`
+}
+
+// Section35aTaxReduction is the yearly tax reduction
+// according to §35a EStG per category of a taxpayer
+type Section35aTaxReduction struct {
+	Year int `json:"year"`
+	// Tenant or owner the tax reduction was calculated for,
+	// empty if calculated directly from invoices
+	Tenant     string                           `json:"tenant,omitempty"`
+	Categories []Section35aTaxReductionCategory `json:"categories"`
+	// Sum of the tax reductions of all categories
+	TaxReduction money.Amount `json:"tax_reduction"`
+	// Invoices excluded by CheckSection35aPayment
+	Excluded []Section35aExcludedInvoice `json:"excluded,omitempty"`
+}
+
+func newSection35aTaxReduction(year int) *Section35aTaxReduction {
+	return &Section35aTaxReduction{
+		Year: year,
+		Categories: []Section35aTaxReductionCategory{
+			{
+				Types:           []Section35aType{Section35aTypeFormallyEmployedWorker, Section35aTypeHouseholdServices},
+				MaxTaxReduction: Section35aMaxTaxReductionHousehold,
+			},
+			{
+				Types:           []Section35aType{Section35aTypeCraftsmanServices},
+				MaxTaxReduction: Section35aMaxTaxReductionCraftsman,
+			},
+		},
+	}
+}
+
+// EligibleCosts returns the EligibleAmount
+// if the labor and material split is known, else the GrossAmount
+func (a *Section35aInvoiceAmount) EligibleCosts() money.Amount {
+	if a.EligibleAmount.IsNotNull() {
+		return a.EligibleAmount.Get()
+	}
+	return a.GrossAmount.Get()
+}
+
+// NewSection35aTaxReduction calculates the capped tax reduction
+// of the §35a amounts of the invoices paid in the passed year
+// by a single taxpayer, because the maximums apply per taxpayer.
+// Use NewSection35aTenantTaxReductions for the invoices
+// of objects that are allocated to multiple tenants.
+// Invoices without PaidDate are not included (§11 EStG).
+//
+// Employment in a private household and household services
+// share the maximum of 4,000 € (§35a Abs. 2 EStG),
+// craftsman services have a maximum of 1,200 € (§35a Abs. 3 EStG).
+// The eligible costs of an amount are its EligibleCosts.
+// Invoices that fail CheckSection35aPayment are excluded
+// and listed with the reason in the result.
+func NewSection35aTaxReduction(invoices []*Invoice, year int) (*Section35aTaxReduction, error) {
+	result := newSection35aTaxReduction(year)
+	for _, inv := range invoices {
+		if inv == nil || inv.AccountingInvoice == nil || len(inv.Section35aAmounts) == 0 {
+			continue
+		}
+		if invoiceYear, paid := inv.section35aYear(); !paid || invoiceYear != year {
+			continue
+		}
+		paymentErr := inv.CheckSection35aPayment()
+		if paymentErr != nil {
+			result.Excluded = append(result.Excluded, Section35aExcludedInvoice{
+				InvoiceID:     inv.InvoiceID.String(),
+				PaymentStatus: inv.PaymentStatus,
+				Reason:        paymentErr.Error(),
+			})
+		}
+		for _, amount := range inv.Section35aAmounts {
+			if amount == nil {
+				continue
+			}
+			if paymentErr != nil && amount.Type != Section35aTypeFormallyEmployedWorker {
+				continue
+			}
+			category := result.category(amount.Type)
+			if category == nil {
+				return nil, fmt.Errorf("invoice %s: %w", inv.InvoiceID.String(), amount.Type.Validate())
+			}
+			category.EligibleCosts += amount.EligibleCosts()
+		}
+	}
+	result.capTaxReductions()
+	return result, nil
+}
+
+// NewSection35aTenantTaxReductions calculates the capped tax reduction
+// per tenant from the unit statements of the §35a statements,
+// so that the maximums apply per tenant and not to all invoices.
+// The amounts of all units of a tenant are summed up per year,
+// units without tenant are not included.
+// The invoices excluded from the statements of the objects
+// of a tenant's units are listed in the tenant's tax reduction.
+// The tax reductions are sorted by tenant and year.
+func NewSection35aTenantTaxReductions(statements []*Section35aStatement) ([]*Section35aTaxReduction, error) {
+	var result []*Section35aTaxReduction
+	for _, statement := range statements {
+		if statement == nil {
+			continue
+		}
+		for _, unit := range statement.Units {
+			if unit.Tenant == "" {
+				continue
+			}
+			index := slices.IndexFunc(result, func(r *Section35aTaxReduction) bool {
+				return r.Tenant == unit.Tenant && r.Year == statement.Year
+			})
+			if index == -1 {
+				index = len(result)
+				reduction := newSection35aTaxReduction(statement.Year)
+				reduction.Tenant = unit.Tenant
+				result = append(result, reduction)
+			}
+			reduction := result[index]
+			for _, amounts := range unit.Amounts {
+				category := reduction.category(amounts.Type)
+				if category == nil {
+					return nil, fmt.Errorf("object %s unit %s: %w", statement.ObjectID, unit.UnitID, amounts.Type.Validate())
+				}
+				category.EligibleCosts += amounts.EligibleAmount
+			}
+			for _, excluded := range statement.Excluded {
+				if !slices.Contains(reduction.Excluded, excluded) {
+					reduction.Excluded = append(reduction.Excluded, excluded)
+				}
+			}
+		}
+	}
+	for _, reduction := range result {
+		reduction.capTaxReductions()
+	}
+	slices.SortFunc(result, func(a, b *Section35aTaxReduction) int {
+		if c := strings.Compare(a.Tenant, b.Tenant); c != 0 {
+			return c
+		}
+		return a.Year - b.Year
+	})
+	return result, nil
+}
+
+// capTaxReductions calculates the tax reduction of every category
+// capped to its maximum and the sum of all categories
+func (r *Section35aTaxReduction) capTaxReductions() {
+	r.TaxReduction = 0
+	for i := range r.Categories {
+		category := &r.Categories[i]
+		reduction := (category.EligibleCosts * Section35aTaxReductionPercent / 100).RoundToCents()
+		category.TaxReduction = min(max(reduction, 0), category.MaxTaxReduction)
+		r.TaxReduction += category.TaxReduction
+	}
+}
+
+func (r *Section35aTaxReduction) category(t Section35aType) *Section35aTaxReductionCategory {
+	for i := range r.Categories {
+		for _, categoryType := range r.Categories[i].Types {
+			if categoryType == t {
+				return &r.Categories[i]
+			}
+		}
+	}
+	return nil
+}
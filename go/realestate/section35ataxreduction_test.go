@@ -0,0 +1,116 @@
+package realestate
+
+import (
+	"testing"
+
+	"github.com/domonda/go-types/money"
+
+	"github.com/docvibe-ai/api/go/invoicing"
+)
+
+func TestInvoice_CheckSection35aPayment(t *testing.T) {
+	tests := []struct {
+		status     invoicing.PaymentStatus
+		amountType Section35aType
+		wantErr    bool
+	}{
+		{status: invoicing.PaymentStatusPaidWithBankTransfer, amountType: Section35aTypeCraftsmanServices},
+		{status: invoicing.PaymentStatusPaidWithDirectDebit, amountType: Section35aTypeHouseholdServices},
+		{status: invoicing.PaymentStatusPaidWithCash, amountType: Section35aTypeCraftsmanServices, wantErr: true},
+		{status: invoicing.PaymentStatusPaidWithCreditcard, amountType: Section35aTypeCraftsmanServices, wantErr: true},
+		{status: invoicing.PaymentStatusPaidWithPaypal, amountType: Section35aTypeHouseholdServices, wantErr: true},
+		{status: invoicing.PaymentStatusUnpaid, amountType: Section35aTypeCraftsmanServices, wantErr: true},
+		{status: "", amountType: Section35aTypeCraftsmanServices, wantErr: true},
+		{status: invoicing.PaymentStatusPaidWithCash, amountType: Section35aTypeFormallyEmployedWorker},
+	}
+	for _, tt := range tests {
+		t.Run(string(tt.status)+" "+string(tt.amountType), func(t *testing.T) {
+			inv := section35aInvoice("1", "object-1", "2025-01-01", tt.status, tt.amountType, 100)
+			if err := inv.CheckSection35aPayment(); (err != nil) != tt.wantErr {
+				t.Errorf("Invoice.CheckSection35aPayment() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestNewSection35aTaxReduction(t *testing.T) {
+	withEligible := func(inv *Invoice, eligible float64) *Invoice {
+		inv.Section35aAmounts[0].EligibleAmount = amount(eligible)
+		return inv
+	}
+	invoices := []*Invoice{
+		section35aInvoice("craftsman", "object-1", "2025-02-01", invoicing.PaymentStatusPaidWithBankTransfer, Section35aTypeCraftsmanServices, 10000),
+		withEligible(section35aInvoice("household", "object-1", "2025-02-01", invoicing.PaymentStatusPaidWithDirectDebit, Section35aTypeHouseholdServices, 2000), 1500),
+		section35aInvoice("cash", "object-1", "2025-02-01", invoicing.PaymentStatusPaidWithCash, Section35aTypeHouseholdServices, 1000),
+		section35aInvoice("unpaid", "object-1", "", invoicing.PaymentStatusUnpaid, Section35aTypeHouseholdServices, 1000),
+		section35aInvoice("last-year", "object-1", "2024-12-31", invoicing.PaymentStatusPaidWithBankTransfer, Section35aTypeHouseholdServices, 1000),
+	}
+	reduction, err := NewSection35aTaxReduction(invoices, 2025)
+	if err != nil {
+		t.Fatalf("NewSection35aTaxReduction() error = %v", err)
+	}
+	household, craftsman := reduction.Categories[0], reduction.Categories[1]
+	if household.EligibleCosts != 1500 || household.TaxReduction != 300 {
+		t.Errorf("household category = %+v, want eligible costs 1500 and tax reduction 300", household)
+	}
+	// 20% of 10000 capped to 1200
+	if craftsman.EligibleCosts != 10000 || craftsman.TaxReduction != Section35aMaxTaxReductionCraftsman {
+		t.Errorf("craftsman category = %+v, want eligible costs 10000 and tax reduction 1200", craftsman)
+	}
+	if reduction.TaxReduction != 1500 {
+		t.Errorf("TaxReduction = %f, want 1500", reduction.TaxReduction)
+	}
+	if len(reduction.Excluded) != 1 || reduction.Excluded[0].InvoiceID != "cash" {
+		t.Errorf("Excluded = %+v, want the cash paid invoice", reduction.Excluded)
+	}
+}
+
+func TestNewSection35aTenantTaxReductions(t *testing.T) {
+	unitAmounts := func(craftsman money.Amount) []Section35aAmounts {
+		return []Section35aAmounts{{Type: Section35aTypeCraftsmanServices, GrossAmount: craftsman, EligibleAmount: craftsman}}
+	}
+	excluded := Section35aExcludedInvoice{InvoiceID: "cash", PaymentStatus: invoicing.PaymentStatusPaidWithCash}
+	statements := []*Section35aStatement{
+		{
+			ObjectID: "object-1",
+			Year:     2025,
+			Excluded: []Section35aExcludedInvoice{excluded},
+			Units: []Section35aUnitStatement{
+				{Section35aUnit: Section35aUnit{ObjectID: "object-1", UnitID: "top-1", Tenant: "Ben"}, Amounts: unitAmounts(4000)},
+				{Section35aUnit: Section35aUnit{ObjectID: "object-1", UnitID: "top-2", Tenant: "Anna"}, Amounts: unitAmounts(4000)},
+				{Section35aUnit: Section35aUnit{ObjectID: "object-1", UnitID: "top-3"}, Amounts: unitAmounts(4000)},
+			},
+		},
+		{
+			ObjectID: "object-2",
+			Year:     2025,
+			Excluded: []Section35aExcludedInvoice{excluded},
+			Units: []Section35aUnitStatement{
+				{Section35aUnit: Section35aUnit{ObjectID: "object-2", UnitID: "garage", Tenant: "Anna"}, Amounts: unitAmounts(4000)},
+			},
+		},
+	}
+	reductions, err := NewSection35aTenantTaxReductions(statements)
+	if err != nil {
+		t.Fatalf("NewSection35aTenantTaxReductions() error = %v", err)
+	}
+	want := []struct {
+		tenant       string
+		costs        money.Amount
+		taxReduction money.Amount
+		excluded     int
+	}{
+		// 20% of 8000 capped to 1200
+		{tenant: "Anna", costs: 8000, taxReduction: 1200, excluded: 1},
+		{tenant: "Ben", costs: 4000, taxReduction: 800, excluded: 1},
+	}
+	if len(reductions) != len(want) {
+		t.Fatalf("NewSection35aTenantTaxReductions() returned %d tax reductions, want %d", len(reductions), len(want))
+	}
+	for i, w := range want {
+		r := reductions[i]
+		if r.Tenant != w.tenant || r.Year != 2025 || r.Categories[1].EligibleCosts != w.costs || r.TaxReduction != w.taxReduction || len(r.Excluded) != w.excluded {
+			t.Errorf("tax reduction %d = %+v, want tenant %s with costs %f, tax reduction %f and %d excluded", i, r, w.tenant, w.costs, w.taxReduction, w.excluded)
+		}
+	}
+}
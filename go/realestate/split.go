@@ -0,0 +1,67 @@
+package realestate
+
+import (
+	"errors"
+	"math"
+	"slices"
+
+	"github.com/domonda/go-types/money"
+)
+
+// splitAmount splits amount proportionally to the weights
+// into amounts rounded to cents that sum up exactly to amount
+// rounded to cents.
+// Remaining cents after rounding down are distributed
+// to the parts with the largest rounding remainders.
+func splitAmount(amount money.Amount, weights []float64) ([]money.Amount, error) {
+	var weightSum float64
+	for _, w := range weights {
+		if w < 0 || math.IsNaN(w) || math.IsInf(w, 0) {
+			return nil, errors.New("weights must be positive finite numbers")
+		}
+		weightSum += w
+	}
+	if weightSum == 0 {
+		return nil, errors.New("sum of weights is zero")
+	}
+
+	totalCents := int64(math.Round(float64(amount) * 100))
+	sign := int64(1)
+	if totalCents < 0 {
+		sign = -1
+		totalCents = -totalCents
+	}
+	cents := make([]int64, len(weights))
+	remainders := make([]float64, len(weights))
+	var distributed int64
+	for i, w := range weights {
+		exact := float64(totalCents) * w / weightSum
+		cents[i] = int64(math.Floor(exact))
+		remainders[i] = exact - float64(cents[i])
+		distributed += cents[i]
+	}
+	order := make([]int, len(weights))
+	for i := range order {
+		order[i] = i
+	}
+	// Stable sort keeps the original order for equal remainders
+	slices.SortStableFunc(order, func(a, b int) int {
+		switch {
+		case remainders[a] > remainders[b]:
+			return -1
+		case remainders[a] < remainders[b]:
+			return 1
+		}
+		return 0
+	})
+	for i := 0; distributed < totalCents; i++ {
+		cents[order[i%len(order)]]++
+		distributed++
+	}
+
+	result := make([]money.Amount, len(weights))
+	for i, c := range cents {
+		result[i] = money.Amount(sign*c) / 100
+	}
+	return result, nil
+}
@@ -0,0 +1,602 @@
+package realestate
+
+//go:generate go tool go-enum $GOFILE
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/domonda/go-types/date"
+	"github.com/domonda/go-types/money"
+	"github.com/domonda/go-types/notnull"
+	"github.com/domonda/go-types/nullable"
+	"github.com/invopop/jsonschema"
+)
+
+// UtilityInvoice is an energy, heating or water bill
+// with the meters, readings and tariff components of the billing period
+type UtilityInvoice struct {
+	*Invoice
+
+	// Utility billed by the invoice
+	Utility UtilityType `json:"utility"`
+	// Meters billed by the invoice
+	Meters []*Meter `json:"meters,omitempty"`
+	// Tariff components like base and consumption price
+	TariffComponents []*TariffComponent `json:"tariff_components,omitempty"`
+}
+
+// Meter is a utility meter with its readings
+// and consumption in the billing period
+type Meter struct {
+	// Meter number
+	ID notnull.TrimmedString `json:"id"`
+	// Market or metering location ID, like the Marktlokation or Zählpunkt
+	LocationID nullable.TrimmedString `json:"location_id,omitempty"`
+	// ID of the real estate object the meter belongs to
+	ObjectID nullable.TrimmedString `json:"object_id,omitempty"`
+	// Unit of the readings and the consumption
+	Unit ConsumptionUnit `json:"unit"`
+	// Start date of the billing period of the meter
+	PeriodStart date.NullableDate `json:"period_start,omitempty"`
+	// End date of the billing period of the meter
+	PeriodEnd date.NullableDate `json:"period_end,omitempty"`
+	// Meter readings in the billing period
+	Readings []*MeterReading `json:"readings,omitempty"`
+	// Consumption in the billing period in the unit of the meter
+	Consumption nullable.Type[float64] `json:"consumption,omitempty,omitzero"`
+	// Factor converting the consumption to kWh,
+	// like calorific value times Z-number for gas meters in m³
+	ConversionFactor nullable.Type[float64] `json:"conversion_factor,omitempty,omitzero"`
+	// Consumption in the billing period in kWh
+	ConsumptionKWh nullable.Type[float64] `json:"consumption_kwh,omitempty,omitzero"`
+}
+
+// MeterReading is a reading of a meter
+type MeterReading struct {
+	// Date of the reading
+	Date date.NullableDate `json:"date"`
+	// Meter value in the unit of the meter
+	Value float64 `json:"value"`
+	// Reading was estimated instead of read from the meter
+	Estimated bool `json:"estimated,omitempty"`
+}
+
+// TariffComponent is a price component of a utility invoice
+type TariffComponent struct {
+	// Type of the tariff component
+	Type TariffComponentType `json:"type"`
+	// Description of the tariff component as printed on the invoice
+	Description nullable.TrimmedString `json:"description,omitempty"`
+	// ID of the meter the component belongs to
+	MeterID nullable.TrimmedString `json:"meter_id,omitempty"`
+	// Start date of the period of the component
+	PeriodStart date.NullableDate `json:"period_start,omitempty"`
+	// End date of the period of the component
+	PeriodEnd date.NullableDate `json:"period_end,omitempty"`
+	// Quantity like consumption or number of days
+	Quantity nullable.Type[float64] `json:"quantity,omitempty,omitzero"`
+	// Unit of the quantity
+	Unit nullable.TrimmedString `json:"unit,omitempty"`
+	// Net price per unit of the quantity
+	UnitPrice money.NullableAmount `json:"unit_price,omitempty,omitzero"`
+	// Net amount of the component
+	NetAmount money.Amount `json:"net_amount"`
+	// Tax percentage of the component
+	TaxPercent money.NullableRate `json:"tax_percent,omitempty,omitzero"`
+}
+
+// Normalize validates and normalizes all fields of the UtilityInvoice
+// including the embedded Invoice, the Meters and the TariffComponents.
+// It returns an aggregated error of all validation issues found.
+//
+// Meters without billing period get the period of the invoice.
+// The billing periods of meters and tariff components and the reading dates
+// must be within the period of the invoice.
+// A missing consumption of a meter is calculated from its readings.
+func (inv *UtilityInvoice) Normalize() error {
+	if inv == nil {
+		return nil
+	}
+	var err, result error
+	if inv.Invoice == nil {
+		result = errors.Join(result, errors.New("missing real estate invoice"))
+	} else if err = inv.Invoice.Normalize(); err != nil {
+		result = errors.Join(result, err)
+	}
+	if err = inv.Utility.Validate(); err != nil {
+		result = errors.Join(result, err)
+		inv.Utility = ""
+	}
+
+	var periodStart, periodEnd date.NullableDate
+	if inv.Invoice != nil && inv.AccountingInvoice != nil {
+		periodStart, periodEnd = inv.PeriodStart, inv.PeriodEnd
+	}
+
+	inv.Meters = slices.DeleteFunc(inv.Meters, func(meter *Meter) bool {
+		return meter == nil || meter.ID.IsEmpty() && len(meter.Readings) == 0 && meter.Consumption.IsNull()
+	})
+	for i, meter := range inv.Meters {
+		if meter.PeriodStart.IsNull() {
+			meter.PeriodStart = periodStart
+		}
+		if meter.PeriodEnd.IsNull() {
+			meter.PeriodEnd = periodEnd
+		}
+		if err = meter.Normalize(); err != nil {
+			result = errors.Join(result, fmt.Errorf("invalid meter %d: %w", i, err))
+		}
+		if err = checkPeriodWithin(meter.PeriodStart, meter.PeriodEnd, periodStart, periodEnd); err != nil {
+			result = errors.Join(result, fmt.Errorf("meter %s: %w", meter.ID, err))
+		}
+		if slices.ContainsFunc(inv.Meters[:i], func(m *Meter) bool { return m.ID == meter.ID }) {
+			result = errors.Join(result, fmt.Errorf("duplicate meter ID %s", meter.ID))
+		}
+	}
+
+	inv.TariffComponents = slices.DeleteFunc(inv.TariffComponents, func(component *TariffComponent) bool {
+		return component == nil || *component == TariffComponent{}
+	})
+	var netSum money.Amount
+	for i, component := range inv.TariffComponents {
+		if err = component.Normalize(); err != nil {
+			result = errors.Join(result, fmt.Errorf("invalid tariff component %d: %w", i, err))
+		}
+		if err = checkPeriodWithin(component.PeriodStart, component.PeriodEnd, periodStart, periodEnd); err != nil {
+			result = errors.Join(result, fmt.Errorf("tariff component %d: %w", i, err))
+		}
+		if component.MeterID.IsNotNull() && !slices.ContainsFunc(inv.Meters, func(m *Meter) bool { return m.ID.String() == component.MeterID.String() }) {
+			result = errors.Join(result, fmt.Errorf("tariff component %d references unknown meter %s", i, component.MeterID))
+		}
+		netSum += component.NetAmount
+	}
+	if len(inv.TariffComponents) > 0 && inv.Invoice != nil && inv.AccountingInvoice != nil && inv.Subtotal.IsNotNull() {
+		tolerance := money.Amount(len(inv.TariffComponents)) * 0.01
+		if (netSum - inv.Subtotal.Get()).Abs() > tolerance {
+			result = errors.Join(result, fmt.Errorf("sum of tariff components %f does not match invoice subtotal %f", netSum, inv.Subtotal.Get()))
+		}
+	}
+	return result
+}
+
+// Normalize validates and normalizes all fields of the Meter.
+// It returns an aggregated error of all validation issues found.
+// Readings are sorted by date and must be within the billing period.
+// A missing consumption is calculated from the first and last reading,
+// a missing consumption in kWh from the consumption and conversion factor.
+func (m *Meter) Normalize() error {
+	if m == nil {
+		return nil
+	}
+	var err, result error
+	if m.ID.IsEmpty() {
+		result = errors.Join(result, errors.New("meter ID is empty"))
+	}
+	if err = m.Unit.Validate(); err != nil {
+		result = errors.Join(result, err)
+		m.Unit = ""
+	}
+	if m.PeriodStart, err = m.PeriodStart.Normalized(); err != nil {
+		result = errors.Join(result, fmt.Errorf("invalid period start date: %w", err))
+		m.PeriodStart.SetNull()
+	}
+	if m.PeriodEnd, err = m.PeriodEnd.Normalized(); err != nil {
+		result = errors.Join(result, fmt.Errorf("invalid period end date: %w", err))
+		m.PeriodEnd.SetNull()
+	}
+	if m.PeriodStart.IsNotNull() && m.PeriodEnd.IsNotNull() && m.PeriodStart.Get().After(m.PeriodEnd.Get()) {
+		result = errors.Join(result, fmt.Errorf("period start date %s is after period end date %s", m.PeriodStart.Get(), m.PeriodEnd.Get()))
+		m.PeriodStart.SetNull()
+	}
+
+	m.Readings = slices.DeleteFunc(m.Readings, func(reading *MeterReading) bool {
+		return reading == nil || *reading == MeterReading{}
+	})
+	for i, reading := range m.Readings {
+		if reading.Date, err = reading.Date.Normalized(); err != nil {
+			result = errors.Join(result, fmt.Errorf("invalid date of reading %d: %w", i, err))
+			reading.Date.SetNull()
+		}
+		if reading.Date.IsNull() {
+			result = errors.Join(result, fmt.Errorf("reading %d has no date", i))
+			continue
+		}
+		if err = checkPeriodWithin(reading.Date, reading.Date, m.PeriodStart, m.PeriodEnd); err != nil {
+			result = errors.Join(result, fmt.Errorf("reading %d: %w", i, err))
+		}
+		if reading.Value < 0 {
+			result = errors.Join(result, fmt.Errorf("reading %d has negative value %f", i, reading.Value))
+		}
+	}
+	// Readings without date are sorted last
+	slices.SortStableFunc(m.Readings, func(a, b *MeterReading) int {
+		switch {
+		case a.Date.IsNull() && b.Date.IsNull():
+			return 0
+		case a.Date.IsNull():
+			return 1
+		case b.Date.IsNull():
+			return -1
+		case a.Date.Get().Before(b.Date.Get()):
+			return -1
+		case a.Date.Get().After(b.Date.Get()):
+			return 1
+		}
+		return 0
+	})
+
+	if m.Consumption.IsNotNull() && m.Consumption.Get() < 0 {
+		result = errors.Join(result, fmt.Errorf("consumption %f is negative", m.Consumption.Get()))
+		m.Consumption.Set(math.Abs(m.Consumption.Get()))
+	}
+	if consumption, ok := m.readingsConsumption(); ok {
+		switch {
+		case consumption < 0:
+			result = errors.Join(result, fmt.Errorf("last reading is lower than first reading by %f", -consumption))
+		case m.Consumption.IsNull():
+			m.Consumption.Set(consumption)
+		case !withinRelativeTolerance(m.Consumption.Get(), consumption):
+			result = errors.Join(result, fmt.Errorf("consumption %f does not match readings difference %f", m.Consumption.Get(), consumption))
+		}
+	}
+
+	if m.ConversionFactor.IsNotNull() && m.Unit == ConsumptionUnitUnits {
+		result = errors.Join(result, errors.New("heat cost allocator units can't be converted to kWh"))
+		m.ConversionFactor.SetNull()
+	}
+	if m.ConversionFactor.IsNotNull() && m.ConversionFactor.Get() <= 0 {
+		result = errors.Join(result, fmt.Errorf("conversion factor %f is not positive", m.ConversionFactor.Get()))
+		m.ConversionFactor.SetNull()
+	}
+	if m.ConsumptionKWh.IsNotNull() && m.ConsumptionKWh.Get() < 0 {
+		result = errors.Join(result, fmt.Errorf("consumption in kWh %f is negative", m.ConsumptionKWh.Get()))
+		m.ConsumptionKWh.Set(math.Abs(m.ConsumptionKWh.Get()))
+	}
+	if m.Consumption.IsNotNull() {
+		factor, hasFactor := m.ConversionFactor.Get(), m.ConversionFactor.IsNotNull()
+		if !hasFactor {
+			factor, hasFactor = m.Unit.KWhFactor()
+		}
+		if hasFactor {
+			kwh := m.Consumption.Get() * factor
+			switch {
+			case m.ConsumptionKWh.IsNull():
+				m.ConsumptionKWh.Set(kwh)
+			case !withinRelativeTolerance(m.ConsumptionKWh.Get(), kwh):
+				result = errors.Join(result, fmt.Errorf("consumption in kWh %f does not match consumption %f times conversion factor %f", m.ConsumptionKWh.Get(), m.Consumption.Get(), factor))
+			}
+		}
+	}
+	return result
+}
+
+// readingsConsumption returns the difference between the last
+// and the first reading with date or false if there are not two such readings
+func (m *Meter) readingsConsumption() (float64, bool) {
+	var first, last *MeterReading
+	for _, reading := range m.Readings {
+		if reading.Date.IsNull() {
+			continue
+		}
+		if first == nil {
+			first = reading
+		}
+		last = reading
+	}
+	if first == nil || first == last {
+		return 0, false
+	}
+	return last.Value - first.Value, true
+}
+
+// Normalize validates and normalizes all fields of the TariffComponent.
+// It returns an aggregated error of all validation issues found.
+func (c *TariffComponent) Normalize() error {
+	if c == nil {
+		return nil
+	}
+	var err, result error
+	if err = c.Type.Validate(); err != nil {
+		result = errors.Join(result, err)
+		c.Type = ""
+	}
+	if c.PeriodStart, err = c.PeriodStart.Normalized(); err != nil {
+		result = errors.Join(result, fmt.Errorf("invalid period start date: %w", err))
+		c.PeriodStart.SetNull()
+	}
+	if c.PeriodEnd, err = c.PeriodEnd.Normalized(); err != nil {
+		result = errors.Join(result, fmt.Errorf("invalid period end date: %w", err))
+		c.PeriodEnd.SetNull()
+	}
+	if c.PeriodStart.IsNotNull() && c.PeriodEnd.IsNotNull() && c.PeriodStart.Get().After(c.PeriodEnd.Get()) {
+		result = errors.Join(result, fmt.Errorf("period start date %s is after period end date %s", c.PeriodStart.Get(), c.PeriodEnd.Get()))
+		c.PeriodStart.SetNull()
+	}
+	c.NetAmount = c.NetAmount.RoundToCents()
+	if c.TaxPercent.IsNotNull() {
+		c.TaxPercent.Set(c.TaxPercent.Get().Abs())
+		if c.TaxPercent.Get() > 100 {
+			result = errors.Join(result, fmt.Errorf("tax percent %f is greater than 100%%", c.TaxPercent.Get()))
+			c.TaxPercent.SetNull()
+		}
+	}
+	if c.Quantity.IsNotNull() && c.UnitPrice.IsNotNull() {
+		expected := money.Amount(c.Quantity.Get()) * c.UnitPrice.Get()
+		if !expected.RoundToCents().WithinOneCent(c.NetAmount) {
+			result = errors.Join(result, fmt.Errorf("net amount %f does not match quantity %f times unit price %f", c.NetAmount, c.Quantity.Get(), c.UnitPrice.Get()))
+		}
+	}
+	return result
+}
+
+// checkPeriodWithin returns an error if the period from start to end
+// is not within the period from outerStart to outerEnd.
+// Null dates are not checked.
+func checkPeriodWithin(start, end, outerStart, outerEnd date.NullableDate) error {
+	if start.IsNotNull() && outerStart.IsNotNull() && start.Get().Before(outerStart.Get()) {
+		return fmt.Errorf("date %s is before period start %s", start.Get(), outerStart.Get())
+	}
+	if end.IsNotNull() && outerEnd.IsNotNull() && end.Get().After(outerEnd.Get()) {
+		return fmt.Errorf("date %s is after period end %s", end.Get(), outerEnd.Get())
+	}
+	return nil
+}
+
+// withinRelativeTolerance returns if a and b differ by at most 0.5%
+// of the larger value, to allow for rounding on the invoice
+func withinRelativeTolerance(a, b float64) bool {
+	return math.Abs(a-b) <= 0.005*math.Max(math.Abs(a), math.Abs(b))+1e-9
+}
+
+// UtilityType is the utility billed by a UtilityInvoice
+type UtilityType string //#enum,jsonschema
+
+const (
+	UtilityTypeElectricity     UtilityType = "ELECTRICITY"      // Strom
+	UtilityTypeGas             UtilityType = "GAS"              // Erdgas
+	UtilityTypeDistrictHeating UtilityType = "DISTRICT_HEATING" // Fernwärme
+	UtilityTypeHeatingOil      UtilityType = "HEATING_OIL"      // Heizöl
+	UtilityTypeWater           UtilityType = "WATER"            // Trinkwasser
+	UtilityTypeWastewater      UtilityType = "WASTEWATER"       // Abwasser
+)
+
+// Valid indicates if t is any of the valid values for UtilityType
+func (t UtilityType) Valid() bool {
+	switch t {
+	case
+		UtilityTypeElectricity,
+		UtilityTypeGas,
+		UtilityTypeDistrictHeating,
+		UtilityTypeHeatingOil,
+		UtilityTypeWater,
+		UtilityTypeWastewater:
+		return true
+	}
+	return false
+}
+
+// Validate returns an error if t is none of the valid values for UtilityType
+func (t UtilityType) Validate() error {
+	if !t.Valid() {
+		return fmt.Errorf("invalid value %#v for type realestate.UtilityType", t)
+	}
+	return nil
+}
+
+// Enums returns all valid values for UtilityType
+func (UtilityType) Enums() []UtilityType {
+	return []UtilityType{
+		UtilityTypeElectricity,
+		UtilityTypeGas,
+		UtilityTypeDistrictHeating,
+		UtilityTypeHeatingOil,
+		UtilityTypeWater,
+		UtilityTypeWastewater,
+	}
+}
+
+// EnumStrings returns all valid values for UtilityType as strings
+func (UtilityType) EnumStrings() []string {
+	return []string{
+		"ELECTRICITY",
+		"GAS",
+		"DISTRICT_HEATING",
+		"HEATING_OIL",
+		"WATER",
+		"WASTEWATER",
+	}
+}
+
+// String implements the fmt.Stringer interface for UtilityType
+func (t UtilityType) String() string {
+	return string(t)
+}
+
+// JSONSchema returns a github.com/invopop/jsonschema.Schema for UtilityType
+func (UtilityType) JSONSchema() *jsonschema.Schema {
+	return &jsonschema.Schema{
+		Type: "string",
+		Enum: []any{
+			"ELECTRICITY",
+			"GAS",
+			"DISTRICT_HEATING",
+			"HEATING_OIL",
+			"WATER",
+			"WASTEWATER",
+		},
+	}
+}
+
+// ConsumptionUnit is the unit of meter readings and consumption
+type ConsumptionUnit string //#enum,jsonschema
+
+const (
+	ConsumptionUnitKWh   ConsumptionUnit = "KWH"   // Kilowatt hours
+	ConsumptionUnitMWh   ConsumptionUnit = "MWH"   // Megawatt hours
+	ConsumptionUnitM3    ConsumptionUnit = "M3"    // Cubic meters
+	ConsumptionUnitLiter ConsumptionUnit = "LITER" // Liters
+	ConsumptionUnitUnits ConsumptionUnit = "UNITS" // Dimensionless units of heat cost allocators (Heizkostenverteiler)
+)
+
+// Valid indicates if u is any of the valid values for ConsumptionUnit
+func (u ConsumptionUnit) Valid() bool {
+	switch u {
+	case
+		ConsumptionUnitKWh,
+		ConsumptionUnitMWh,
+		ConsumptionUnitM3,
+		ConsumptionUnitLiter,
+		ConsumptionUnitUnits:
+		return true
+	}
+	return false
+}
+
+// Validate returns an error if u is none of the valid values for ConsumptionUnit
+func (u ConsumptionUnit) Validate() error {
+	if !u.Valid() {
+		return fmt.Errorf("invalid value %#v for type realestate.ConsumptionUnit", u)
+	}
+	return nil
+}
+
+// Enums returns all valid values for ConsumptionUnit
+func (ConsumptionUnit) Enums() []ConsumptionUnit {
+	return []ConsumptionUnit{
+		ConsumptionUnitKWh,
+		ConsumptionUnitMWh,
+		ConsumptionUnitM3,
+		ConsumptionUnitLiter,
+		ConsumptionUnitUnits,
+	}
+}
+
+// EnumStrings returns all valid values for ConsumptionUnit as strings
+func (ConsumptionUnit) EnumStrings() []string {
+	return []string{
+		"KWH",
+		"MWH",
+		"M3",
+		"LITER",
+		"UNITS",
+	}
+}
+
+// String implements the fmt.Stringer interface for ConsumptionUnit
+func (u ConsumptionUnit) String() string {
+	return string(u)
+}
+
+// JSONSchema returns a github.com/invopop/jsonschema.Schema for ConsumptionUnit
+func (ConsumptionUnit) JSONSchema() *jsonschema.Schema {
+	return &jsonschema.Schema{
+		Type: "string",
+		Enum: []any{
+			"KWH",
+			"MWH",
+			"M3",
+			"LITER",
+			"UNITS",
+		},
+	}
+}
+
+// KWhFactor returns the factor to convert the unit to kWh
+// or false if the conversion depends on the energy carrier
+// or the unit is not an energy unit like ConsumptionUnitUnits
+func (u ConsumptionUnit) KWhFactor() (float64, bool) {
+	switch u {
+	case ConsumptionUnitKWh:
+		return 1, true
+	case ConsumptionUnitMWh:
+		return 1000, true
+	}
+	return 0, false
+}
+
+// TariffComponentType is the type of a TariffComponent
+type TariffComponentType string //#enum,jsonschema
+
+const (
+	TariffComponentTypeBasePrice        TariffComponentType = "BASE_PRICE"        // Grundpreis
+	TariffComponentTypeConsumptionPrice TariffComponentType = "CONSUMPTION_PRICE" // Arbeitspreis
+	TariffComponentTypeDemandPrice      TariffComponentType = "DEMAND_PRICE"      // Leistungspreis
+	TariffComponentTypeMeterFee         TariffComponentType = "METER_FEE"         // Messstellenbetrieb, Zählermiete
+	TariffComponentTypeCO2Price         TariffComponentType = "CO2_PRICE"         // CO2-Preis nach BEHG
+	TariffComponentTypeTaxOrLevy        TariffComponentType = "TAX_OR_LEVY"       // Energiesteuer, Stromsteuer, Umlagen, Konzessionsabgabe
+	TariffComponentTypeOther            TariffComponentType = "OTHER"
+)
+
+// Valid indicates if t is any of the valid values for TariffComponentType
+func (t TariffComponentType) Valid() bool {
+	switch t {
+	case
+		TariffComponentTypeBasePrice,
+		TariffComponentTypeConsumptionPrice,
+		TariffComponentTypeDemandPrice,
+		TariffComponentTypeMeterFee,
+		TariffComponentTypeCO2Price,
+		TariffComponentTypeTaxOrLevy,
+		TariffComponentTypeOther:
+		return true
+	}
+	return false
+}
+
+// Validate returns an error if t is none of the valid values for TariffComponentType
+func (t TariffComponentType) Validate() error {
+	if !t.Valid() {
+		return fmt.Errorf("invalid value %#v for type realestate.TariffComponentType", t)
+	}
+	return nil
+}
+
+// Enums returns all valid values for TariffComponentType
+func (TariffComponentType) Enums() []TariffComponentType {
+	return []TariffComponentType{
+		TariffComponentTypeBasePrice,
+		TariffComponentTypeConsumptionPrice,
+		TariffComponentTypeDemandPrice,
+		TariffComponentTypeMeterFee,
+		TariffComponentTypeCO2Price,
+		TariffComponentTypeTaxOrLevy,
+		TariffComponentTypeOther,
+	}
+}
+
+// EnumStrings returns all valid values for TariffComponentType as strings
+func (TariffComponentType) EnumStrings() []string {
+	return []string{
+		"BASE_PRICE",
+		"CONSUMPTION_PRICE",
+		"DEMAND_PRICE",
+		"METER_FEE",
+		"CO2_PRICE",
+		"TAX_OR_LEVY",
+		"OTHER",
+	}
+}
+
+// String implements the fmt.Stringer interface for TariffComponentType
+func (t TariffComponentType) String() string {
+	return string(t)
+}
+
+// JSONSchema returns a github.com/invopop/jsonschema.Schema for TariffComponentType
+func (TariffComponentType) JSONSchema() *jsonschema.Schema {
+	return &jsonschema.Schema{
+		Type: "string",
+		Enum: []any{
+			"BASE_PRICE",
+			"CONSUMPTION_PRICE",
+			"DEMAND_PRICE",
+			"METER_FEE",
+			"CO2_PRICE",
+			"TAX_OR_LEVY",
+			"OTHER",
+		},
+	}
+}
@@ -0,0 +1,128 @@
+package realestate
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/domonda/go-types/date"
+	"github.com/domonda/go-types/nullable"
+)
+
+func TestMeter_Normalize(t *testing.T) {
+	reading := func(d string, value float64) *MeterReading {
+		return &MeterReading{Date: date.NullableDate(d), Value: value}
+	}
+	number := func(v float64) (n nullable.Type[float64]) {
+		n.Set(v)
+		return n
+	}
+	tests := []struct {
+		name               string
+		meter              Meter
+		wantReadingDates   []date.NullableDate
+		wantConsumption    nullable.Type[float64]
+		wantConsumptionKWh nullable.Type[float64]
+		wantErr            []string
+	}{
+		{
+			name: "readings sorted with null dates last",
+			meter: Meter{
+				ID:   "1",
+				Unit: ConsumptionUnitKWh,
+				Readings: []*MeterReading{
+					reading("", 50),
+					reading("2025-12-31", 1500),
+					reading("2025-01-01", 1000),
+					reading("", 60),
+				},
+			},
+			wantReadingDates:   []date.NullableDate{"2025-01-01", "2025-12-31", "", ""},
+			wantConsumption:    number(500),
+			wantConsumptionKWh: number(500),
+			wantErr:            []string{"reading 0 has no date", "reading 3 has no date"},
+		},
+		{
+			name: "MWh converted to kWh",
+			meter: Meter{
+				ID:          "1",
+				Unit:        ConsumptionUnitMWh,
+				Consumption: number(2.5),
+			},
+			wantConsumption:    number(2.5),
+			wantConsumptionKWh: number(2500),
+		},
+		{
+			name: "gas with conversion factor",
+			meter: Meter{
+				ID:               "1",
+				Unit:             ConsumptionUnitM3,
+				Consumption:      number(100),
+				ConversionFactor: number(10.5),
+			},
+			wantConsumption:    number(100),
+			wantConsumptionKWh: number(1050),
+		},
+		{
+			name: "heat cost allocator units",
+			meter: Meter{
+				ID:               "1",
+				Unit:             ConsumptionUnitUnits,
+				Readings:         []*MeterReading{reading("2025-01-01", 0), reading("2025-12-31", 420)},
+				ConversionFactor: number(1.2),
+			},
+			wantReadingDates: []date.NullableDate{"2025-01-01", "2025-12-31"},
+			wantConsumption:  number(420),
+			wantErr:          []string{"heat cost allocator units can't be converted to kWh"},
+		},
+		{
+			name: "consumption mismatch",
+			meter: Meter{
+				ID:          "1",
+				Unit:        ConsumptionUnitKWh,
+				Readings:    []*MeterReading{reading("2025-01-01", 1000), reading("2025-12-31", 1500)},
+				Consumption: number(600),
+			},
+			wantReadingDates:   []date.NullableDate{"2025-01-01", "2025-12-31"},
+			wantConsumption:    number(600),
+			wantConsumptionKWh: number(600),
+			wantErr:            []string{"consumption 600.000000 does not match readings difference 500.000000"},
+		},
+		{
+			name: "empty ID and invalid unit",
+			meter: Meter{
+				Unit:        "GALLONS",
+				Consumption: number(10),
+			},
+			wantConsumption: number(10),
+			wantErr:         []string{"meter ID is empty", "GALLONS"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m := tt.meter
+			err := m.Normalize()
+			if len(tt.wantErr) == 0 && err != nil {
+				t.Errorf("Meter.Normalize() error = %v", err)
+			}
+			for _, want := range tt.wantErr {
+				if err == nil || !strings.Contains(err.Error(), want) {
+					t.Errorf("Meter.Normalize() error = %v, want it to contain %q", err, want)
+				}
+			}
+			var dates []date.NullableDate
+			for _, r := range m.Readings {
+				dates = append(dates, r.Date)
+			}
+			if !slices.Equal(dates, tt.wantReadingDates) {
+				t.Errorf("reading dates = %v, want %v", dates, tt.wantReadingDates)
+			}
+			if m.Consumption != tt.wantConsumption {
+				t.Errorf("Consumption = %v, want %v", m.Consumption, tt.wantConsumption)
+			}
+			if m.ConsumptionKWh != tt.wantConsumptionKWh {
+				t.Errorf("ConsumptionKWh = %v, want %v", m.ConsumptionKWh, tt.wantConsumptionKWh)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/domonda/go-types/uu"
+)
+
+func (s *Server) domondaExtractInvoice(w http.ResponseWriter, r *http.Request) {
+	if s.InvoiceExtractor == nil {
+		WriteError(w, errNotImplemented("InvoiceExtractor"))
+		return
+	}
+	apiKey, documentID, err := s.domondaRequestHeaders(r)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	document, err := s.DomondaDocuments.LoadDocument(r.Context(), apiKey, documentID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	result, err := s.InvoiceExtractor.ExtractInvoice(r.Context(), document)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) domondaExtractAccountingInvoice(w http.ResponseWriter, r *http.Request) {
+	if s.AccountingInvoiceExtractor == nil {
+		WriteError(w, errNotImplemented("AccountingInvoiceExtractor"))
+		return
+	}
+	apiKey, documentID, err := s.domondaRequestHeaders(r)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	document, err := s.DomondaDocuments.LoadDocument(r.Context(), apiKey, documentID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	result, err := s.AccountingInvoiceExtractor.ExtractAccountingInvoice(r.Context(), document)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) domondaExtractRealEstateInvoice(w http.ResponseWriter, r *http.Request) {
+	if s.RealEstateInvoiceExtractor == nil {
+		WriteError(w, errNotImplemented("RealEstateInvoiceExtractor"))
+		return
+	}
+	apiKey, documentID, err := s.domondaRequestHeaders(r)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	document, err := s.DomondaDocuments.LoadDocument(r.Context(), apiKey, documentID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	objects, err := s.DomondaDocuments.LoadRealEstateObjects(r.Context(), apiKey, documentID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	result, err := s.RealEstateInvoiceExtractor.ExtractRealEstateInvoice(r.Context(), document, objects)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) domondaIdentifyRealEstateObject(w http.ResponseWriter, r *http.Request) {
+	if s.ObjectIdentifier == nil {
+		WriteError(w, errNotImplemented("ObjectIdentifier"))
+		return
+	}
+	apiKey, documentID, err := s.domondaRequestHeaders(r)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	document, err := s.DomondaDocuments.LoadDocument(r.Context(), apiKey, documentID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	objects, err := s.DomondaDocuments.LoadRealEstateObjects(r.Context(), apiKey, documentID)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	result, err := s.ObjectIdentifier.IdentifyRealEstateObject(r.Context(), document, objects)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// domondaRequestHeaders returns the values of the
+// X-Domonda-API-Key and X-Document-ID request headers
+func (s *Server) domondaRequestHeaders(r *http.Request) (apiKey string, documentID uu.ID, err error) {
+	if s.DomondaDocuments == nil {
+		return "", uu.IDNil, errNotImplemented("DomondaDocumentLoader")
+	}
+	apiKey = r.Header.Get("X-Domonda-API-Key")
+	if apiKey == "" {
+		return "", uu.IDNil, NewError(http.StatusUnauthorized, "missing X-Domonda-API-Key header")
+	}
+	documentID, err = uu.IDFromString(r.Header.Get("X-Document-ID"))
+	if err != nil {
+		return "", uu.IDNil, NewError(http.StatusBadRequest, "invalid X-Document-ID header: %s", err)
+	}
+	return apiKey, documentID, nil
+}
@@ -0,0 +1,50 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// Error is an error with an HTTP status code
+// that is written as JSON error response of the API
+type Error struct {
+	// Error message
+	Message string `json:"error"`
+	// HTTP status code
+	Code int `json:"code"`
+}
+
+// NewError returns an *Error with the passed HTTP status code
+// and a message formatted from format and args.
+func NewError(code int, format string, args ...any) *Error {
+	return &Error{Message: fmt.Sprintf(format, args...), Code: code}
+}
+
+// Error implements the error interface
+func (e *Error) Error() string {
+	return fmt.Sprintf("%d %s: %s", e.Code, http.StatusText(e.Code), e.Message)
+}
+
+// WriteError writes err as JSON error response.
+// The status code is taken from an *Error wrapped by err
+// or http.StatusInternalServerError is used.
+func WriteError(w http.ResponseWriter, err error) {
+	var e *Error
+	if !errors.As(err, &e) {
+		e = &Error{Message: err.Error(), Code: http.StatusInternalServerError}
+	}
+	writeJSON(w, e.Code, e)
+}
+
+func writeJSON(w http.ResponseWriter, code int, value any) {
+	body, err := json.Marshal(value)
+	if err != nil {
+		code = http.StatusInternalServerError
+		body, _ = json.Marshal(&Error{Message: fmt.Sprintf("failed to encode response: %s", err), Code: code})
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	w.Write(body)
+}
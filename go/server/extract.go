@@ -0,0 +1,126 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/docvibe-ai/api/go/realestate"
+)
+
+func (s *Server) identifyRealEstateObject(w http.ResponseWriter, r *http.Request) {
+	if s.ObjectIdentifier == nil {
+		WriteError(w, errNotImplemented("ObjectIdentifier"))
+		return
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, s.maxDocumentSize())
+	err := r.ParseMultipartForm(32 << 20)
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			WriteError(w, NewError(http.StatusRequestEntityTooLarge, "request is larger than %d bytes", maxBytesErr.Limit))
+			return
+		}
+		WriteError(w, NewError(http.StatusBadRequest, "invalid multipart form: %s", err))
+		return
+	}
+	document, err := readFormFile(r, "document")
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	objectsJSON, err := readFormFile(r, "objects")
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	var objects []*realestate.Object
+	err = json.Unmarshal(objectsJSON, &objects)
+	if err != nil {
+		WriteError(w, NewError(http.StatusBadRequest, "invalid objects JSON: %s", err))
+		return
+	}
+
+	result, err := s.ObjectIdentifier.IdentifyRealEstateObject(r.Context(), document, objects)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) extractSection35aInvoiceAmounts(w http.ResponseWriter, r *http.Request) {
+	if s.Section35aExtractor == nil {
+		WriteError(w, errNotImplemented("Section35aExtractor"))
+		return
+	}
+	document, err := s.readDocument(w, r)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	result, err := s.Section35aExtractor.ExtractSection35aInvoiceAmounts(r.Context(), document)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+func (s *Server) extractTextAndPageImages(w http.ResponseWriter, r *http.Request) {
+	if s.TextAndPageImagesExtractor == nil {
+		WriteError(w, errNotImplemented("TextAndPageImagesExtractor"))
+		return
+	}
+	document, err := s.readDocument(w, r)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	result, err := s.TextAndPageImagesExtractor.ExtractTextAndPageImages(r.Context(), document)
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	writeZIP(w, result)
+}
+
+// readDocument reads the request body as document file
+func (s *Server) readDocument(w http.ResponseWriter, r *http.Request) ([]byte, error) {
+	document, err := io.ReadAll(http.MaxBytesReader(w, r.Body, s.maxDocumentSize()))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return nil, NewError(http.StatusRequestEntityTooLarge, "document is larger than %d bytes", maxBytesErr.Limit)
+		}
+		return nil, NewError(http.StatusBadRequest, "failed to read document: %s", err)
+	}
+	if len(document) == 0 {
+		return nil, NewError(http.StatusBadRequest, "empty document")
+	}
+	return document, nil
+}
+
+func readFormFile(r *http.Request, name string) ([]byte, error) {
+	file, _, err := r.FormFile(name)
+	if err != nil {
+		return nil, NewError(http.StatusBadRequest, "missing form file %q: %s", name, err)
+	}
+	defer file.Close()
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read form file %q: %w", name, err)
+	}
+	if len(data) == 0 {
+		return nil, NewError(http.StatusBadRequest, "empty form file %q", name)
+	}
+	return data, nil
+}
+
+func writeZIP(w http.ResponseWriter, data []byte) {
+	w.Header().Set("Content-Type", "application/zip")
+	w.WriteHeader(http.StatusOK)
+	w.Write(data)
+}
@@ -0,0 +1,54 @@
+package server
+
+import (
+	"context"
+
+	"github.com/domonda/go-types/uu"
+
+	"github.com/docvibe-ai/api/go/invoicing"
+	"github.com/docvibe-ai/api/go/realestate"
+)
+
+// InvoiceExtractor extracts structured invoice data from a document
+type InvoiceExtractor interface {
+	ExtractInvoice(ctx context.Context, document []byte) (*invoicing.Invoice, error)
+}
+
+// AccountingInvoiceExtractor extracts structured invoice data
+// including accounting entries from a document
+type AccountingInvoiceExtractor interface {
+	ExtractAccountingInvoice(ctx context.Context, document []byte) (*invoicing.AccountingInvoice, error)
+}
+
+// RealEstateInvoiceExtractor extracts structured real estate invoice data
+// including §35a amounts and identified objects from a document
+type RealEstateInvoiceExtractor interface {
+	ExtractRealEstateInvoice(ctx context.Context, document []byte, objects []*realestate.Object) (*realestate.Invoice, error)
+}
+
+// Section35aExtractor extracts the amounts of an invoice document
+// that are tax deductible according to German §35a EStG
+type Section35aExtractor interface {
+	ExtractSection35aInvoiceAmounts(ctx context.Context, document []byte) ([]*realestate.Section35aInvoiceAmount, error)
+}
+
+// ObjectIdentifier returns the objects that are mentioned in a document
+type ObjectIdentifier interface {
+	IdentifyRealEstateObject(ctx context.Context, document []byte, objects []*realestate.Object) ([]*realestate.Object, error)
+}
+
+// TextAndPageImagesExtractor extracts the text and page images of a document
+// and returns them as ZIP file
+type TextAndPageImagesExtractor interface {
+	ExtractTextAndPageImages(ctx context.Context, document []byte) (zip []byte, err error)
+}
+
+// DomondaDocumentLoader loads documents and real estate objects from Domonda
+// for the Domonda integration endpoints.
+// An invalid API key should be reported as error with the code http.StatusUnauthorized
+// and an unknown document as error with the code http.StatusBadRequest,
+// see NewError.
+type DomondaDocumentLoader interface {
+	LoadDocument(ctx context.Context, apiKey string, documentID uu.ID) (document []byte, err error)
+	LoadRealEstateObjects(ctx context.Context, apiKey string, documentID uu.ID) ([]*realestate.Object, error)
+}
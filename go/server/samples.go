@@ -0,0 +1,77 @@
+package server
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"net/http"
+	"path"
+
+	"github.com/docvibe-ai/api/go/document"
+)
+
+//go:embed samples/*.json
+var samples embed.FS
+
+// serveSample returns a handler for the GET test variant
+// of an endpoint that responds with the embedded sample JSON file
+func serveSample(filename string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		data, err := samples.ReadFile(path.Join("samples", filename))
+		if err != nil {
+			WriteError(w, err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusOK)
+		w.Write(data)
+	}
+}
+
+func serveSampleTextAndPageImages(w http.ResponseWriter, r *http.Request) {
+	data, err := sampleTextAndPageImagesZIP()
+	if err != nil {
+		WriteError(w, err)
+		return
+	}
+	writeZIP(w, data)
+}
+
+// sampleTextAndPageImagesZIP returns a ZIP file with the pages
+// of the embedded sample document.json and blank A4 page images
+func sampleTextAndPageImagesZIP() ([]byte, error) {
+	documentJSON, err := samples.ReadFile("samples/" + document.JSONFilename)
+	if err != nil {
+		return nil, err
+	}
+	var doc document.Document
+	err = json.Unmarshal(documentJSON, &doc)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	zipWriter := document.NewZIPWriter(&buf)
+	for _, page := range doc.Pages {
+		img := image.NewGray(image.Rect(0, 0, page.ImageWidth, page.ImageHeight))
+		draw.Draw(img, img.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+		var pngData bytes.Buffer
+		err = png.Encode(&pngData, img)
+		if err != nil {
+			return nil, err
+		}
+		err = zipWriter.AddPage(page.Text, &pngData, ".png", page.ImageWidth, page.ImageHeight)
+		if err != nil {
+			return nil, err
+		}
+	}
+	err = zipWriter.Close()
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,66 @@
+package server
+
+import (
+	"net/http"
+)
+
+// DefaultMaxDocumentSize is the maximum size of an uploaded document
+// used when Server.MaxDocumentSize is zero
+const DefaultMaxDocumentSize = 64 << 20
+
+// Server implements the endpoints of schema/swagger.yaml
+// and schema/swagger-domonda.yaml.
+// The extraction is delegated to the extractor interfaces,
+// endpoints with a nil extractor respond with
+// http.StatusNotImplemented.
+// The GET test variants of all endpoints respond with sample data.
+type Server struct {
+	InvoiceExtractor           InvoiceExtractor
+	AccountingInvoiceExtractor AccountingInvoiceExtractor
+	RealEstateInvoiceExtractor RealEstateInvoiceExtractor
+	Section35aExtractor        Section35aExtractor
+	ObjectIdentifier           ObjectIdentifier
+	TextAndPageImagesExtractor TextAndPageImagesExtractor
+
+	// DomondaDocuments is used to load the documents
+	// for the Domonda integration endpoints
+	DomondaDocuments DomondaDocumentLoader
+
+	// MaxDocumentSize is the maximum size of an uploaded document in bytes.
+	// If zero, then DefaultMaxDocumentSize is used.
+	MaxDocumentSize int64
+}
+
+// Handler returns an http.Handler serving all API endpoints
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+
+	mux.HandleFunc("POST /extract/identify-real-estate-object", s.identifyRealEstateObject)
+	mux.HandleFunc("GET /extract/identify-real-estate-object", serveSample("real-estate-objects.json"))
+	mux.HandleFunc("POST /extract/section-35a-invoice-amounts", s.extractSection35aInvoiceAmounts)
+	mux.HandleFunc("GET /extract/section-35a-invoice-amounts", serveSample("section35a-invoice-amounts.json"))
+	mux.HandleFunc("POST /extract/text-and-page-images", s.extractTextAndPageImages)
+	mux.HandleFunc("GET /extract/text-and-page-images", serveSampleTextAndPageImages)
+
+	mux.HandleFunc("POST /domonda/extract/invoice", s.domondaExtractInvoice)
+	mux.HandleFunc("GET /domonda/extract/invoice", serveSample("invoice.json"))
+	mux.HandleFunc("POST /domonda/extract/accounting-invoice", s.domondaExtractAccountingInvoice)
+	mux.HandleFunc("GET /domonda/extract/accounting-invoice", serveSample("accounting-invoice.json"))
+	mux.HandleFunc("POST /domonda/extract/real-estate-invoice", s.domondaExtractRealEstateInvoice)
+	mux.HandleFunc("GET /domonda/extract/real-estate-invoice", serveSample("real-estate-invoice.json"))
+	mux.HandleFunc("POST /domonda/extract/identify-real-estate-object", s.domondaIdentifyRealEstateObject)
+	mux.HandleFunc("GET /domonda/extract/identify-real-estate-object", serveSample("real-estate-objects.json"))
+
+	return mux
+}
+
+func (s *Server) maxDocumentSize() int64 {
+	if s.MaxDocumentSize > 0 {
+		return s.MaxDocumentSize
+	}
+	return DefaultMaxDocumentSize
+}
+
+func errNotImplemented(extractor string) *Error {
+	return NewError(http.StatusNotImplemented, "no %s configured", extractor)
+}